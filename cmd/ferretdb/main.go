@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/expfmt"
@@ -27,6 +28,7 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/FerretDB/FerretDB/internal/clientconn"
+	"github.com/FerretDB/FerretDB/internal/handlers/common"
 	"github.com/FerretDB/FerretDB/internal/pg"
 	"github.com/FerretDB/FerretDB/internal/util/debug"
 	"github.com/FerretDB/FerretDB/internal/util/logging"
@@ -35,11 +37,15 @@ import (
 
 //nolint:gochecknoglobals // flags are defined there to be visible in `bin/ferretdb-testcover -h` output
 var (
+	cursorTimeoutF   = flag.Duration("cursor-timeout", common.DefaultCursorTimeout, "close cursors idle for longer than that")
 	debugAddrF       = flag.String("debug-addr", "127.0.0.1:8088", "debug address")
-	listenAddrF      = flag.String("listen-addr", "127.0.0.1:27017", "listen address")
+	listenAddrF      = flag.String("listen-addr", "127.0.0.1:27017", "listen TCP address (disabled if empty)")
+	listenUnixF      = flag.String("listen-unix", "", "listen Unix socket path (disabled if empty)")
+	listenUnixPermF  = flag.String("listen-unix-perm", "0700", "Unix socket file permissions (octal)")
 	modeF            = flag.String("mode", string(clientconn.AllModes[0]), fmt.Sprintf("operation mode: %v", clientconn.AllModes))
 	postgresqlURLF   = flag.String("postgresql-url", "postgres://postgres@127.0.0.1:5432/ferretdb", "PostgreSQL URL")
 	proxyAddrF       = flag.String("proxy-addr", "127.0.0.1:37017", "")
+	recordDirF       = flag.String("record-dir", "", "directory for recording all requests and responses (disabled if empty);\nwarning: recordings contain sensitive data like credentials") //nolint:lll // help text
 	tlsF             = flag.Bool("tls", false, "enable insecure TLS")
 	versionF         = flag.Bool("version", false, "print version to stdout (full version, commit, branch, dirty flag) and exit")
 	testConnTimeoutF = flag.Duration("test-conn-timeout", 0, "test: set connection timeout")
@@ -83,6 +89,11 @@ func main() {
 		logger.Sugar().Warn("The current TLS implementation is not secure.")
 	}
 
+	listenUnixPerm, err := strconv.ParseUint(*listenUnixPermF, 8, 32)
+	if err != nil {
+		logger.Sugar().Fatalf("Invalid Unix socket permissions %q: %s.", *listenUnixPermF, err)
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), unix.SIGTERM, unix.SIGINT)
 	go func() {
 		<-ctx.Done()
@@ -100,11 +111,15 @@ func main() {
 
 	l := clientconn.NewListener(&clientconn.NewListenerOpts{
 		ListenAddr:      *listenAddrF,
+		ListenUnix:      *listenUnixF,
+		ListenUnixPerm:  os.FileMode(listenUnixPerm),
 		TLS:             *tlsF,
 		ProxyAddr:       *proxyAddrF,
 		Mode:            clientconn.Mode(*modeF),
 		PgPool:          pgPool,
 		Logger:          logger.Named("listener"),
+		RecordDir:       *recordDirF,
+		CursorTimeout:   *cursorTimeoutF,
 		TestConnTimeout: *testConnTimeoutF,
 	})
 
@@ -0,0 +1,301 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Replaytool replays requests recorded by FerretDB's -record-dir flag
+// against any wire protocol compatible endpoint and reports responses that do not match the recording.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"go.uber.org/zap"
+
+	"github.com/FerretDB/FerretDB/internal/fjson"
+	"github.com/FerretDB/FerretDB/internal/recorder"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+	"github.com/FerretDB/FerretDB/internal/util/logging"
+	"github.com/FerretDB/FerretDB/internal/wire"
+)
+
+// replayConn represents a connection to the endpoint for a single recorded client connection.
+type replayConn struct {
+	netConn net.Conn
+	bufr    *bufio.Reader
+	bufw    *bufio.Writer
+}
+
+// replayer replays records against the endpoint.
+type replayer struct {
+	addr   string
+	ignore map[string]struct{}
+	conns  map[int64]*replayConn
+	l      *zap.SugaredLogger
+}
+
+// conn returns the endpoint connection for the given recorded connection ID, creating it if needed.
+func (r *replayer) conn(connID int64) (*replayConn, error) {
+	if c, ok := r.conns[connID]; ok {
+		return c, nil
+	}
+
+	network, addr := "tcp", r.addr
+	if strings.HasPrefix(addr, "unix:") {
+		network, addr = "unix", strings.TrimPrefix(addr, "unix:")
+	}
+
+	netConn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	c := &replayConn{
+		netConn: netConn,
+		bufr:    bufio.NewReader(netConn),
+		bufw:    bufio.NewWriter(netConn),
+	}
+	r.conns[connID] = c
+	return c, nil
+}
+
+// close closes all endpoint connections.
+func (r *replayer) close() {
+	for _, c := range r.conns {
+		c.netConn.Close()
+	}
+}
+
+// replay sends the recorded request and compares responses with recorded ones.
+//
+// It returns the number of mismatched responses.
+func (r *replayer) replay(rec *recorder.Record) (int, error) {
+	c, err := r.conn(rec.ConnID)
+	if err != nil {
+		return 0, lazyerrors.Error(err)
+	}
+
+	// send recorded bytes as-is, including invalid messages and checksums
+	if _, err = c.bufw.Write(rec.Request.Raw); err != nil {
+		return 0, lazyerrors.Error(err)
+	}
+	if err = c.bufw.Flush(); err != nil {
+		return 0, lazyerrors.Error(err)
+	}
+
+	// the client did not expect a response
+	if len(rec.Responses) == 0 {
+		return 0, nil
+	}
+
+	var mismatches int
+	for i := 0; ; i++ {
+		header, body, err := wire.ReadMessage(c.bufr)
+		if err != nil {
+			return mismatches, lazyerrors.Error(err)
+		}
+
+		actual, moreToCome, err := r.normalize(header, body)
+		if err != nil {
+			return mismatches, lazyerrors.Error(err)
+		}
+
+		var expected string
+		if i < len(rec.Responses) {
+			if expected, _, err = r.normalize(rec.Responses[i].Header, rec.Responses[i].Body); err != nil {
+				return mismatches, lazyerrors.Error(err)
+			}
+		}
+
+		if actual != expected {
+			mismatches++
+
+			diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(expected),
+				FromFile: "recorded",
+				B:        difflib.SplitLines(actual),
+				ToFile:   "replayed",
+				Context:  1,
+			})
+			if err != nil {
+				return mismatches, lazyerrors.Error(err)
+			}
+
+			r.l.Warnf(
+				"Response %d to request %d (connection %d) does not match:\n%s",
+				i, rec.Request.Header.RequestID, rec.ConnID, diff,
+			)
+		}
+
+		if !moreToCome {
+			if n := len(rec.Responses); i+1 < n {
+				r.l.Warnf(
+					"Got %d responses to request %d (connection %d), expected %d.",
+					i+1, rec.Request.Header.RequestID, rec.ConnID, n,
+				)
+				mismatches += n - i - 1
+			}
+
+			return mismatches, nil
+		}
+	}
+}
+
+// normalize returns a string representation of the message for comparison.
+//
+// Compressed messages are decompressed; header fields that change between runs
+// and ignored top-level document fields are omitted.
+// It also returns true if the message has moreToCome flag set.
+func (r *replayer) normalize(header *wire.MsgHeader, body wire.MsgBody) (string, bool, error) {
+	if compressed, ok := body.(*wire.OpCompressed); ok {
+		var err error
+		if header, body, err = wire.Decompress(header, compressed); err != nil {
+			return "", false, lazyerrors.Error(err)
+		}
+	}
+
+	var docs []*types.Document
+	var moreToCome bool
+	switch body := body.(type) {
+	case *wire.OpMsg:
+		doc, err := body.Document()
+		if err != nil {
+			return "", false, lazyerrors.Error(err)
+		}
+		docs = []*types.Document{doc}
+		moreToCome = body.FlagBits.FlagSet(wire.OpMsgMoreToCome)
+
+	case *wire.OpReply:
+		docs = body.Documents
+
+	default:
+		return "", false, lazyerrors.Errorf("unexpected response body %T", body)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "opcode: %s\n", header.OpCode)
+	for _, doc := range docs {
+		d := new(types.Document)
+		m := doc.Map()
+		for _, k := range doc.Keys() {
+			if _, ok := r.ignore[k]; ok {
+				continue
+			}
+			if err := d.Set(k, m[k]); err != nil {
+				return "", false, lazyerrors.Error(err)
+			}
+		}
+
+		b, err := fjson.Marshal(d)
+		if err != nil {
+			return "", false, lazyerrors.Error(err)
+		}
+
+		if err = json.Indent(&buf, b, "", "  "); err != nil {
+			return "", false, lazyerrors.Error(err)
+		}
+		buf.WriteString("\n")
+	}
+
+	return buf.String(), moreToCome, nil
+}
+
+// run replays all records from the recording file at path and closes endpoint connections.
+//
+// It returns the number of replayed records and mismatched responses,
+// including ones counted before an error.
+func (r *replayer) run(path string) (records, mismatches int, err error) {
+	defer r.close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, lazyerrors.Error(err)
+	}
+	defer f.Close()
+
+	bufr := bufio.NewReader(f)
+	for {
+		rec, err := recorder.ReadRecord(bufr)
+		if err == io.EOF {
+			return records, mismatches, nil
+		}
+		if err != nil {
+			return records, mismatches, lazyerrors.Error(err)
+		}
+
+		r.l.Debugf("Replaying request %s (connection %d).", rec.Request.Header, rec.ConnID)
+
+		n, err := r.replay(rec)
+		mismatches += n
+		if err != nil {
+			return records, mismatches, lazyerrors.Error(err)
+		}
+		records++
+	}
+}
+
+func main() {
+	debugF := flag.Bool("debug", false, "enable debug mode")
+	addrF := flag.String("addr", "127.0.0.1:27017", "endpoint address: host:port or unix:<socket path>")
+	ignoreF := flag.String(
+		"ignore", "localTime,operationTime,$clusterTime,connectionId,topologyVersion",
+		"comma-separated list of top-level response fields to ignore",
+	)
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		fmt.Fprintln(flag.CommandLine.Output(), "recording file path expected")
+		os.Exit(2)
+	}
+
+	logging.Setup(zap.InfoLevel)
+	if *debugF {
+		logging.Setup(zap.DebugLevel)
+	}
+	logger := zap.S()
+
+	r := &replayer{
+		addr:   *addrF,
+		ignore: make(map[string]struct{}),
+		conns:  make(map[int64]*replayConn),
+		l:      logger,
+	}
+
+	for _, field := range strings.Split(*ignoreF, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			r.ignore[field] = struct{}{}
+		}
+	}
+
+	records, mismatches, err := r.run(flag.Arg(0))
+
+	logger.Infof("Replayed %d records, %d mismatched responses.", records, mismatches)
+
+	if err != nil {
+		logger.Error(err)
+	}
+
+	if err != nil || mismatches != 0 {
+		os.Exit(1)
+	}
+}
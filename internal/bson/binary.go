@@ -38,6 +38,9 @@ func (bin *binaryType) ReadFrom(r *bufio.Reader) error {
 	if l < 0 {
 		return lazyerrors.Errorf("bson.Binary.ReadFrom: invalid length: %d", l)
 	}
+	if l > types.MaxDocumentLen {
+		return lazyerrors.Errorf("bson.Binary.ReadFrom: length %d: %w", l, ErrDocumentTooLarge)
+	}
 
 	subtype, err := r.ReadByte()
 	if err != nil {
@@ -52,6 +52,10 @@ var binaryTestCases = []testCase{{
 	name: "EOF",
 	b:    []byte{0x00},
 	bErr: `unexpected EOF`,
+}, {
+	name: "TooLarge",
+	b:    []byte{0x01, 0x00, 0x00, 0x01, 0x00},
+	bErr: `BSON document is too large`,
 }}
 
 func TestBinary(t *testing.T) {
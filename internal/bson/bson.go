@@ -20,6 +20,7 @@ package bson
 import (
 	"bufio"
 	"encoding"
+	"errors"
 	"fmt"
 	"time"
 
@@ -38,6 +39,9 @@ type bsontype interface {
 
 //go-sumtype:decl bsontype
 
+// ErrDocumentTooLarge is returned when a document or its value is larger than types.MaxDocumentLen.
+var ErrDocumentTooLarge = errors.New("BSON document is too large")
+
 //nolint:deadcode // remove later if it is not needed
 func fromBSON(v bsontype) any {
 	switch v := v.(type) {
@@ -67,8 +71,24 @@ func fromBSON(v bsontype) any {
 		return types.Timestamp(*v)
 	case *int64Type:
 		return int64(*v)
+	case *decimal128Type:
+		return types.Decimal128(*v)
 	case *CString:
 		return types.CString(*v)
+	case *minKeyType:
+		return types.MinKey
+	case *maxKeyType:
+		return types.MaxKey
+	case *undefinedType:
+		return types.Undefined
+	case *dbPointerType:
+		return types.DBPointer(*v)
+	case *javaScriptType:
+		return types.JavaScript(*v)
+	case *symbolType:
+		return types.Symbol(*v)
+	case *javaScriptScopeType:
+		return types.JavaScriptScope(*v)
 	}
 
 	panic(fmt.Sprintf("not reached: %T", v)) // for go-sumtype to work
@@ -103,8 +123,24 @@ func toBSON(v any) bsontype {
 		return pointer.To(timestampType(v))
 	case int64:
 		return pointer.To(int64Type(v))
+	case types.Decimal128:
+		return pointer.To(decimal128Type(v))
 	case types.CString:
 		return pointer.To(CString(v))
+	case types.MinKeyType:
+		return pointer.To(minKeyType(v))
+	case types.MaxKeyType:
+		return pointer.To(maxKeyType(v))
+	case types.UndefinedType:
+		return pointer.To(undefinedType(v))
+	case types.DBPointer:
+		return pointer.To(dbPointerType(v))
+	case types.JavaScript:
+		return pointer.To(javaScriptType(v))
+	case types.Symbol:
+		return pointer.To(symbolType(v))
+	case types.JavaScriptScope:
+		return pointer.To(javaScriptScopeType(v))
 	}
 
 	panic(fmt.Sprintf("not reached: %T", v)) // for go-sumtype to work
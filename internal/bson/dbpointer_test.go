@@ -0,0 +1,52 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bson
+
+import (
+	"testing"
+
+	"github.com/AlekSi/pointer"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+)
+
+var dbPointerTestCases = []testCase{{
+	name: "normal",
+	v: pointer.To(dbPointerType{
+		Namespace: "db.c",
+		ID:        types.ObjectID{0x62, 0x56, 0xc5, 0xba, 0x18, 0x2d, 0x4b, 0xa5, 0x07, 0x0f, 0x8b, 0x9c},
+	}),
+	b: []byte{
+		0x05, 0x00, 0x00, 0x00, 0x64, 0x62, 0x2e, 0x63, 0x00,
+		0x62, 0x56, 0xc5, 0xba, 0x18, 0x2d, 0x4b, 0xa5, 0x07, 0x0f, 0x8b, 0x9c,
+	},
+}, {
+	name: "EOF",
+	b:    []byte{0x05, 0x00, 0x00, 0x00, 0x64, 0x62, 0x2e, 0x63, 0x00, 0x62},
+	bErr: `unexpected EOF`,
+}}
+
+func TestDBPointer(t *testing.T) {
+	t.Parallel()
+	testBinary(t, dbPointerTestCases, func() bsontype { return new(dbPointerType) })
+}
+
+func FuzzDBPointer(f *testing.F) {
+	fuzzBinary(f, dbPointerTestCases, func() bsontype { return new(dbPointerType) })
+}
+
+func BenchmarkDBPointer(b *testing.B) {
+	benchmark(b, dbPointerTestCases, func() bsontype { return new(dbPointerType) })
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bson
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+)
+
+// decimal128Type represents BSON 128-bit decimal floating point type.
+type decimal128Type types.Decimal128
+
+func (d *decimal128Type) bsontype() {}
+
+// ReadFrom implements bsontype interface.
+func (d *decimal128Type) ReadFrom(r *bufio.Reader) error {
+	if err := binary.Read(r, binary.LittleEndian, &d.L); err != nil {
+		return lazyerrors.Errorf("bson.Decimal128.ReadFrom (binary.Read): %w", err)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &d.H); err != nil {
+		return lazyerrors.Errorf("bson.Decimal128.ReadFrom (binary.Read): %w", err)
+	}
+
+	return nil
+}
+
+// WriteTo implements bsontype interface.
+func (d decimal128Type) WriteTo(w *bufio.Writer) error {
+	v, err := d.MarshalBinary()
+	if err != nil {
+		return lazyerrors.Errorf("bson.Decimal128.WriteTo: %w", err)
+	}
+
+	_, err = w.Write(v)
+	if err != nil {
+		return lazyerrors.Errorf("bson.Decimal128.WriteTo: %w", err)
+	}
+
+	return nil
+}
+
+// MarshalBinary implements bsontype interface.
+func (d decimal128Type) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+
+	binary.Write(&buf, binary.LittleEndian, d.L)
+	binary.Write(&buf, binary.LittleEndian, d.H)
+
+	return buf.Bytes(), nil
+}
+
+// check interfaces
+var (
+	_ bsontype = (*decimal128Type)(nil)
+)
@@ -89,9 +89,12 @@ func (doc *Document) ReadFrom(r *bufio.Reader) error {
 	if err := binary.Read(r, binary.LittleEndian, &l); err != nil {
 		return lazyerrors.Errorf("bson.Document.ReadFrom (binary.Read): %w", err)
 	}
-	if l < minDocumentLen || l > types.MaxDocumentLen {
+	if l < minDocumentLen {
 		return lazyerrors.Errorf("bson.Document.ReadFrom: invalid length %d", l)
 	}
+	if l > types.MaxDocumentLen {
+		return lazyerrors.Errorf("bson.Document.ReadFrom: length %d: %w", l, ErrDocumentTooLarge)
+	}
 
 	// make buffer
 	b := make([]byte, l)
@@ -176,7 +179,8 @@ func (doc *Document) ReadFrom(r *bufio.Reader) error {
 			doc.m[string(ename)] = types.Binary(v)
 
 		case tagUndefined:
-			return lazyerrors.Errorf("bson.Document.ReadFrom: unhandled element type `Undefined (value) — Deprecated`")
+			// skip calling ReadFrom that does nothing
+			doc.m[string(ename)] = types.Undefined
 
 		case tagObjectID:
 			var v objectIDType
@@ -231,8 +235,49 @@ func (doc *Document) ReadFrom(r *bufio.Reader) error {
 			}
 			doc.m[string(ename)] = int64(v)
 
-		case tagDBPointer, tagDecimal, tagJavaScript, tagJavaScriptScope, tagMaxKey, tagMinKey, tagSymbol:
-			return lazyerrors.Errorf("bson.Document.ReadFrom: unhandled element type %#02x (%s)", t, tag(t))
+		case tagDecimal:
+			var v decimal128Type
+			if err := v.ReadFrom(bufr); err != nil {
+				return lazyerrors.Errorf("bson.Document.ReadFrom (Decimal128): %w", err)
+			}
+			doc.m[string(ename)] = types.Decimal128(v)
+
+		case tagDBPointer:
+			var v dbPointerType
+			if err := v.ReadFrom(bufr); err != nil {
+				return lazyerrors.Errorf("bson.Document.ReadFrom (DBPointer): %w", err)
+			}
+			doc.m[string(ename)] = types.DBPointer(v)
+
+		case tagJavaScript:
+			var v javaScriptType
+			if err := v.ReadFrom(bufr); err != nil {
+				return lazyerrors.Errorf("bson.Document.ReadFrom (JavaScript): %w", err)
+			}
+			doc.m[string(ename)] = types.JavaScript(v)
+
+		case tagSymbol:
+			var v symbolType
+			if err := v.ReadFrom(bufr); err != nil {
+				return lazyerrors.Errorf("bson.Document.ReadFrom (Symbol): %w", err)
+			}
+			doc.m[string(ename)] = types.Symbol(v)
+
+		case tagJavaScriptScope:
+			var v javaScriptScopeType
+			if err := v.ReadFrom(bufr); err != nil {
+				return lazyerrors.Errorf("bson.Document.ReadFrom (JavaScriptScope): %w", err)
+			}
+			doc.m[string(ename)] = types.JavaScriptScope(v)
+
+		case tagMinKey:
+			// skip calling ReadFrom that does nothing
+			doc.m[string(ename)] = types.MinKey
+
+		case tagMaxKey:
+			// skip calling ReadFrom that does nothing
+			doc.m[string(ename)] = types.MaxKey
+
 		default:
 			return lazyerrors.Errorf("bson.Document.ReadFrom: unhandled element type %#02x (%s)", t, tag(t))
 		}
@@ -392,6 +437,72 @@ func (doc Document) MarshalBinary() ([]byte, error) {
 				return nil, lazyerrors.Error(err)
 			}
 
+		case types.Decimal128:
+			bufw.WriteByte(byte(tagDecimal))
+			if err := ename.WriteTo(bufw); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+			if err := decimal128Type(elV).WriteTo(bufw); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+
+		case types.MinKeyType:
+			bufw.WriteByte(byte(tagMinKey))
+			if err := ename.WriteTo(bufw); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+			// skip calling WriteTo that does nothing
+
+		case types.MaxKeyType:
+			bufw.WriteByte(byte(tagMaxKey))
+			if err := ename.WriteTo(bufw); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+			// skip calling WriteTo that does nothing
+
+		case types.UndefinedType:
+			bufw.WriteByte(byte(tagUndefined))
+			if err := ename.WriteTo(bufw); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+			// skip calling WriteTo that does nothing
+
+		case types.DBPointer:
+			bufw.WriteByte(byte(tagDBPointer))
+			if err := ename.WriteTo(bufw); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+			if err := dbPointerType(elV).WriteTo(bufw); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+
+		case types.JavaScript:
+			bufw.WriteByte(byte(tagJavaScript))
+			if err := ename.WriteTo(bufw); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+			if err := javaScriptType(elV).WriteTo(bufw); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+
+		case types.Symbol:
+			bufw.WriteByte(byte(tagSymbol))
+			if err := ename.WriteTo(bufw); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+			if err := symbolType(elV).WriteTo(bufw); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+
+		case types.JavaScriptScope:
+			bufw.WriteByte(byte(tagJavaScriptScope))
+			if err := ename.WriteTo(bufw); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+			if err := javaScriptScopeType(elV).WriteTo(bufw); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+
 		default:
 			return nil, lazyerrors.Errorf("bson.Document.MarshalBinary: unhandled element type %T", elV)
 		}
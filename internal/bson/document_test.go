@@ -158,13 +158,65 @@ var (
 		b: testutil.MustParseDumpFile("testdata", "all.hex"),
 	}
 
+	decimal = testCase{
+		name: "decimal",
+		v: MustConvertDocument(types.MustNewDocument(
+			"d", types.Decimal128{H: 0x3040000000000000, L: 1},
+		)),
+		b: []byte{
+			0x18, 0x00, 0x00, 0x00, 0x13, 0x64, 0x00,
+			0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40, 0x30,
+			0x00,
+		},
+	}
+
+	deprecated = testCase{
+		name: "deprecated",
+		v: MustConvertDocument(types.MustNewDocument(
+			"min", types.MinKey,
+			"max", types.MaxKey,
+			"undefined", types.Undefined,
+			"symbol", types.Symbol("sym"),
+			"js", types.JavaScript("x"),
+			"dbpointer", types.DBPointer{
+				Namespace: "db.c",
+				ID:        types.ObjectID{0x62, 0x56, 0xc5, 0xba, 0x18, 0x2d, 0x4b, 0xa5, 0x07, 0x0f, 0x8b, 0x9c},
+			},
+			"scope", types.JavaScriptScope{Code: "x", Scope: types.MustNewDocument("x", int32(1))},
+		)),
+		b: []byte{
+			0x71, 0x00, 0x00, 0x00, 0xff, 0x6d, 0x69, 0x6e,
+			0x00, 0x7f, 0x6d, 0x61, 0x78, 0x00, 0x06, 0x75,
+			0x6e, 0x64, 0x65, 0x66, 0x69, 0x6e, 0x65, 0x64,
+			0x00, 0x0e, 0x73, 0x79, 0x6d, 0x62, 0x6f, 0x6c,
+			0x00, 0x04, 0x00, 0x00, 0x00, 0x73, 0x79, 0x6d,
+			0x00, 0x0d, 0x6a, 0x73, 0x00, 0x02, 0x00, 0x00,
+			0x00, 0x78, 0x00, 0x0c, 0x64, 0x62, 0x70, 0x6f,
+			0x69, 0x6e, 0x74, 0x65, 0x72, 0x00, 0x05, 0x00,
+			0x00, 0x00, 0x64, 0x62, 0x2e, 0x63, 0x00, 0x62,
+			0x56, 0xc5, 0xba, 0x18, 0x2d, 0x4b, 0xa5, 0x07,
+			0x0f, 0x8b, 0x9c, 0x0f, 0x73, 0x63, 0x6f, 0x70,
+			0x65, 0x00, 0x16, 0x00, 0x00, 0x00, 0x02, 0x00,
+			0x00, 0x00, 0x78, 0x00, 0x0c, 0x00, 0x00, 0x00,
+			0x10, 0x78, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00,
+			0x00,
+		},
+	}
+
 	eof = testCase{
 		name: "EOF",
 		b:    []byte{0x00},
 		bErr: `unexpected EOF`,
 	}
 
-	documentTestCases = []testCase{handshake1, handshake2, handshake3, handshake4, all, eof}
+	tooLarge = testCase{
+		name: "TooLarge",
+		b:    []byte{0x01, 0x00, 0x00, 0x01, 0x00},
+		bErr: `BSON document is too large`,
+	}
+
+	documentTestCases = []testCase{handshake1, handshake2, handshake3, handshake4, all, decimal, deprecated, eof, tooLarge}
 )
 
 func TestDocument(t *testing.T) {
@@ -0,0 +1,57 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bson
+
+import (
+	"bufio"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+)
+
+// javaScriptType represents deprecated BSON JavaScript code type.
+type javaScriptType types.JavaScript
+
+func (v *javaScriptType) bsontype() {}
+
+// ReadFrom implements bsontype interface.
+func (v *javaScriptType) ReadFrom(r *bufio.Reader) error {
+	var str stringType
+	if err := str.ReadFrom(r); err != nil {
+		return lazyerrors.Errorf("bson.JavaScript.ReadFrom: %w", err)
+	}
+
+	*v = javaScriptType(str)
+	return nil
+}
+
+// WriteTo implements bsontype interface.
+func (v javaScriptType) WriteTo(w *bufio.Writer) error {
+	if err := stringType(v).WriteTo(w); err != nil {
+		return lazyerrors.Errorf("bson.JavaScript.WriteTo: %w", err)
+	}
+
+	return nil
+}
+
+// MarshalBinary implements bsontype interface.
+func (v javaScriptType) MarshalBinary() ([]byte, error) {
+	return stringType(v).MarshalBinary()
+}
+
+// check interfaces
+var (
+	_ bsontype = (*javaScriptType)(nil)
+)
@@ -0,0 +1,44 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bson
+
+import (
+	"testing"
+
+	"github.com/AlekSi/pointer"
+)
+
+var javaScriptTestCases = []testCase{{
+	name: "code",
+	v:    pointer.To(javaScriptType("x")),
+	b:    []byte{0x02, 0x00, 0x00, 0x00, 0x78, 0x00},
+}, {
+	name: "EOF",
+	b:    []byte{0x00},
+	bErr: `unexpected EOF`,
+}}
+
+func TestJavaScript(t *testing.T) {
+	t.Parallel()
+	testBinary(t, javaScriptTestCases, func() bsontype { return new(javaScriptType) })
+}
+
+func FuzzJavaScript(f *testing.F) {
+	fuzzBinary(f, javaScriptTestCases, func() bsontype { return new(javaScriptType) })
+}
+
+func BenchmarkJavaScript(b *testing.B) {
+	benchmark(b, javaScriptTestCases, func() bsontype { return new(javaScriptType) })
+}
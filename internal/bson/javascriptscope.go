@@ -0,0 +1,128 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bson
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+)
+
+// minJavaScriptScopeLen is the length of code with scope with empty code and empty scope:
+// total length, code string length, code terminating zero, and empty document.
+const minJavaScriptScopeLen = 4 + 4 + 1 + minDocumentLen
+
+// javaScriptScopeType represents deprecated BSON JavaScript code with scope type.
+type javaScriptScopeType types.JavaScriptScope
+
+func (js *javaScriptScopeType) bsontype() {}
+
+// ReadFrom implements bsontype interface.
+func (js *javaScriptScopeType) ReadFrom(r *bufio.Reader) error {
+	var l int32
+	if err := binary.Read(r, binary.LittleEndian, &l); err != nil {
+		return lazyerrors.Errorf("bson.JavaScriptScope.ReadFrom (binary.Read): %w", err)
+	}
+	if l < minJavaScriptScopeLen {
+		return lazyerrors.Errorf("bson.JavaScriptScope.ReadFrom: invalid length %d", l)
+	}
+	if l > types.MaxDocumentLen {
+		return lazyerrors.Errorf("bson.JavaScriptScope.ReadFrom: length %d: %w", l, ErrDocumentTooLarge)
+	}
+
+	b := make([]byte, l-4)
+	if n, err := io.ReadFull(r, b); err != nil {
+		return lazyerrors.Errorf("bson.JavaScriptScope.ReadFrom: expected %d, read %d: %w", len(b), n, err)
+	}
+
+	br := bytes.NewReader(b)
+	bufr := bufio.NewReader(br)
+
+	var code stringType
+	if err := code.ReadFrom(bufr); err != nil {
+		return lazyerrors.Errorf("bson.JavaScriptScope.ReadFrom (code): %w", err)
+	}
+
+	var scope Document
+	if err := scope.ReadFrom(bufr); err != nil {
+		return lazyerrors.Errorf("bson.JavaScriptScope.ReadFrom (scope): %w", err)
+	}
+
+	if rest := bufr.Buffered() + br.Len(); rest != 0 {
+		return lazyerrors.Errorf("bson.JavaScriptScope.ReadFrom: %d extra bytes", rest)
+	}
+
+	doc, err := types.ConvertDocument(&scope)
+	if err != nil {
+		return lazyerrors.Errorf("bson.JavaScriptScope.ReadFrom (scope): %w", err)
+	}
+
+	*js = javaScriptScopeType{
+		Code:  string(code),
+		Scope: doc,
+	}
+	return nil
+}
+
+// WriteTo implements bsontype interface.
+func (js javaScriptScopeType) WriteTo(w *bufio.Writer) error {
+	v, err := js.MarshalBinary()
+	if err != nil {
+		return lazyerrors.Errorf("bson.JavaScriptScope.WriteTo: %w", err)
+	}
+
+	_, err = w.Write(v)
+	if err != nil {
+		return lazyerrors.Errorf("bson.JavaScriptScope.WriteTo: %w", err)
+	}
+
+	return nil
+}
+
+// MarshalBinary implements bsontype interface.
+func (js javaScriptScopeType) MarshalBinary() ([]byte, error) {
+	code, err := stringType(js.Code).MarshalBinary()
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	scope := new(Document)
+	if js.Scope != nil {
+		if scope, err = ConvertDocument(js.Scope); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+	}
+
+	doc, err := scope.MarshalBinary()
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, int32(4+len(code)+len(doc)))
+	buf.Write(code)
+	buf.Write(doc)
+
+	return buf.Bytes(), nil
+}
+
+// check interfaces
+var (
+	_ bsontype = (*javaScriptScopeType)(nil)
+)
@@ -0,0 +1,46 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bson
+
+import (
+	"bufio"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+)
+
+// maxKeyType represents BSON MaxKey type.
+type maxKeyType types.MaxKeyType
+
+func (*maxKeyType) bsontype() {}
+
+// ReadFrom implements bsontype interface.
+func (*maxKeyType) ReadFrom(r *bufio.Reader) error {
+	return nil
+}
+
+// WriteTo implements bsontype interface.
+func (maxKeyType) WriteTo(w *bufio.Writer) error {
+	return nil
+}
+
+// MarshalBinary implements bsontype interface.
+func (maxKeyType) MarshalBinary() ([]byte, error) {
+	return nil, nil
+}
+
+// check interfaces
+var (
+	_ bsontype = (*maxKeyType)(nil)
+)
@@ -20,6 +20,7 @@ import (
 	"encoding/binary"
 	"io"
 
+	"github.com/FerretDB/FerretDB/internal/types"
 	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
 )
 
@@ -37,6 +38,9 @@ func (str *stringType) ReadFrom(r *bufio.Reader) error {
 	if l <= 0 {
 		return lazyerrors.Errorf("invalid length %d", l)
 	}
+	if l > types.MaxDocumentLen {
+		return lazyerrors.Errorf("length %d: %w", l, ErrDocumentTooLarge)
+	}
 
 	b := make([]byte, l)
 	if n, err := io.ReadFull(r, b); err != nil {
@@ -36,6 +36,10 @@ var stringTestCases = []testCase{{
 	name: "EOF",
 	b:    []byte{0x00},
 	bErr: `unexpected EOF`,
+}, {
+	name: "TooLarge",
+	b:    []byte{0x01, 0x00, 0x00, 0x01, 0x00},
+	bErr: `BSON document is too large`,
 }}
 
 func TestString(t *testing.T) {
@@ -27,10 +27,13 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/FerretDB/FerretDB/internal/handlers"
+	"github.com/FerretDB/FerretDB/internal/handlers/common"
 	"github.com/FerretDB/FerretDB/internal/handlers/jsonb1"
 	"github.com/FerretDB/FerretDB/internal/handlers/proxy"
 	"github.com/FerretDB/FerretDB/internal/handlers/sql"
 	"github.com/FerretDB/FerretDB/internal/pg"
+	"github.com/FerretDB/FerretDB/internal/recorder"
+	"github.com/FerretDB/FerretDB/internal/types"
 	"github.com/FerretDB/FerretDB/internal/wire"
 )
 
@@ -60,6 +63,12 @@ type conn struct {
 	h       *handlers.Handler
 	proxy   *proxy.Handler
 	l       *zap.SugaredLogger
+
+	// compressors negotiated with the client by hello or isMaster command
+	compressors []wire.Compressor
+
+	connID   int64
+	recorder *recorder.Recorder // nil if recording is disabled
 }
 
 // newConnOpts represents newConn options.
@@ -69,7 +78,10 @@ type newConnOpts struct {
 	proxyAddr       string
 	mode            Mode
 	handlersMetrics *handlers.Metrics
+	cursors         *common.Cursors
 	startTime       time.Time
+	connID          int64
+	recorder        *recorder.Recorder
 }
 
 // newConn creates a new client connection for given net.Conn.
@@ -77,9 +89,8 @@ func newConn(opts *newConnOpts) (*conn, error) {
 	prefix := fmt.Sprintf("// %s -> %s ", opts.netConn.RemoteAddr(), opts.netConn.LocalAddr())
 	l := zap.L().Named(prefix)
 
-	peerAddr := opts.netConn.RemoteAddr().String()
-	sqlH := sql.NewStorage(opts.pgPool, l.Sugar())
-	jsonb1H := jsonb1.NewStorage(opts.pgPool, l)
+	sqlH := sql.NewStorage(opts.pgPool, l.Sugar(), opts.cursors)
+	jsonb1H := jsonb1.NewStorage(opts.pgPool, l, opts.cursors)
 
 	var p *proxy.Handler
 	if opts.mode != NormalMode {
@@ -92,26 +103,52 @@ func newConn(opts *newConnOpts) (*conn, error) {
 	handlerOpts := &handlers.NewOpts{
 		PgPool:        opts.pgPool,
 		Logger:        l,
-		PeerAddr:      peerAddr,
+		PeerAddr:      peerAddr(opts.netConn),
 		SQLStorage:    sqlH,
 		JSONB1Storage: jsonb1H,
+		Cursors:       opts.cursors,
 		Metrics:       opts.handlersMetrics,
 		StartTime:     opts.startTime,
 	}
 	return &conn{
-		netConn: opts.netConn,
-		mode:    opts.mode,
-		h:       handlers.New(handlerOpts),
-		proxy:   p,
-		l:       l.Sugar(),
+		netConn:  opts.netConn,
+		mode:     opts.mode,
+		h:        handlers.New(handlerOpts),
+		proxy:    p,
+		l:        l.Sugar(),
+		connID:   opts.connID,
+		recorder: opts.recorder,
 	}, nil
 }
 
+// peerAddr returns the client address for whatsMyURI command.
+//
+// Unix socket clients are usually unnamed, so the socket path is used instead, like mongod does.
+func peerAddr(netConn net.Conn) string {
+	addr, ok := netConn.RemoteAddr().(*net.UnixAddr)
+	if !ok {
+		return netConn.RemoteAddr().String()
+	}
+
+	if addr.Name != "" && addr.Name != "@" {
+		return addr.Name
+	}
+
+	if local, ok := netConn.LocalAddr().(*net.UnixAddr); ok && local.Name != "" {
+		return local.Name
+	}
+
+	return "anonymous unix socket"
+}
+
 // run runs the client connection until ctx is canceled, client disconnects,
 // or fatal error or panic is encountered.
 //
 // The caller is responsible for closing the underlying net.Conn.
 func (c *conn) run(ctx context.Context) (err error) {
+	// the current request and responses sent to it; recorded even if handling fails or panics
+	var rec *recorder.Record
+
 	done := make(chan struct{})
 	defer func() {
 		if p := recover(); p != nil {
@@ -120,6 +157,8 @@ func (c *conn) run(ctx context.Context) (err error) {
 			err = errors.New("panic")
 		}
 
+		c.record(rec)
+
 		if err == nil {
 			err = ctx.Err()
 		}
@@ -153,40 +192,85 @@ func (c *conn) run(ctx context.Context) (err error) {
 	for {
 		var reqHeader *wire.MsgHeader
 		var reqBody wire.MsgBody
-		reqHeader, reqBody, err = wire.ReadMessage(bufr)
+		var raw []byte
+		reqHeader, reqBody, raw, err = wire.ReadMessageRaw(bufr)
 		if err != nil {
-			return
+			if !errors.As(err, new(*wire.ValidationError)) {
+				return
+			}
+
+			rec = c.newRecord(reqHeader, nil, raw)
+			if err = c.writeValidationError(bufw, rec, reqHeader, err); err != nil {
+				return
+			}
+
+			c.record(rec)
+			rec = nil
+			continue
 		}
 
+		rec = c.newRecord(reqHeader, reqBody, raw)
+
 		// do not spend time dumping if we are not going to log it
 		if c.l.Desugar().Core().Enabled(zap.DebugLevel) {
 			c.l.Debugf("Request header: %s", reqHeader)
 			c.l.Debugf("Request message:\n%s\n\n\n", reqBody)
 		}
 
+		// handler gets decompressed request, proxy gets it as-is
+		msgHeader, msgBody := reqHeader, reqBody
+		compressed, _ := reqBody.(*wire.OpCompressed)
+		if compressed != nil {
+			if msgHeader, msgBody, err = wire.Decompress(reqHeader, compressed); err != nil {
+				if !errors.As(err, new(*wire.ValidationError)) {
+					return
+				}
+
+				if err = c.writeValidationError(bufw, rec, msgHeader, err); err != nil {
+					return
+				}
+
+				c.record(rec)
+				rec = nil
+				continue
+			}
+
+			// do not spend time dumping if we are not going to log it
+			if c.l.Desugar().Core().Enabled(zap.DebugLevel) {
+				c.l.Debugf("Decompressed request header: %s", msgHeader)
+				c.l.Debugf("Decompressed request message:\n%s\n\n\n", msgBody)
+			}
+		}
+
+		noReply := !expectsReply(msgBody)
+
 		// handle request unless we are in proxy mode
 		var resHeader *wire.MsgHeader
 		var resBody wire.MsgBody
-		var closeConn bool
+		var resMoreToCome, closeConn bool
 		if c.mode != ProxyMode {
-			resHeader, resBody, closeConn = c.h.Handle(ctx, reqHeader, reqBody)
-
-			// do not spend time dumping if we are not going to log it
-			if c.l.Desugar().Core().Enabled(zap.DebugLevel) {
-				c.l.Debugf("Response header: %s", resHeader)
-				c.l.Debugf("Response message:\n%s\n\n\n", resBody)
+			resHeader, resBody, resMoreToCome, closeConn, err = c.handle(ctx, msgHeader, msgBody, compressed)
+			if err != nil {
+				return
 			}
+
+			c.negotiateCompressors(msgBody)
 		}
 
 		// send request to proxy unless we are in normal mode
 		var proxyHeader *wire.MsgHeader
 		var proxyBody wire.MsgBody
+		var proxyMoreToCome bool
 		if c.mode != NormalMode {
 			if c.proxy == nil {
 				panic("proxy addr was nil")
 			}
 
-			proxyHeader, proxyBody, err = c.proxy.Handle(ctx, reqHeader, reqBody)
+			if noReply {
+				err = c.proxy.Send(ctx, reqHeader, reqBody)
+			} else {
+				proxyHeader, proxyBody, err = c.proxy.Handle(ctx, reqHeader, reqBody)
+			}
 			if err != nil {
 				c.l.Warnf("Proxy returned error, closing connection: %s.", err)
 				return
@@ -197,59 +281,280 @@ func (c *conn) run(ctx context.Context) (err error) {
 				c.l.Debugf("Proxy header: %s", proxyHeader)
 				c.l.Debugf("Proxy message:\n%s\n\n\n", proxyBody)
 			}
+
+			proxyMoreToCome = replyMoreToCome(proxyHeader, proxyBody)
 		}
 
-		// diff in diff mode
-		if c.mode == DiffNormalMode || c.mode == DiffProxyMode {
-			var diffHeader string
-			diffHeader, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
-				A:        difflib.SplitLines(resHeader.String()),
-				FromFile: "res header",
-				B:        difflib.SplitLines(proxyHeader.String()),
-				ToFile:   "proxy header",
-				Context:  1,
-			})
-			if err != nil {
+		if noReply {
+			if closeConn {
+				err = errors.New("internal error")
 				return
 			}
 
-			var diffBody string
-			diffBody, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
-				A:        difflib.SplitLines(resBody.String()),
-				FromFile: "res body",
-				B:        difflib.SplitLines(proxyBody.String()),
-				ToFile:   "proxy body",
-				Context:  1,
-			})
-			if err != nil {
+			c.record(rec)
+			rec = nil
+			continue
+		}
+
+		// both handler and proxy may send several replies with moreToCome flag (for exhaust cursors)
+		for {
+			// diff in diff mode
+			if c.mode == DiffNormalMode || c.mode == DiffProxyMode {
+				if err = c.logDiff(resHeader, proxyHeader, resBody, proxyBody); err != nil {
+					return
+				}
+			}
+
+			// send response from proxy in proxy and diff-proxy modes
+			sendHeader, sendBody, sendMoreToCome := resHeader, resBody, resMoreToCome
+			if c.mode == ProxyMode || c.mode == DiffProxyMode {
+				sendHeader, sendBody, sendMoreToCome = proxyHeader, proxyBody, proxyMoreToCome
+			}
+
+			if sendHeader == nil || sendBody == nil {
+				c.l.Info("no response to send to client")
 				return
 			}
 
-			c.l.Infof("Header diff:\n%s\nBody diff:\n%s\n\n", diffHeader, diffBody)
-		}
+			if err = c.write(bufw, rec, sendHeader, sendBody); err != nil {
+				return
+			}
+
+			if closeConn {
+				err = errors.New("internal error")
+				return
+			}
+
+			if !sendMoreToCome {
+				break
+			}
+
+			// the next handler reply is a response to the previous one
+			if resMoreToCome {
+				nextHeader := &wire.MsgHeader{
+					MessageLength: msgHeader.MessageLength,
+					RequestID:     resHeader.RequestID,
+					OpCode:        msgHeader.OpCode,
+				}
+				resHeader, resBody, resMoreToCome, closeConn, err = c.handle(ctx, nextHeader, msgBody, compressed)
+				if err != nil {
+					return
+				}
+			} else {
+				resHeader, resBody = nil, nil
+			}
 
-		// replace response with one from proxy in proxy and diff-proxy modes
-		if c.mode == ProxyMode || c.mode == DiffProxyMode {
-			resHeader = proxyHeader
-			resBody = proxyBody
+			if proxyMoreToCome {
+				if proxyHeader, proxyBody, err = c.proxy.Receive(ctx); err != nil {
+					c.l.Warnf("Proxy returned error, closing connection: %s.", err)
+					return
+				}
+
+				// do not spend time dumping if we are not going to log it
+				if c.l.Desugar().Core().Enabled(zap.DebugLevel) {
+					c.l.Debugf("Proxy header: %s", proxyHeader)
+					c.l.Debugf("Proxy message:\n%s\n\n\n", proxyBody)
+				}
+
+				proxyMoreToCome = replyMoreToCome(proxyHeader, proxyBody)
+			} else {
+				proxyHeader, proxyBody = nil, nil
+			}
 		}
 
-		if resHeader == nil || resBody == nil {
-			c.l.Info("no response to send to client")
-			return
+		// proxy may send more replies than handler in diff-normal mode, skip them
+		for proxyMoreToCome {
+			if proxyHeader, proxyBody, err = c.proxy.Receive(ctx); err != nil {
+				c.l.Warnf("Proxy returned error, closing connection: %s.", err)
+				return
+			}
+
+			proxyMoreToCome = replyMoreToCome(proxyHeader, proxyBody)
 		}
 
-		if err = wire.WriteMessage(bufw, resHeader, resBody); err != nil {
+		c.record(rec)
+		rec = nil
+	}
+}
+
+// newRecord returns a new record for the request read from the client.
+func (c *conn) newRecord(header *wire.MsgHeader, body wire.MsgBody, raw []byte) *recorder.Record {
+	return &recorder.Record{
+		ConnID:  c.connID,
+		Request: recorder.Message{Time: time.Now(), Header: header, Body: body, Raw: raw},
+	}
+}
+
+// record writes the request and responses sent to it if recording is enabled and rec is not nil.
+func (c *conn) record(rec *recorder.Record) {
+	if c.recorder == nil || rec == nil {
+		return
+	}
+
+	if err := c.recorder.Write(rec); err != nil {
+		c.l.Warnf("Failed to record request: %s.", err)
+	}
+}
+
+// handle handles the request by the handler.
+//
+// The response is compressed with the same compressor as the request
+// if the request was compressed and that compressor was negotiated.
+// Returned moreToCome is true if the response has moreToCome flag set.
+//
+//nolint:lll // arguments are long
+func (c *conn) handle(ctx context.Context, reqHeader *wire.MsgHeader, reqBody wire.MsgBody, compressed *wire.OpCompressed) (resHeader *wire.MsgHeader, resBody wire.MsgBody, moreToCome, closeConn bool, err error) {
+	resHeader, resBody, closeConn = c.h.Handle(ctx, reqHeader, reqBody)
+
+	// do not spend time dumping if we are not going to log it
+	if c.l.Desugar().Core().Enabled(zap.DebugLevel) {
+		c.l.Debugf("Response header: %s", resHeader)
+		c.l.Debugf("Response message:\n%s\n\n\n", resBody)
+	}
+
+	moreToCome = replyMoreToCome(resHeader, resBody)
+
+	// reply with the same compressor as the request if it was negotiated
+	if compressed != nil && resHeader != nil && resBody != nil && c.compressorNegotiated(compressed.Compressor) {
+		if resHeader, resBody, err = wire.Compress(compressed.Compressor, resHeader, resBody); err != nil {
 			return
 		}
+	}
 
-		if err = bufw.Flush(); err != nil {
-			return
+	return
+}
+
+// logDiff logs the difference between handler and proxy responses.
+func (c *conn) logDiff(resHeader, proxyHeader *wire.MsgHeader, resBody, proxyBody wire.MsgBody) error {
+	diffHeader, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(resHeader.String()),
+		FromFile: "res header",
+		B:        difflib.SplitLines(proxyHeader.String()),
+		ToFile:   "proxy header",
+		Context:  1,
+	})
+	if err != nil {
+		return err
+	}
+
+	diffBody, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(bodyString(resBody)),
+		FromFile: "res body",
+		B:        difflib.SplitLines(bodyString(proxyBody)),
+		ToFile:   "proxy body",
+		Context:  1,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.l.Infof("Header diff:\n%s\nBody diff:\n%s\n\n", diffHeader, diffBody)
+
+	return nil
+}
+
+// bodyString returns a string representation of the message body that could be nil.
+func bodyString(body wire.MsgBody) string {
+	if body == nil {
+		return "<nil>"
+	}
+
+	return body.String()
+}
+
+// expectsReply returns false if the client does not expect a response for the given request:
+// OP_MSG with moreToCome flag, or legacy OP_INSERT, OP_UPDATE, OP_DELETE, and OP_KILL_CURSORS.
+func expectsReply(body wire.MsgBody) bool {
+	switch body := body.(type) {
+	case *wire.OpMsg:
+		return !body.FlagBits.FlagSet(wire.OpMsgMoreToCome)
+	case *wire.OpInsert, *wire.OpUpdate, *wire.OpDelete, *wire.OpKillCursors:
+		return false
+	default:
+		return true
+	}
+}
+
+// replyMoreToCome returns true if the given reply (possibly compressed) is OP_MSG with moreToCome flag set.
+func replyMoreToCome(header *wire.MsgHeader, body wire.MsgBody) bool {
+	if compressed, ok := body.(*wire.OpCompressed); ok {
+		var err error
+		if _, body, err = wire.Decompress(header, compressed); err != nil {
+			return false
 		}
+	}
 
-		if closeConn {
-			err = errors.New("internal error")
-			return
+	msg, ok := body.(*wire.OpMsg)
+	return ok && msg.FlagBits.FlagSet(wire.OpMsgMoreToCome)
+}
+
+// writeValidationError replies to the request that was read completely, but failed validation.
+//
+// It returns validationErr if there is no way to reply, and the connection should be closed.
+func (c *conn) writeValidationError(bufw *bufio.Writer, rec *recorder.Record, header *wire.MsgHeader, validationErr error) error {
+	c.l.Warnf("Request failed validation: %s.", validationErr)
+
+	resHeader, resBody := c.h.HandleValidationError(header, validationErr)
+	if resHeader == nil || resBody == nil {
+		return validationErr
+	}
+
+	// do not spend time dumping if we are not going to log it
+	if c.l.Desugar().Core().Enabled(zap.DebugLevel) {
+		c.l.Debugf("Response header: %s", resHeader)
+		c.l.Debugf("Response message:\n%s\n\n\n", resBody)
+	}
+
+	return c.write(bufw, rec, resHeader, resBody)
+}
+
+// write sends the response to the client and adds it to the record exactly as it was sent.
+func (c *conn) write(bufw *bufio.Writer, rec *recorder.Record, resHeader *wire.MsgHeader, resBody wire.MsgBody) error {
+	raw, err := wire.MarshalMessage(resHeader, resBody)
+	if err != nil {
+		return err
+	}
+
+	if _, err = bufw.Write(raw); err != nil {
+		return err
+	}
+
+	if err = bufw.Flush(); err != nil {
+		return err
+	}
+
+	rec.Responses = append(rec.Responses, recorder.Message{Time: time.Now(), Header: resHeader, Body: resBody, Raw: raw})
+	return nil
+}
+
+// negotiateCompressors remembers compressors negotiated by hello or isMaster command.
+func (c *conn) negotiateCompressors(body wire.MsgBody) {
+	var document *types.Document
+	switch body := body.(type) {
+	case *wire.OpMsg:
+		document, _ = body.Document()
+	case *wire.OpQuery:
+		// legacy drivers may wrap the command into $query
+		document, _ = handlers.UnwrapQuery(body.Query)
+	}
+
+	if document.Len() == 0 {
+		return
+	}
+
+	switch document.Command() {
+	case "hello", "ismaster":
+		c.compressors = wire.NegotiateCompressors(document)
+	}
+}
+
+// compressorNegotiated returns true if the given compressor was negotiated with the client.
+func (c *conn) compressorNegotiated(compressor wire.Compressor) bool {
+	for _, negotiated := range c.compressors {
+		if negotiated == compressor {
+			return true
 		}
 	}
+
+	return false
 }
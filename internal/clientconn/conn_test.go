@@ -0,0 +1,426 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientconn
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/FerretDB/FerretDB/internal/handlers"
+	"github.com/FerretDB/FerretDB/internal/handlers/common"
+	"github.com/FerretDB/FerretDB/internal/recorder"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+	"github.com/FerretDB/FerretDB/internal/util/testutil"
+	"github.com/FerretDB/FerretDB/internal/wire"
+)
+
+// startTestConn runs a new client connection with the given options (netConn is set to one end of a pipe)
+// and returns the other end of the pipe for the client, and a channel closed when the connection stops running.
+//
+// Connection mode defaults to normal; PostgreSQL pool is not required in proxy mode.
+func startTestConn(t *testing.T, opts *newConnOpts) (net.Conn, <-chan struct{}) {
+	t.Helper()
+
+	clientConn, serverConn := net.Pipe()
+
+	opts.netConn = serverConn
+	if opts.mode == "" {
+		opts.mode = NormalMode
+	}
+	opts.handlersMetrics = handlers.NewMetrics()
+	opts.cursors = common.NewCursors(zaptest.NewLogger(t), common.DefaultCursorTimeout)
+
+	c, err := newConn(opts)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = c.run(testutil.Ctx(t))
+		serverConn.Close()
+	}()
+
+	// close open cursors before the pool is closed
+	t.Cleanup(func() {
+		clientConn.Close()
+		<-done
+		opts.cursors.Close()
+	})
+
+	return clientConn, done
+}
+
+// marshalMessage returns the whole message with the given opcode, request ID and body.
+func marshalMessage(t *testing.T, opCode wire.OpCode, requestID int32, body wire.MsgBody) []byte {
+	t.Helper()
+
+	b, err := body.MarshalBinary()
+	require.NoError(t, err)
+
+	header := &wire.MsgHeader{
+		MessageLength: int32(wire.MsgHeaderLen + len(b)),
+		RequestID:     requestID,
+		OpCode:        opCode,
+	}
+
+	raw, err := wire.MarshalMessage(header, body)
+	require.NoError(t, err)
+
+	return raw
+}
+
+func TestConnRecord(t *testing.T) {
+	t.Parallel()
+
+	ping := must.NotFail(types.NewDocument("ping", int32(1), "$db", "admin"))
+
+	for name, tc := range map[string]struct {
+		req       func(t *testing.T) []byte
+		responses int
+	}{
+		"ChecksumMismatch": {
+			req: func(t *testing.T) []byte {
+				msg := &wire.OpMsg{FlagBits: wire.OpMsgFlags(wire.OpMsgChecksumPresent)}
+				require.NoError(t, msg.SetSections(wire.OpMsgSection{Documents: []*types.Document{ping}}))
+
+				raw := marshalMessage(t, wire.OP_MSG, 1, msg)
+				raw[len(raw)-1]++
+				return raw
+			},
+			responses: 1,
+		},
+		"Panic": {
+			// handler panics on OP_REPLY from the client
+			req: func(t *testing.T) []byte {
+				return marshalMessage(t, wire.OP_REPLY, 1, &wire.OpReply{
+					NumberReturned: 1,
+					Documents:      []*types.Document{ping},
+				})
+			},
+		},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := recorder.New(t.TempDir())
+			require.NoError(t, err)
+
+			clientConn, done := startTestConn(t, &newConnOpts{recorder: r})
+
+			req := tc.req(t)
+			_, err = clientConn.Write(req)
+			require.NoError(t, err)
+
+			bufr := bufio.NewReader(clientConn)
+			responses := make([][]byte, tc.responses)
+			for i := range responses {
+				_, _, responses[i], err = wire.ReadMessageRaw(bufr)
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, clientConn.Close())
+			<-done
+			require.NoError(t, r.Close())
+
+			f, err := os.Open(r.Path())
+			require.NoError(t, err)
+			t.Cleanup(func() { f.Close() })
+
+			// request is recorded exactly as it was sent, responses - as they were received
+			rec, err := recorder.ReadRecord(bufio.NewReader(f))
+			require.NoError(t, err)
+			assert.Equal(t, req, rec.Request.Raw)
+			require.Len(t, rec.Responses, len(responses))
+			for i := range responses {
+				assert.Equal(t, responses[i], rec.Responses[i].Raw)
+			}
+		})
+	}
+}
+
+// writeMsg sends OP_MSG with the given request ID, flags and document.
+func writeMsg(t *testing.T, netConn net.Conn, requestID int32, flags wire.OpMsgFlags, doc *types.Document) {
+	t.Helper()
+
+	msg := &wire.OpMsg{FlagBits: flags}
+	require.NoError(t, msg.SetSections(wire.OpMsgSection{Documents: []*types.Document{doc}}))
+
+	_, err := netConn.Write(marshalMessage(t, wire.OP_MSG, requestID, msg))
+	require.NoError(t, err)
+}
+
+// readReplies reads OP_MSG replies until the one without moreToCome flag.
+func readReplies(t *testing.T, bufr *bufio.Reader) ([]*wire.MsgHeader, []*wire.OpMsg) {
+	t.Helper()
+
+	var headers []*wire.MsgHeader
+	var msgs []*wire.OpMsg
+	for i := 0; i < 100; i++ {
+		header, body, err := wire.ReadMessage(bufr)
+		require.NoError(t, err)
+
+		msg, ok := body.(*wire.OpMsg)
+		require.True(t, ok, "%s", body)
+
+		headers = append(headers, header)
+		msgs = append(msgs, msg)
+
+		if !msg.FlagBits.FlagSet(wire.OpMsgMoreToCome) {
+			return headers, msgs
+		}
+	}
+
+	t.Fatal("too many replies")
+	return nil, nil
+}
+
+// checkExhaust checks that replies to the request with the given ID form exhaust cursor chain:
+// each reply is a response to the previous one, and all but the last have moreToCome flag set.
+func checkExhaust(t *testing.T, requestID int32, headers []*wire.MsgHeader, msgs []*wire.OpMsg) {
+	t.Helper()
+
+	require.Greater(t, len(msgs), 1)
+
+	responseTo := requestID
+	for i, header := range headers {
+		assert.Equal(t, responseTo, header.ResponseTo, "reply %d", i)
+		assert.Equal(t, i < len(headers)-1, msgs[i].FlagBits.FlagSet(wire.OpMsgMoreToCome), "reply %d", i)
+		responseTo = header.RequestID
+	}
+}
+
+// nextBatch returns the cursor ID and nextBatch documents' _id values of getMore reply.
+func nextBatch(t *testing.T, msg *wire.OpMsg) (int64, []any) {
+	t.Helper()
+
+	doc, err := msg.Document()
+	require.NoError(t, err)
+
+	cursor, ok := doc.Map()["cursor"].(*types.Document)
+	require.True(t, ok, "%s", doc)
+
+	batch := cursor.Map()["nextBatch"].(*types.Array)
+	ids := make([]any, batch.Len())
+	for i := range ids {
+		ids[i] = must.NotFail(batch.Get(i)).(*types.Document).Map()["_id"]
+	}
+
+	return cursor.Map()["id"].(int64), ids
+}
+
+func TestConnExhaust(t *testing.T) {
+	t.Parallel()
+
+	ctx := testutil.Ctx(t)
+	pool := testutil.Pool(ctx, t, nil)
+	db := testutil.Schema(ctx, t, pool)
+	collection := testutil.CreateTable(ctx, t, pool, db)
+
+	clientConn, _ := startTestConn(t, &newConnOpts{pgPool: pool})
+	bufr := bufio.NewReader(clientConn)
+
+	docs := new(types.Array)
+	for i := int32(1); i <= 5; i++ {
+		require.NoError(t, docs.Append(must.NotFail(types.NewDocument("_id", i))))
+	}
+	writeMsg(t, clientConn, 1, 0, must.NotFail(types.NewDocument(
+		"insert", collection,
+		"documents", docs,
+		"$db", db,
+	)))
+	readReplies(t, bufr)
+
+	writeMsg(t, clientConn, 2, 0, must.NotFail(types.NewDocument(
+		"find", collection,
+		"sort", must.NotFail(types.NewDocument("_id", int32(1))),
+		"batchSize", int32(1),
+		"$db", db,
+	)))
+	_, msgs := readReplies(t, bufr)
+	doc, err := msgs[0].Document()
+	require.NoError(t, err)
+	cursorID := must.NotFail(doc.GetByPath("cursor", "id")).(int64)
+	require.NotZero(t, cursorID)
+
+	writeMsg(t, clientConn, 3, wire.OpMsgFlags(wire.OpMsgExhaustAllowed), must.NotFail(types.NewDocument(
+		"getMore", cursorID,
+		"collection", collection,
+		"batchSize", int32(1),
+		"$db", db,
+	)))
+	headers, msgs := readReplies(t, bufr)
+	checkExhaust(t, 3, headers, msgs)
+
+	var ids []any
+	for i, msg := range msgs {
+		id, batch := nextBatch(t, msg)
+		ids = append(ids, batch...)
+
+		// the last reply closes the cursor
+		assert.Equal(t, i == len(msgs)-1, id == 0, "reply %d", i)
+	}
+	assert.Equal(t, []any{int32(2), int32(3), int32(4), int32(5)}, ids)
+}
+
+// startUpstream starts a wire protocol server that replies to each OP_MSG request
+// with the given number of getMore replies, all but the last with moreToCome flag set;
+// it returns the server address.
+func startUpstream(t *testing.T, replies int) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	go func() {
+		netConn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer netConn.Close()
+
+		bufr := bufio.NewReader(netConn)
+		bufw := bufio.NewWriter(netConn)
+
+		var requestID int32
+		for {
+			reqHeader, _, err := wire.ReadMessage(bufr)
+			if err != nil {
+				return
+			}
+
+			responseTo := reqHeader.RequestID
+			for i := 0; i < replies; i++ {
+				var cursorID int64
+				var msg wire.OpMsg
+				if i < replies-1 {
+					cursorID = 42
+					msg.FlagBits = wire.OpMsgFlags(wire.OpMsgMoreToCome)
+				}
+
+				must.NoError(msg.SetSections(wire.OpMsgSection{
+					Documents: []*types.Document{must.NotFail(types.NewDocument(
+						"cursor", must.NotFail(types.NewDocument(
+							"nextBatch", must.NotFail(types.NewArray(must.NotFail(types.NewDocument("_id", int32(i))))),
+							"id", cursorID,
+							"ns", "test.test",
+						)),
+						"ok", float64(1),
+					))},
+				}))
+
+				requestID++
+				header := &wire.MsgHeader{
+					MessageLength: int32(wire.MsgHeaderLen + len(must.NotFail(msg.MarshalBinary()))),
+					RequestID:     requestID,
+					ResponseTo:    responseTo,
+					OpCode:        wire.OP_MSG,
+				}
+				if err = wire.WriteMessage(bufw, header, &msg); err != nil {
+					return
+				}
+				responseTo = requestID
+			}
+
+			if err = bufw.Flush(); err != nil {
+				return
+			}
+		}
+	}()
+
+	return lis.Addr().String()
+}
+
+func TestConnExhaustProxy(t *testing.T) {
+	t.Parallel()
+
+	clientConn, _ := startTestConn(t, &newConnOpts{
+		mode:      ProxyMode,
+		proxyAddr: startUpstream(t, 3),
+	})
+	bufr := bufio.NewReader(clientConn)
+
+	writeMsg(t, clientConn, 7, wire.OpMsgFlags(wire.OpMsgExhaustAllowed), must.NotFail(types.NewDocument(
+		"getMore", int64(42),
+		"collection", "test",
+		"$db", "test",
+	)))
+	headers, msgs := readReplies(t, bufr)
+	require.Len(t, msgs, 3)
+	checkExhaust(t, 7, headers, msgs)
+
+	for i, msg := range msgs {
+		id, ids := nextBatch(t, msg)
+		assert.Equal(t, []any{int32(i)}, ids)
+		assert.Equal(t, i == len(msgs)-1, id == 0, "reply %d", i)
+	}
+}
+
+func TestNegotiateCompressors(t *testing.T) {
+	t.Parallel()
+
+	hello := must.NotFail(types.NewDocument(
+		"isMaster", int32(1),
+		"compression", must.NotFail(types.NewArray("zstd", "unknown", "snappy")),
+	))
+	expected := []wire.Compressor{wire.CompressorZstd, wire.CompressorSnappy}
+
+	var msg wire.OpMsg
+	require.NoError(t, msg.SetSections(wire.OpMsgSection{Documents: []*types.Document{hello}}))
+
+	for name, tc := range map[string]struct {
+		body     wire.MsgBody
+		expected []wire.Compressor
+	}{
+		"OpMsg": {
+			body:     &msg,
+			expected: expected,
+		},
+		"OpQuery": {
+			body:     &wire.OpQuery{FullCollectionName: "admin.$cmd", Query: hello},
+			expected: expected,
+		},
+		"OpQueryWrapped": {
+			body: &wire.OpQuery{FullCollectionName: "admin.$cmd", Query: must.NotFail(types.NewDocument(
+				"$query", hello,
+				"$readPreference", must.NotFail(types.NewDocument("mode", "secondaryPreferred")),
+			))},
+			expected: expected,
+		},
+		"OpQueryOtherCommand": {
+			body: &wire.OpQuery{FullCollectionName: "admin.$cmd", Query: must.NotFail(types.NewDocument(
+				"ping", int32(1),
+				"compression", must.NotFail(types.NewArray("zstd")),
+			))},
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var c conn
+			c.negotiateCompressors(tc.body)
+			assert.Equal(t, tc.expected, c.compressors)
+		})
+	}
+}
@@ -19,88 +19,277 @@ import (
 	"crypto/tls"
 	"errors"
 	"io"
+	"io/fs"
 	"net"
+	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
 	"github.com/FerretDB/FerretDB/internal/handlers"
+	"github.com/FerretDB/FerretDB/internal/handlers/common"
 	"github.com/FerretDB/FerretDB/internal/pg"
+	"github.com/FerretDB/FerretDB/internal/recorder"
 	"github.com/FerretDB/FerretDB/internal/util/ctxutil"
 	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
 )
 
 // Listener accepts incoming client connections.
 type Listener struct {
+	lastConnID      int64 // accessed atomically; first field for 64-bit alignment
 	opts            *NewListenerOpts
 	metrics         *ListenerMetrics
 	handlersMetrics *handlers.Metrics
+	cursors         *common.Cursors
 	startTime       time.Time
 }
 
+// DefaultUnixSocketPerm is the default Unix socket file permissions, the same as mongod's.
+const DefaultUnixSocketPerm os.FileMode = 0o700
+
 // NewListenerOpts represents listener configuration.
 type NewListenerOpts struct {
-	ListenAddr      string
+	ListenAddr      string      // TCP address, disabled if empty
+	ListenUnix      string      // Unix socket path, disabled if empty
+	ListenUnixPerm  os.FileMode // Unix socket file permissions, DefaultUnixSocketPerm if zero
 	TLS             bool
 	ProxyAddr       string
 	Mode            Mode
 	PgPool          *pg.Pool
 	Logger          *zap.Logger
+	RecordDir       string        // record all requests and responses to a new file in this directory, if set
+	CursorTimeout   time.Duration // idle cursor timeout, common.DefaultCursorTimeout if zero
 	TestConnTimeout time.Duration
 }
 
 // NewListener returns a new listener, configured by the NewListenerOpts argument.
 func NewListener(opts *NewListenerOpts) *Listener {
+	cursorTimeout := opts.CursorTimeout
+	if cursorTimeout == 0 {
+		cursorTimeout = common.DefaultCursorTimeout
+	}
+
 	return &Listener{
 		opts:            opts,
 		metrics:         NewListenerMetrics(),
 		handlersMetrics: handlers.NewMetrics(),
+		cursors:         common.NewCursors(opts.Logger.Named("cursors"), cursorTimeout),
 		startTime:       time.Now(),
 	}
 }
 
 // Run runs the listener until ctx is canceled or some unrecoverable error occurs.
 func (l *Listener) Run(ctx context.Context) error {
-	lis, err := net.Listen("tcp", l.opts.ListenAddr)
-	if err != nil {
-		return lazyerrors.Error(err)
+	if l.opts.ListenAddr == "" && l.opts.ListenUnix == "" {
+		return lazyerrors.New("no TCP address or Unix socket path to listen on")
 	}
 
-	l.opts.Logger.Sugar().Infof("Listening on %s ...", l.opts.ListenAddr)
+	var listeners []net.Listener
+	closeListeners := func() {
+		for _, lis := range listeners {
+			lis.Close()
+		}
+	}
+
+	if l.opts.ListenAddr != "" {
+		lis, err := l.listenTCP()
+		if err != nil {
+			return err
+		}
+		listeners = append(listeners, lis)
+	}
 
-	if l.opts.TLS {
-		l.opts.Logger.Sugar().Info("Using insecure TLS.")
-		cert, err := generateInsecureCert()
+	if l.opts.ListenUnix != "" {
+		lis, err := l.listenUnix()
 		if err != nil {
+			closeListeners()
 			return err
 		}
-		l.opts.Logger.Sugar().Info("Insecure self-signed certificate generated.")
+		listeners = append(listeners, lis)
+	}
 
-		tlsConfig := &tls.Config{
-			Certificates:       []tls.Certificate{*cert},
-			InsecureSkipVerify: true,
+	var rec *recorder.Recorder
+	if l.opts.RecordDir != "" {
+		var err error
+		if rec, err = recorder.New(l.opts.RecordDir); err != nil {
+			closeListeners()
+			return err
 		}
-		lis = tls.NewListener(lis, tlsConfig)
+		defer func() {
+			if e := rec.Close(); e != nil {
+				l.opts.Logger.Warn("Failed to close recording", zap.Error(e))
+			}
+		}()
+
+		l.opts.Logger.Sugar().Infof("Recording requests and responses to %s.", rec.Path())
 	}
 
 	// handle ctx cancelation
 	go func() {
 		<-ctx.Done()
-		lis.Close()
+		closeListeners()
 	}()
 
+	// cursors are shared by all connections and closed after all of them are stopped
+	go l.cursors.Run(ctx)
+	defer l.cursors.Close()
+
+	var connsWG, acceptWG sync.WaitGroup
+	for _, lis := range listeners {
+		lis := lis
+		acceptWG.Add(1)
+		go func() {
+			defer acceptWG.Done()
+			l.accept(ctx, lis, rec, &connsWG)
+		}()
+	}
+
+	acceptWG.Wait()
+
+	l.opts.Logger.Info("Waiting for all connections to stop...")
+	connsWG.Wait()
+
+	return ctx.Err()
+}
+
+// listenTCP returns TCP listener, with TLS if enabled.
+func (l *Listener) listenTCP() (net.Listener, error) {
+	lis, err := net.Listen("tcp", l.opts.ListenAddr)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	l.opts.Logger.Sugar().Infof("Listening on %s ...", l.opts.ListenAddr)
+
+	if !l.opts.TLS {
+		return lis, nil
+	}
+
+	l.opts.Logger.Sugar().Info("Using insecure TLS.")
+	cert, err := generateInsecureCert()
+	if err != nil {
+		lis.Close()
+		return nil, err
+	}
+	l.opts.Logger.Sugar().Info("Insecure self-signed certificate generated.")
+
+	tlsConfig := &tls.Config{
+		Certificates:       []tls.Certificate{*cert},
+		InsecureSkipVerify: true,
+	}
+	return tls.NewListener(lis, tlsConfig), nil
+}
+
+// listenUnix returns Unix socket listener, removing a stale socket file first.
+//
+// The socket is created in a temporary directory accessible only by the current user,
+// and moved to the given path after setting its permissions,
+// so it is never accessible with umask permissions.
+// The socket file is removed when the listener is closed.
+func (l *Listener) listenUnix() (net.Listener, error) {
+	path := l.opts.ListenUnix
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	perm := l.opts.ListenUnixPerm
+	if perm == 0 {
+		perm = DefaultUnixSocketPerm
+	}
+
+	// MkdirTemp creates the directory with 0o700 permissions
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".ferretdb-")
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmpPath := filepath.Join(dir, "s")
+	lis, err := net.Listen("unix", tmpPath)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	// the socket file is moved, so it is removed by unixListener
+	unixLis := lis.(*net.UnixListener)
+	unixLis.SetUnlinkOnClose(false)
+
+	if err = os.Chmod(tmpPath, perm); err != nil {
+		lis.Close()
+		return nil, lazyerrors.Error(err)
+	}
+
+	if err = os.Rename(tmpPath, path); err != nil {
+		lis.Close()
+		return nil, lazyerrors.Error(err)
+	}
+
+	l.opts.Logger.Sugar().Infof("Listening on %s (%s) ...", path, perm)
+
+	return &unixListener{UnixListener: unixLis, path: path}, nil
+}
+
+// unixListener is a Unix socket listener that removes the socket file at path when closed.
+type unixListener struct {
+	*net.UnixListener
+	path      string
+	closeOnce sync.Once
+}
+
+// Close implements net.Listener interface.
+func (lis *unixListener) Close() error {
+	err := lis.UnixListener.Close()
+
+	lis.closeOnce.Do(func() {
+		os.Remove(lis.path)
+	})
+
+	return err
+}
+
+// removeStaleSocket removes Unix socket file left by the previous process, if any.
+//
+// It returns an error if the path exists and is not a socket, or if some process still listens on it.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return lazyerrors.Error(err)
+	}
+
+	if fi.Mode()&fs.ModeSocket == 0 {
+		return lazyerrors.Errorf("%s exists and is not a socket", path)
+	}
+
+	if c, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		c.Close()
+		return lazyerrors.Errorf("%s is in use by another process", path)
+	}
+
+	if err = os.Remove(path); err != nil {
+		return lazyerrors.Error(err)
+	}
+
+	return nil
+}
+
+// accept accepts and runs connections until ctx is canceled.
+func (l *Listener) accept(ctx context.Context, lis net.Listener, rec *recorder.Recorder, wg *sync.WaitGroup) {
 	const delay = 3 * time.Second
 
-	var wg sync.WaitGroup
 	for {
 		netConn, err := lis.Accept()
 		if err != nil {
 			l.metrics.accepts.WithLabelValues("1").Inc()
 
 			if ctx.Err() != nil {
-				break
+				return
 			}
 
 			l.opts.Logger.Warn("Failed to accept connection", zap.Error(err))
@@ -128,7 +317,10 @@ func (l *Listener) Run(ctx context.Context) error {
 				proxyAddr:       l.opts.ProxyAddr,
 				mode:            l.opts.Mode,
 				handlersMetrics: l.handlersMetrics,
+				cursors:         l.cursors,
 				startTime:       l.startTime,
+				connID:          atomic.AddInt64(&l.lastConnID, 1),
+				recorder:        rec,
 			}
 			conn, e := newConn(opts)
 			if e != nil {
@@ -152,11 +344,6 @@ func (l *Listener) Run(ctx context.Context) error {
 			}
 		}()
 	}
-
-	l.opts.Logger.Info("Waiting for all connections to stop...")
-	wg.Wait()
-
-	return ctx.Err()
 }
 
 // Describe implements prometheus.Collector.
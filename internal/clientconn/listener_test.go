@@ -0,0 +1,169 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientconn
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+	"github.com/FerretDB/FerretDB/internal/util/testutil"
+)
+
+// staleSocket creates a Unix socket file at the given path that nobody listens on.
+func staleSocket(t *testing.T, path string) {
+	t.Helper()
+
+	lis, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	lis.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, lis.Close())
+
+	fi, err := os.Lstat(path)
+	require.NoError(t, err)
+	require.NotZero(t, fi.Mode()&os.ModeSocket)
+}
+
+func TestListenUnix(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "ferretdb.sock")
+	staleSocket(t, path)
+
+	l := NewListener(&NewListenerOpts{
+		ListenUnix:     path,
+		ListenUnixPerm: 0o660,
+		Mode:           NormalMode,
+		Logger:         zaptest.NewLogger(t),
+	})
+
+	ctx, cancel := context.WithCancel(testutil.Ctx(t))
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- l.Run(ctx)
+	}()
+
+	// stale socket refuses connections until it is replaced by the listener's one
+	var netConn net.Conn
+	require.Eventually(t, func() bool {
+		var err error
+		netConn, err = net.Dial("unix", path)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o660), fi.Mode().Perm())
+
+	// temporary directory for creating the socket is removed
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, filepath.Base(path), entries[0].Name())
+
+	ping := must.NotFail(types.NewDocument("ping", int32(1), "$db", "admin"))
+	writeMsg(t, netConn, 1, 0, ping)
+
+	_, msgs := readReplies(t, bufio.NewReader(netConn))
+	require.Len(t, msgs, 1)
+	doc, err := msgs[0].Document()
+	require.NoError(t, err)
+	assert.Equal(t, must.NotFail(types.NewDocument("ok", float64(1))), doc)
+
+	require.NoError(t, netConn.Close())
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	// socket file is removed when the listener is closed
+	_, err = os.Lstat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRemoveStaleSocket(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		create func(t *testing.T, path string)
+		err    bool
+		exists bool
+	}{
+		"NotExist": {
+			create: func(t *testing.T, path string) {},
+		},
+		"Stale": {
+			create: staleSocket,
+		},
+		"NotSocket": {
+			create: func(t *testing.T, path string) {
+				require.NoError(t, os.WriteFile(path, nil, 0o600))
+			},
+			err:    true,
+			exists: true,
+		},
+		"InUse": {
+			create: func(t *testing.T, path string) {
+				lis, err := net.Listen("unix", path)
+				require.NoError(t, err)
+				t.Cleanup(func() { lis.Close() })
+
+				go func() {
+					for {
+						c, err := lis.Accept()
+						if err != nil {
+							return
+						}
+						c.Close()
+					}
+				}()
+			},
+			err:    true,
+			exists: true,
+		},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "ferretdb.sock")
+			tc.create(t, path)
+
+			err := removeStaleSocket(path)
+			if tc.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			_, err = os.Lstat(path)
+			if tc.exists {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, os.ErrNotExist)
+			}
+		})
+	}
+}
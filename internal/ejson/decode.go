@@ -0,0 +1,435 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ejson
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+)
+
+// field represents a single field of JSON object.
+type field struct {
+	key   string
+	value any
+}
+
+// object represents JSON object with fields in the original order.
+type object []field
+
+// get returns the value of the given field.
+func (o object) get(key string) (any, bool) {
+	for _, f := range o {
+		if f.key == key {
+			return f.value, true
+		}
+	}
+	return nil, false
+}
+
+// hasKeys returns true if the object has exactly the given keys in any order.
+func (o object) hasKeys(keys ...string) bool {
+	if len(o) != len(keys) {
+		return false
+	}
+	for _, k := range keys {
+		if _, ok := o.get(k); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// readValue reads a single JSON value, preserving the order of object fields.
+//
+// It returns object, []any, json.Number, string, bool or nil.
+func readValue(dec *json.Decoder) (any, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	switch t := t.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			var o object
+			for dec.More() {
+				k, err := dec.Token()
+				if err != nil {
+					return nil, lazyerrors.Error(err)
+				}
+				v, err := readValue(dec)
+				if err != nil {
+					return nil, lazyerrors.Error(err)
+				}
+				o = append(o, field{key: k.(string), value: v})
+			}
+			if _, err = dec.Token(); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+			if o == nil {
+				o = object{}
+			}
+			return o, nil
+
+		case '[':
+			a := []any{}
+			for dec.More() {
+				v, err := readValue(dec)
+				if err != nil {
+					return nil, lazyerrors.Error(err)
+				}
+				a = append(a, v)
+			}
+			if _, err = dec.Token(); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+			return a, nil
+
+		default:
+			return nil, lazyerrors.Errorf("ejson.readValue: unexpected delimiter %q", t)
+		}
+
+	default:
+		return t, nil
+	}
+}
+
+// keywords are keys that start Extended JSON type wrappers.
+var keywords = map[string]struct{}{
+	"$oid":               {},
+	"$symbol":            {},
+	"$numberInt":         {},
+	"$numberLong":        {},
+	"$numberDouble":      {},
+	"$numberDecimal":     {},
+	"$binary":            {},
+	"$code":              {},
+	"$scope":             {},
+	"$timestamp":         {},
+	"$regularExpression": {},
+	"$dbPointer":         {},
+	"$date":              {},
+	"$minKey":            {},
+	"$maxKey":            {},
+	"$undefined":         {},
+}
+
+// convert converts a value returned by readValue to built-in or types' package value.
+func convert(v any) (any, error) {
+	switch v := v.(type) {
+	case object:
+		if len(v) > 0 {
+			if _, ok := keywords[v[0].key]; ok {
+				return convertWrapper(v)
+			}
+		}
+
+		doc := new(types.Document)
+		for _, f := range v {
+			fv, err := convert(f.value)
+			if err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+			if err = doc.Set(f.key, fv); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+		}
+		return doc, nil
+
+	case []any:
+		arr := types.MakeArray(len(v))
+		for _, el := range v {
+			ev, err := convert(el)
+			if err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+			if err = arr.Append(ev); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+		}
+		return arr, nil
+
+	case json.Number:
+		return convertNumber(v)
+
+	case string:
+		return v, nil
+
+	case bool:
+		return v, nil
+
+	case nil:
+		return types.Null, nil
+
+	default:
+		panic(fmt.Sprintf("not reached: %T", v))
+	}
+}
+
+// convertNumber converts relaxed JSON number.
+func convertNumber(n json.Number) (any, error) {
+	s := n.String()
+	if !strings.ContainsAny(s, ".eE") {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			if i >= math.MinInt32 && i <= math.MaxInt32 {
+				return int32(i), nil
+			}
+			return i, nil
+		}
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+	return f, nil
+}
+
+// convertWrapper converts type wrapper object like {"$oid": "..."}.
+func convertWrapper(o object) (any, error) {
+	if o.hasKeys("$code", "$scope") {
+		code, _ := o.get("$code")
+		scope, _ := o.get("$scope")
+
+		c, ok := code.(string)
+		if !ok {
+			return nil, lazyerrors.Errorf("ejson.convertWrapper: invalid $code %v", code)
+		}
+		so, ok := scope.(object)
+		if !ok {
+			return nil, lazyerrors.Errorf("ejson.convertWrapper: invalid $scope %v", scope)
+		}
+		s, err := convert(so)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		d, ok := s.(*types.Document)
+		if !ok {
+			return nil, lazyerrors.Errorf("ejson.convertWrapper: invalid $scope %v", scope)
+		}
+
+		return types.JavaScriptScope{Code: c, Scope: d}, nil
+	}
+
+	if len(o) != 1 {
+		return nil, lazyerrors.Errorf("ejson.convertWrapper: unexpected fields for %s", o[0].key)
+	}
+
+	key, v := o[0].key, o[0].value
+	s, isString := v.(string)
+	obj, isObject := v.(object)
+
+	switch {
+	case key == "$oid" && isString:
+		return parseObjectID(s)
+
+	case key == "$symbol" && isString:
+		return types.Symbol(s), nil
+
+	case key == "$numberInt" && isString:
+		i, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		return int32(i), nil
+
+	case key == "$numberLong" && isString:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		return i, nil
+
+	case key == "$numberDouble" && isString:
+		return parseDouble(s)
+
+	case key == "$numberDecimal" && isString:
+		d, err := types.ParseDecimal128(s)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		return d, nil
+
+	case key == "$binary" && isObject && obj.hasKeys("base64", "subType"):
+		b64, _ := obj.get("base64")
+		st, _ := obj.get("subType")
+		b64s, ok1 := b64.(string)
+		sts, ok2 := st.(string)
+		if !ok1 || !ok2 || len(sts) == 0 || len(sts) > 2 {
+			return nil, lazyerrors.Errorf("ejson.convertWrapper: invalid $binary %v", v)
+		}
+		b, err := base64.StdEncoding.DecodeString(b64s)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		subtype, err := strconv.ParseUint(sts, 16, 8)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		return types.Binary{Subtype: types.BinarySubtype(subtype), B: b}, nil
+
+	case key == "$code" && isString:
+		return types.JavaScript(s), nil
+
+	case key == "$timestamp" && isObject && obj.hasKeys("t", "i"):
+		t, _ := obj.get("t")
+		i, _ := obj.get("i")
+		tn, err := parseUint32(t)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		in, err := parseUint32(i)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		return types.Timestamp(uint64(tn)<<32 | uint64(in)), nil
+
+	case key == "$regularExpression" && isObject && obj.hasKeys("pattern", "options"):
+		p, _ := obj.get("pattern")
+		o, _ := obj.get("options")
+		pattern, ok1 := p.(string)
+		options, ok2 := o.(string)
+		if !ok1 || !ok2 {
+			return nil, lazyerrors.Errorf("ejson.convertWrapper: invalid $regularExpression %v", v)
+		}
+		return types.Regex{Pattern: pattern, Options: options}, nil
+
+	case key == "$dbPointer" && isObject && obj.hasKeys("$ref", "$id"):
+		ref, _ := obj.get("$ref")
+		id, _ := obj.get("$id")
+		ns, ok := ref.(string)
+		if !ok {
+			return nil, lazyerrors.Errorf("ejson.convertWrapper: invalid $dbPointer %v", v)
+		}
+		idObj, ok := id.(object)
+		if !ok || !idObj.hasKeys("$oid") {
+			return nil, lazyerrors.Errorf("ejson.convertWrapper: invalid $dbPointer %v", v)
+		}
+		oid, err := convertWrapper(idObj)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		return types.DBPointer{Namespace: ns, ID: oid.(types.ObjectID)}, nil
+
+	case key == "$date":
+		return parseDate(v)
+
+	case key == "$minKey" && v == json.Number("1"):
+		return types.MinKey, nil
+
+	case key == "$maxKey" && v == json.Number("1"):
+		return types.MaxKey, nil
+
+	case key == "$undefined" && v == true:
+		return types.Undefined, nil
+	}
+
+	return nil, lazyerrors.Errorf("ejson.convertWrapper: invalid %s value %v", key, v)
+}
+
+// parseObjectID parses ObjectID from 24 character hex string.
+func parseObjectID(s string) (types.ObjectID, error) {
+	var id types.ObjectID
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return id, lazyerrors.Error(err)
+	}
+	if len(b) != len(id) {
+		return id, lazyerrors.Errorf("ejson.parseObjectID: %d bytes", len(b))
+	}
+
+	copy(id[:], b)
+	return id, nil
+}
+
+// parseDouble parses $numberDouble value.
+func parseDouble(s string) (float64, error) {
+	switch s {
+	case "Infinity":
+		return math.Inf(1), nil
+	case "-Infinity":
+		return math.Inf(-1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+
+	// reject special values in other forms, like "inf"
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, lazyerrors.Errorf("ejson.parseDouble: invalid value %q", s)
+	}
+	return f, nil
+}
+
+// parseUint32 parses JSON number as uint32.
+func parseUint32(v any) (uint32, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, lazyerrors.Errorf("ejson.parseUint32: invalid value %v", v)
+	}
+
+	i, err := strconv.ParseUint(n.String(), 10, 32)
+	if err != nil {
+		return 0, lazyerrors.Error(err)
+	}
+	return uint32(i), nil
+}
+
+// parseDate parses $date value in canonical, relaxed or legacy (milliseconds as JSON number) formats.
+func parseDate(v any) (time.Time, error) {
+	switch v := v.(type) {
+	case object:
+		if !v.hasKeys("$numberLong") {
+			break
+		}
+		l, _ := v.get("$numberLong")
+		s, ok := l.(string)
+		if !ok {
+			break
+		}
+		ms, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, lazyerrors.Error(err)
+		}
+		return time.UnixMilli(ms), nil
+
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, lazyerrors.Error(err)
+		}
+		return time.UnixMilli(t.UnixMilli()), nil
+
+	case json.Number:
+		ms, err := strconv.ParseInt(v.String(), 10, 64)
+		if err != nil {
+			return time.Time{}, lazyerrors.Error(err)
+		}
+		return time.UnixMilli(ms), nil
+	}
+
+	return time.Time{}, lazyerrors.Errorf("ejson.parseDate: invalid $date value %v", v)
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ejson provides converters from/to MongoDB Extended JSON v2 for built-in and `types` types.
+//
+// See https://github.com/mongodb/specifications/blob/master/source/extended-json.rst.
+//
+// Mapping
+//
+// Composite types
+//  *types.Document        JSON object
+//  *types.Array           JSON array
+// Scalar types
+//  float64                {"$numberDouble": "<number as string>"}, or JSON number in relaxed mode if finite
+//  string                 JSON string
+//  types.Binary           {"$binary": {"base64": "<base 64 string>", "subType": "<subtype as 2 character hex string>"}}
+//  types.ObjectID         {"$oid": "<ObjectID as 24 character hex string>"}
+//  bool                   JSON true / false values
+//  time.Time              {"$date": {"$numberLong": "<milliseconds since epoch as string>"}},
+//                         or {"$date": "<ISO-8601 string>"} in relaxed mode for years 1970-9999
+//  types.NullType         JSON null
+//  types.Regex            {"$regularExpression": {"pattern": "<string>", "options": "<string>"}}
+//  int32                  {"$numberInt": "<number as string>"}, or JSON number in relaxed mode
+//  types.Timestamp        {"$timestamp": {"t": <seconds as JSON number>, "i": <increment as JSON number>}}
+//  int64                  {"$numberLong": "<number as string>"}, or JSON number in relaxed mode
+//  types.Decimal128       {"$numberDecimal": "<number as string>"}
+//  types.CString          JSON string (decoded as string)
+//  types.MinKeyType       {"$minKey": 1}
+//  types.MaxKeyType       {"$maxKey": 1}
+// Deprecated scalar types
+//  types.UndefinedType    {"$undefined": true}
+//  types.DBPointer        {"$dbPointer": {"$ref": "<namespace>", "$id": {"$oid": "<ObjectID as 24 character hex string>"}}}
+//  types.JavaScript       {"$code": "<string>"}
+//  types.Symbol           {"$symbol": "<string>"}
+//  types.JavaScriptScope  {"$code": "<string>", "$scope": <document>}
+//
+// Strings must be valid UTF-8: Marshal and Unmarshal return ErrInvalidUTF8 otherwise.
+// Unmarshal accepts both canonical and relaxed formats.
+// JSON numbers without fraction and exponent are decoded as int32 or int64 (whichever is the smallest that fits),
+// other JSON numbers are decoded as float64.
+package ejson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"unicode/utf8"
+
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+)
+
+// ErrInvalidUTF8 is returned when a string is not valid UTF-8;
+// JSON can't represent such strings without replacing invalid bytes.
+var ErrInvalidUTF8 = errors.New("ejson: invalid UTF-8 string")
+
+// Mode represents Extended JSON format.
+type Mode int
+
+const (
+	// Canonical format preserves type information at the expense of readability.
+	Canonical Mode = iota
+
+	// Relaxed format uses native JSON representation for numbers and recent dates.
+	// Some type information is lost.
+	Relaxed
+)
+
+// Marshal encodes given built-in or types' package value into Extended JSON using the given mode.
+func Marshal(v any, mode Mode) ([]byte, error) {
+	if v == nil {
+		panic("v is nil")
+	}
+
+	var buf bytes.Buffer
+	e := encoder{buf: &buf, relaxed: mode == Relaxed}
+	err := e.encode(v)
+	if err == nil {
+		err = e.err
+	}
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the given Extended JSON data in canonical or relaxed format.
+func Unmarshal(data []byte) (any, error) {
+	if !utf8.Valid(data) {
+		return nil, lazyerrors.Error(ErrInvalidUTF8)
+	}
+
+	r := bytes.NewReader(data)
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	v, err := readValue(dec)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	if _, err = dec.Token(); err != io.EOF {
+		return nil, lazyerrors.Errorf("ejson.Unmarshal: unexpected data after value")
+	}
+
+	res, err := convert(v)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	return res, nil
+}
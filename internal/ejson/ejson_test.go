@@ -0,0 +1,361 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ejson
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FerretDB/FerretDB/internal/bson"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+	"github.com/FerretDB/FerretDB/internal/util/testutil"
+)
+
+type testCase struct {
+	name      string
+	v         any
+	canonical string
+	relaxed   string
+	relaxedV  any // value decoded from relaxed format, if it differs from v
+}
+
+var testCases = []testCase{{
+	name:      "double",
+	v:         float64(1),
+	canonical: `{"$numberDouble":"1.0"}`,
+	relaxed:   `1.0`,
+}, {
+	name:      "doubleExp",
+	v:         float64(-1.2345678921232e+18),
+	canonical: `{"$numberDouble":"-1.2345678921232E+18"}`,
+	relaxed:   `-1.2345678921232E+18`,
+}, {
+	name:      "doubleNegZero",
+	v:         math.Copysign(0, -1),
+	canonical: `{"$numberDouble":"-0.0"}`,
+	relaxed:   `-0.0`,
+}, {
+	name:      "doubleInf",
+	v:         math.Inf(-1),
+	canonical: `{"$numberDouble":"-Infinity"}`,
+	relaxed:   `{"$numberDouble":"-Infinity"}`,
+}, {
+	name:      "string",
+	v:         "<foo & \"bar\">",
+	canonical: `"<foo & \"bar\">"`,
+	relaxed:   `"<foo & \"bar\">"`,
+}, {
+	name:      "binary",
+	v:         types.Binary{Subtype: types.BinaryUser, B: []byte{0xff, 0xff}},
+	canonical: `{"$binary":{"base64":"//8=","subType":"80"}}`,
+	relaxed:   `{"$binary":{"base64":"//8=","subType":"80"}}`,
+}, {
+	name:      "objectID",
+	v:         types.ObjectID{0x57, 0xe1, 0x93, 0xd7, 0xa9, 0xcc, 0x81, 0xb4, 0x02, 0x74, 0x98, 0xb5},
+	canonical: `{"$oid":"57e193d7a9cc81b4027498b5"}`,
+	relaxed:   `{"$oid":"57e193d7a9cc81b4027498b5"}`,
+}, {
+	name:      "bool",
+	v:         true,
+	canonical: `true`,
+	relaxed:   `true`,
+}, {
+	name:      "date",
+	v:         time.UnixMilli(1356351330501),
+	canonical: `{"$date":{"$numberLong":"1356351330501"}}`,
+	relaxed:   `{"$date":"2012-12-24T12:15:30.501Z"}`,
+}, {
+	name:      "dateNegative",
+	v:         time.UnixMilli(-284643869501),
+	canonical: `{"$date":{"$numberLong":"-284643869501"}}`,
+	relaxed:   `{"$date":{"$numberLong":"-284643869501"}}`,
+}, {
+	name:      "null",
+	v:         types.Null,
+	canonical: `null`,
+	relaxed:   `null`,
+}, {
+	name:      "regex",
+	v:         types.Regex{Pattern: "^foo$", Options: "im"},
+	canonical: `{"$regularExpression":{"pattern":"^foo$","options":"im"}}`,
+	relaxed:   `{"$regularExpression":{"pattern":"^foo$","options":"im"}}`,
+}, {
+	name:      "int32",
+	v:         int32(-42),
+	canonical: `{"$numberInt":"-42"}`,
+	relaxed:   `-42`,
+}, {
+	name:      "timestamp",
+	v:         types.Timestamp(4294967295<<32 | 42),
+	canonical: `{"$timestamp":{"t":4294967295,"i":42}}`,
+	relaxed:   `{"$timestamp":{"t":4294967295,"i":42}}`,
+}, {
+	name:      "int64",
+	v:         int64(42),
+	canonical: `{"$numberLong":"42"}`,
+	relaxed:   `42`,
+	relaxedV:  int32(42),
+}, {
+	name:      "int64Large",
+	v:         int64(math.MaxInt64),
+	canonical: `{"$numberLong":"9223372036854775807"}`,
+	relaxed:   `9223372036854775807`,
+}, {
+	name:      "decimal128",
+	v:         must.NotFail(types.ParseDecimal128("-1.5E+10")),
+	canonical: `{"$numberDecimal":"-1.5E+10"}`,
+	relaxed:   `{"$numberDecimal":"-1.5E+10"}`,
+}, {
+	name:      "minKey",
+	v:         types.MinKey,
+	canonical: `{"$minKey":1}`,
+	relaxed:   `{"$minKey":1}`,
+}, {
+	name:      "maxKey",
+	v:         types.MaxKey,
+	canonical: `{"$maxKey":1}`,
+	relaxed:   `{"$maxKey":1}`,
+}, {
+	name:      "undefined",
+	v:         types.Undefined,
+	canonical: `{"$undefined":true}`,
+	relaxed:   `{"$undefined":true}`,
+}, {
+	name: "dbPointer",
+	v: types.DBPointer{
+		Namespace: "db.c",
+		ID:        types.ObjectID{0x57, 0xe1, 0x93, 0xd7, 0xa9, 0xcc, 0x81, 0xb4, 0x02, 0x74, 0x98, 0xb5},
+	},
+	canonical: `{"$dbPointer":{"$ref":"db.c","$id":{"$oid":"57e193d7a9cc81b4027498b5"}}}`,
+	relaxed:   `{"$dbPointer":{"$ref":"db.c","$id":{"$oid":"57e193d7a9cc81b4027498b5"}}}`,
+}, {
+	name:      "javaScript",
+	v:         types.JavaScript("function() {}"),
+	canonical: `{"$code":"function() {}"}`,
+	relaxed:   `{"$code":"function() {}"}`,
+}, {
+	name:      "symbol",
+	v:         types.Symbol("foo"),
+	canonical: `{"$symbol":"foo"}`,
+	relaxed:   `{"$symbol":"foo"}`,
+}, {
+	name:      "javaScriptScope",
+	v:         types.JavaScriptScope{Code: "x", Scope: types.MustNewDocument("x", int32(1))},
+	canonical: `{"$code":"x","$scope":{"x":{"$numberInt":"1"}}}`,
+	relaxed:   `{"$code":"x","$scope":{"x":1}}`,
+}, {
+	name: "document",
+	v: types.MustNewDocument(
+		"b", must.NotFail(types.NewArray(int32(1), "two", types.MustNewDocument())),
+		"a", types.MustNewDocument("$ref", "c", "$id", int32(1)),
+	),
+	canonical: `{"b":[{"$numberInt":"1"},"two",{}],"a":{"$ref":"c","$id":{"$numberInt":"1"}}}`,
+	relaxed:   `{"b":[1,"two",{}],"a":{"$ref":"c","$id":1}}`,
+}}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := Marshal(tc.v, Canonical)
+			require.NoError(t, err)
+			assert.Equal(t, tc.canonical, string(actual))
+
+			actual, err = Marshal(tc.v, Relaxed)
+			require.NoError(t, err)
+			assert.Equal(t, tc.relaxed, string(actual))
+
+			v, err := Unmarshal([]byte(tc.canonical))
+			require.NoError(t, err)
+			assert.Equal(t, tc.v, v)
+
+			expected := tc.relaxedV
+			if expected == nil {
+				expected = tc.v
+			}
+			v, err = Unmarshal([]byte(tc.relaxed))
+			require.NoError(t, err)
+			assert.Equal(t, expected, v)
+		})
+	}
+}
+
+func TestUnmarshalCompat(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		data     string
+		expected any
+	}{{
+		data:     `{"$date":"1970-01-01T00:00:00Z"}`,
+		expected: time.UnixMilli(0),
+	}, {
+		data:     `{"$date":"2012-12-24T14:15:30.501+02:00"}`,
+		expected: time.UnixMilli(1356351330501),
+	}, {
+		data:     `{"$date":42}`,
+		expected: time.UnixMilli(42),
+	}, {
+		data:     `{"$binary":{"subType":"0","base64":""}}`,
+		expected: types.Binary{B: []byte{}},
+	}, {
+		data:     `{"$timestamp":{"i":1,"t":2}}`,
+		expected: types.Timestamp(2<<32 | 1),
+	}, {
+		data:     `{"$scope":{},"$code":"x"}`,
+		expected: types.JavaScriptScope{Code: "x", Scope: types.MustNewDocument()},
+	}, {
+		data:     ` { "a" : { "$numberDouble" : "NaN" } } `,
+		expected: types.MustNewDocument("a", math.NaN()),
+	}, {
+		data:     `{"$regularExpression":{"options":"","pattern":"a"}}`,
+		expected: types.Regex{Pattern: "a"},
+	}, {
+		data:     `{"$dbPointer":{"$id":{"$oid":"000000000000000000000000"},"$ref":"b"}}`,
+		expected: types.DBPointer{Namespace: "b"},
+	}} {
+		data, expected := tc.data, tc.expected
+		v, err := Unmarshal([]byte(data))
+		require.NoError(t, err, data)
+
+		if doc, ok := expected.(*types.Document); ok {
+			// NaN is not equal to itself
+			expectedJ := must.NotFail(Marshal(doc, Canonical))
+			actualJ := must.NotFail(Marshal(v, Canonical))
+			assert.Equal(t, string(expectedJ), string(actualJ), data)
+			continue
+		}
+
+		assert.Equal(t, expected, v, data)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, data := range []string{
+		``,
+		`{`,
+		`{} {}`,
+		`{"$oid":"123"}`,
+		`{"$oid":1}`,
+		`{"$oid":"57e193d7a9cc81b4027498b5","x":1}`,
+		`{"$numberInt":"2147483648"}`,
+		`{"$numberInt":1}`,
+		`{"$numberLong":"1.0"}`,
+		`{"$numberDouble":"inf"}`,
+		`{"$numberDecimal":"foo"}`,
+		`{"$binary":{"base64":"!","subType":"00"}}`,
+		`{"$binary":{"base64":"","subType":"100"}}`,
+		`{"$binary":"","$type":"00"}`,
+		`{"$timestamp":{"t":-1,"i":0}}`,
+		`{"$timestamp":{"t":4294967296,"i":0}}`,
+		`{"$date":"yesterday"}`,
+		`{"$date":{"$numberLong":1}}`,
+		`{"$minKey":0}`,
+		`{"$undefined":false}`,
+		`{"$code":"x","$scope":1}`,
+		`{"$scope":{}}`,
+		`{"$k":1}`,
+		"\"\x95\"",
+	} {
+		_, err := Unmarshal([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
+
+func TestMarshalInvalidUTF8(t *testing.T) {
+	t.Parallel()
+
+	for name, v := range map[string]any{
+		"String": "\x95",
+		"Regex":  types.Regex{Pattern: "\x95"},
+		"Symbol": types.Symbol("\x95"),
+	} {
+		v := v
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, mode := range []Mode{Canonical, Relaxed} {
+				_, err := Marshal(v, mode)
+				assert.ErrorIs(t, err, ErrInvalidUTF8)
+			}
+		})
+	}
+}
+
+func FuzzDocument(f *testing.F) {
+	files, err := filepath.Glob(filepath.Join("..", "bson", "testdata", "*.hex"))
+	require.NoError(f, err)
+	require.NotEmpty(f, files)
+
+	for _, file := range files {
+		f.Add(testutil.MustParseDumpFile(file))
+	}
+
+	for _, tc := range testCases {
+		if doc, ok := tc.v.(*types.Document); ok {
+			f.Add(must.NotFail(bson.MustConvertDocument(doc).MarshalBinary()))
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		t.Parallel()
+
+		var bdoc bson.Document
+		if err := bdoc.ReadFrom(bufio.NewReader(bytes.NewReader(b))); err != nil {
+			t.Skip(err)
+		}
+
+		doc, err := types.ConvertDocument(&bdoc)
+		require.NoError(t, err)
+
+		for _, mode := range []Mode{Canonical, Relaxed} {
+			j, err := Marshal(doc, mode)
+			if errors.Is(err, ErrInvalidUTF8) {
+				t.Skip(err)
+			}
+			require.NoError(t, err)
+
+			v, err := Unmarshal(j)
+			require.NoError(t, err, "%s", j)
+
+			// compare encoded data to handle NaNs
+			actualJ, err := Marshal(v, mode)
+			require.NoError(t, err)
+			assert.Equal(t, string(j), string(actualJ))
+
+			// canonical format should be lossless
+			if mode == Canonical {
+				actualB, err := bson.MustConvertDocument(v.(*types.Document)).MarshalBinary()
+				require.NoError(t, err)
+				assert.Equal(t, b[:len(actualB)], actualB)
+			}
+		}
+	})
+}
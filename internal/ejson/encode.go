@@ -0,0 +1,231 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ejson
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+)
+
+// encoder writes Extended JSON representation of values to the buffer.
+type encoder struct {
+	buf     *bytes.Buffer
+	relaxed bool
+	err     error // first error from writeString
+}
+
+// encode writes the given value.
+func (e *encoder) encode(v any) error {
+	switch v := v.(type) {
+	case *types.Document:
+		return e.encodeDocument(v)
+
+	case *types.Array:
+		e.buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i != 0 {
+				e.buf.WriteByte(',')
+			}
+			el, err := v.Get(i)
+			if err != nil {
+				return lazyerrors.Error(err)
+			}
+			if err = e.encode(el); err != nil {
+				return lazyerrors.Error(err)
+			}
+		}
+		e.buf.WriteByte(']')
+
+	case float64:
+		s := formatDouble(v)
+		if e.relaxed && !math.IsInf(v, 0) && !math.IsNaN(v) {
+			e.buf.WriteString(s)
+			break
+		}
+		e.writeWrapped("$numberDouble", s)
+
+	case string:
+		e.writeString(v)
+
+	case types.Binary:
+		e.buf.WriteString(`{"$binary":{"base64":`)
+		e.writeString(base64.StdEncoding.EncodeToString(v.B))
+		e.buf.WriteString(`,"subType":`)
+		e.writeString(fmt.Sprintf("%02x", byte(v.Subtype)))
+		e.buf.WriteString(`}}`)
+
+	case types.ObjectID:
+		e.writeWrapped("$oid", hex.EncodeToString(v[:]))
+
+	case bool:
+		e.buf.WriteString(strconv.FormatBool(v))
+
+	case time.Time:
+		if y := v.UTC().Year(); e.relaxed && y >= 1970 && y <= 9999 {
+			e.writeWrapped("$date", v.UTC().Format(relaxedDateFormat))
+			break
+		}
+		e.buf.WriteString(`{"$date":`)
+		e.writeWrapped("$numberLong", strconv.FormatInt(v.UnixMilli(), 10))
+		e.buf.WriteByte('}')
+
+	case types.NullType:
+		e.buf.WriteString("null")
+
+	case types.Regex:
+		e.buf.WriteString(`{"$regularExpression":{"pattern":`)
+		e.writeString(v.Pattern)
+		e.buf.WriteString(`,"options":`)
+		e.writeString(v.Options)
+		e.buf.WriteString(`}}`)
+
+	case int32:
+		if e.relaxed {
+			e.buf.WriteString(strconv.FormatInt(int64(v), 10))
+			break
+		}
+		e.writeWrapped("$numberInt", strconv.FormatInt(int64(v), 10))
+
+	case types.Timestamp:
+		fmt.Fprintf(e.buf, `{"$timestamp":{"t":%d,"i":%d}}`, uint64(v)>>32, uint64(v)&math.MaxUint32)
+
+	case int64:
+		if e.relaxed {
+			e.buf.WriteString(strconv.FormatInt(v, 10))
+			break
+		}
+		e.writeWrapped("$numberLong", strconv.FormatInt(v, 10))
+
+	case types.Decimal128:
+		e.writeWrapped("$numberDecimal", v.String())
+
+	case types.CString:
+		e.writeString(string(v))
+
+	case types.MinKeyType:
+		e.buf.WriteString(`{"$minKey":1}`)
+
+	case types.MaxKeyType:
+		e.buf.WriteString(`{"$maxKey":1}`)
+
+	case types.UndefinedType:
+		e.buf.WriteString(`{"$undefined":true}`)
+
+	case types.DBPointer:
+		e.buf.WriteString(`{"$dbPointer":{"$ref":`)
+		e.writeString(v.Namespace)
+		e.buf.WriteString(`,"$id":`)
+		e.writeWrapped("$oid", hex.EncodeToString(v.ID[:]))
+		e.buf.WriteString(`}}`)
+
+	case types.JavaScript:
+		e.writeWrapped("$code", string(v))
+
+	case types.Symbol:
+		e.writeWrapped("$symbol", string(v))
+
+	case types.JavaScriptScope:
+		e.buf.WriteString(`{"$code":`)
+		e.writeString(v.Code)
+		e.buf.WriteString(`,"$scope":`)
+		if err := e.encodeDocument(v.Scope); err != nil {
+			return lazyerrors.Error(err)
+		}
+		e.buf.WriteByte('}')
+
+	default:
+		return lazyerrors.Errorf("ejson.encode: unhandled type %T", v)
+	}
+
+	return nil
+}
+
+// encodeDocument writes the given document; nil document is written as an empty one.
+func (e *encoder) encodeDocument(doc *types.Document) error {
+	e.buf.WriteByte('{')
+
+	m := doc.Map()
+	for i, k := range doc.Keys() {
+		if i != 0 {
+			e.buf.WriteByte(',')
+		}
+		e.writeString(k)
+		e.buf.WriteByte(':')
+		if err := e.encode(m[k]); err != nil {
+			return lazyerrors.Error(err)
+		}
+	}
+
+	e.buf.WriteByte('}')
+	return nil
+}
+
+// writeWrapped writes {"<key>": "<s>"}.
+func (e *encoder) writeWrapped(key, s string) {
+	e.buf.WriteString(`{"` + key + `":`)
+	e.writeString(s)
+	e.buf.WriteByte('}')
+}
+
+// writeString writes JSON string without HTML escaping.
+//
+// Invalid UTF-8 string sets e.err to ErrInvalidUTF8.
+func (e *encoder) writeString(s string) {
+	if !utf8.ValidString(s) && e.err == nil {
+		e.err = ErrInvalidUTF8
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	// encoding string never fails
+	_ = enc.Encode(s)
+
+	e.buf.Write(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}))
+}
+
+// relaxedDateFormat is the format of dates in relaxed mode.
+const relaxedDateFormat = "2006-01-02T15:04:05.999Z07:00"
+
+// formatDouble formats float64 value as a string with the shortest representation
+// that decodes to the same value, always with a fraction or exponent.
+func formatDouble(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+
+	s := strconv.FormatFloat(f, 'G', -1, 64)
+	if !strings.ContainsAny(s, ".E") {
+		s += ".0"
+	}
+	return s
+}
@@ -33,8 +33,17 @@
 //  int32            JSON number
 //  types.Timestamp  {"$t": "<number as string>"}
 //  int64            {"$l": "<number as string>"}
-//  TODO Decimal128  {"$n": "<number as string>"}
+//  types.Decimal128 {"$n": "<number as string>"}
 //  types.CString    {"$c": "<string without terminating 0x0>"}
+//  types.MinKeyType {"$m": -1}
+//  types.MaxKeyType {"$m": 1}
+//
+// Deprecated scalar types
+//  types.UndefinedType   {"$u": true}
+//  types.DBPointer       {"$p": "<namespace>", "o": "<ObjectID as 24 character hex string>"}
+//  types.JavaScript      {"$j": "<code>"}
+//  types.Symbol          {"$s": "<string>"}
+//  types.JavaScriptScope {"$j": "<code>", "s": <scope document>}
 package fjson
 
 import (
@@ -104,8 +113,24 @@ func fromFJSON(v fjsontype) any {
 		return types.Timestamp(*v)
 	case *int64Type:
 		return int64(*v)
+	case *decimal128Type:
+		return types.Decimal128(*v)
 	case *cstringType:
 		return types.CString(*v)
+	case *minKeyType:
+		return types.MinKey
+	case *maxKeyType:
+		return types.MaxKey
+	case *undefinedType:
+		return types.Undefined
+	case *dbPointerType:
+		return types.DBPointer(*v)
+	case *javaScriptType:
+		return types.JavaScript(*v)
+	case *symbolType:
+		return types.Symbol(*v)
+	case *javaScriptScopeType:
+		return types.JavaScriptScope(*v)
 	}
 
 	panic(fmt.Sprintf("not reached: %T", v)) // for go-sumtype to work
@@ -140,8 +165,24 @@ func toFJSON(v any) fjsontype {
 		return pointer.To(timestampType(v))
 	case int64:
 		return pointer.To(int64Type(v))
+	case types.Decimal128:
+		return pointer.To(decimal128Type(v))
 	case types.CString:
 		return pointer.To(cstringType(v))
+	case types.MinKeyType:
+		return pointer.To(minKeyType(v))
+	case types.MaxKeyType:
+		return pointer.To(maxKeyType(v))
+	case types.UndefinedType:
+		return pointer.To(undefinedType(v))
+	case types.DBPointer:
+		return pointer.To(dbPointerType(v))
+	case types.JavaScript:
+		return pointer.To(javaScriptType(v))
+	case types.Symbol:
+		return pointer.To(symbolType(v))
+	case types.JavaScriptScope:
+		return pointer.To(javaScriptScopeType(v))
 	}
 
 	panic(fmt.Sprintf("not reached: %T", v)) // for go-sumtype to work
@@ -196,10 +237,47 @@ func Unmarshal(data []byte) (any, error) {
 			var o int64Type
 			err = o.UnmarshalJSON(data)
 			res = &o
+		case v["$n"] != nil:
+			var o decimal128Type
+			err = o.UnmarshalJSON(data)
+			res = &o
 		case v["$c"] != nil:
 			var o cstringType
 			err = o.UnmarshalJSON(data)
 			res = &o
+		case v["$m"] != nil:
+			// MinKey and MaxKey share the same key to sort correctly
+			if v["$m"] == float64(-1) {
+				var o minKeyType
+				err = o.UnmarshalJSON(data)
+				res = &o
+			} else {
+				var o maxKeyType
+				err = o.UnmarshalJSON(data)
+				res = &o
+			}
+		case v["$u"] != nil:
+			var o undefinedType
+			err = o.UnmarshalJSON(data)
+			res = &o
+		case v["$p"] != nil:
+			var o dbPointerType
+			err = o.UnmarshalJSON(data)
+			res = &o
+		case v["$j"] != nil:
+			if _, ok := v["s"]; ok {
+				var o javaScriptScopeType
+				err = o.UnmarshalJSON(data)
+				res = &o
+			} else {
+				var o javaScriptType
+				err = o.UnmarshalJSON(data)
+				res = &o
+			}
+		case v["$s"] != nil:
+			var o symbolType
+			err = o.UnmarshalJSON(data)
+			res = &o
 		default:
 			err = lazyerrors.Errorf("fjson.Unmarshal: unhandled map %v", v)
 		}
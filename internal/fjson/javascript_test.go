@@ -0,0 +1,44 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fjson
+
+import (
+	"testing"
+
+	"github.com/AlekSi/pointer"
+)
+
+var javaScriptTestCases = []testCase{{
+	name: "code",
+	v:    pointer.To(javaScriptType("function() { return 1; }")),
+	j:    `{"$j":"function() { return 1; }"}`,
+}, {
+	name: "EOF",
+	j:    `{`,
+	jErr: `unexpected EOF`,
+}}
+
+func TestJavaScript(t *testing.T) {
+	t.Parallel()
+	testJSON(t, javaScriptTestCases, func() fjsontype { return new(javaScriptType) })
+}
+
+func FuzzJavaScript(f *testing.F) {
+	fuzzJSON(f, javaScriptTestCases, func() fjsontype { return new(javaScriptType) })
+}
+
+func BenchmarkJavaScript(b *testing.B) {
+	benchmark(b, javaScriptTestCases, func() fjsontype { return new(javaScriptType) })
+}
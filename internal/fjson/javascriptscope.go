@@ -0,0 +1,97 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fjson
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/AlekSi/pointer"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+)
+
+// javaScriptScopeType represents deprecated BSON JavaScript code with scope type.
+type javaScriptScopeType types.JavaScriptScope
+
+// fjsontype implements fjsontype interface.
+func (js *javaScriptScopeType) fjsontype() {}
+
+type javaScriptScopeJSON struct {
+	J string          `json:"$j"`
+	S json.RawMessage `json:"s"`
+}
+
+// UnmarshalJSON implements fjsontype interface.
+func (js *javaScriptScopeType) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		panic("null data")
+	}
+
+	r := bytes.NewReader(data)
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var o javaScriptScopeJSON
+	if err := dec.Decode(&o); err != nil {
+		return lazyerrors.Error(err)
+	}
+	if err := checkConsumed(dec, r); err != nil {
+		return lazyerrors.Error(err)
+	}
+
+	if o.S == nil {
+		return lazyerrors.Errorf("fjson.JavaScriptScope.UnmarshalJSON: missing scope")
+	}
+
+	var scope documentType
+	if err := scope.UnmarshalJSON(o.S); err != nil {
+		return lazyerrors.Error(err)
+	}
+
+	*js = javaScriptScopeType{
+		Code:  o.J,
+		Scope: pointer.To(types.Document(scope)),
+	}
+	return nil
+}
+
+// MarshalJSON implements fjsontype interface.
+func (js *javaScriptScopeType) MarshalJSON() ([]byte, error) {
+	scope := js.Scope
+	if scope == nil {
+		scope = types.MustNewDocument()
+	}
+
+	s, err := toFJSON(scope).MarshalJSON()
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	res, err := json.Marshal(javaScriptScopeJSON{
+		J: js.Code,
+		S: s,
+	})
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+	return res, nil
+}
+
+// check interfaces
+var (
+	_ fjsontype = (*javaScriptScopeType)(nil)
+)
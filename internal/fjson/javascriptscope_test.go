@@ -0,0 +1,50 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fjson
+
+import (
+	"testing"
+
+	"github.com/AlekSi/pointer"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+)
+
+var javaScriptScopeTestCases = []testCase{{
+	name: "normal",
+	v:    pointer.To(javaScriptScopeType{Code: "x", Scope: types.MustNewDocument("x", int32(1))}),
+	j:    `{"$j":"x","s":{"$k":["x"],"x":1}}`,
+}, {
+	name: "empty",
+	v:    pointer.To(javaScriptScopeType{Code: "", Scope: types.MustNewDocument()}),
+	j:    `{"$j":"","s":{"$k":[]}}`,
+}, {
+	name: "EOF",
+	j:    `{`,
+	jErr: `unexpected EOF`,
+}}
+
+func TestJavaScriptScope(t *testing.T) {
+	t.Parallel()
+	testJSON(t, javaScriptScopeTestCases, func() fjsontype { return new(javaScriptScopeType) })
+}
+
+func FuzzJavaScriptScope(f *testing.F) {
+	fuzzJSON(f, javaScriptScopeTestCases, func() fjsontype { return new(javaScriptScopeType) })
+}
+
+func BenchmarkJavaScriptScope(b *testing.B) {
+	benchmark(b, javaScriptScopeTestCases, func() fjsontype { return new(javaScriptScopeType) })
+}
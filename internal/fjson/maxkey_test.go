@@ -0,0 +1,48 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fjson
+
+import (
+	"testing"
+
+	"github.com/AlekSi/pointer"
+)
+
+var maxKeyTestCases = []testCase{{
+	name: "MaxKey",
+	v:    pointer.To(maxKeyType{}),
+	j:    `{"$m":1}`,
+}, {
+	name: "invalid",
+	j:    `{"$m":2}`,
+	jErr: `fjson.MaxKey.UnmarshalJSON: unexpected value 2`,
+}, {
+	name: "EOF",
+	j:    `{`,
+	jErr: `unexpected EOF`,
+}}
+
+func TestMaxKey(t *testing.T) {
+	t.Parallel()
+	testJSON(t, maxKeyTestCases, func() fjsontype { return new(maxKeyType) })
+}
+
+func FuzzMaxKey(f *testing.F) {
+	fuzzJSON(f, maxKeyTestCases, func() fjsontype { return new(maxKeyType) })
+}
+
+func BenchmarkMaxKey(b *testing.B) {
+	benchmark(b, maxKeyTestCases, func() fjsontype { return new(maxKeyType) })
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fjson
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+)
+
+// minKeyType represents BSON MinKey type.
+type minKeyType types.MinKeyType
+
+// fjsontype implements fjsontype interface.
+func (v *minKeyType) fjsontype() {}
+
+type minKeyJSON struct {
+	M int `json:"$m"`
+}
+
+// UnmarshalJSON implements fjsontype interface.
+func (v *minKeyType) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		panic("null data")
+	}
+
+	r := bytes.NewReader(data)
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var o minKeyJSON
+	if err := dec.Decode(&o); err != nil {
+		return lazyerrors.Error(err)
+	}
+	if err := checkConsumed(dec, r); err != nil {
+		return lazyerrors.Error(err)
+	}
+
+	if o.M != -1 {
+		return lazyerrors.Errorf("fjson.MinKey.UnmarshalJSON: unexpected value %d", o.M)
+	}
+
+	return nil
+}
+
+// MarshalJSON implements fjsontype interface.
+func (v *minKeyType) MarshalJSON() ([]byte, error) {
+	res, err := json.Marshal(minKeyJSON{
+		M: -1,
+	})
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+	return res, nil
+}
+
+// check interfaces
+var (
+	_ fjsontype = (*minKeyType)(nil)
+)
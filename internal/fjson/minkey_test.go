@@ -0,0 +1,44 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fjson
+
+import (
+	"testing"
+
+	"github.com/AlekSi/pointer"
+)
+
+var minKeyTestCases = []testCase{{
+	name: "MinKey",
+	v:    pointer.To(minKeyType{}),
+	j:    `{"$m":-1}`,
+}, {
+	name: "EOF",
+	j:    `{`,
+	jErr: `unexpected EOF`,
+}}
+
+func TestMinKey(t *testing.T) {
+	t.Parallel()
+	testJSON(t, minKeyTestCases, func() fjsontype { return new(minKeyType) })
+}
+
+func FuzzMinKey(f *testing.F) {
+	fuzzJSON(f, minKeyTestCases, func() fjsontype { return new(minKeyType) })
+}
+
+func BenchmarkMinKey(b *testing.B) {
+	benchmark(b, minKeyTestCases, func() fjsontype { return new(minKeyType) })
+}
@@ -85,6 +85,11 @@ var commands = map[string]command{
 		help:    "Returns the most recent logged events from memory.",
 		handler: (*Handler).MsgGetLog,
 	},
+	"getmore": {
+		name:    "getMore",
+		help:    "Returns the next batch of documents of the cursor.",
+		handler: (*Handler).MsgGetMore,
+	},
 	"getparameter": {
 		name:    "getParameter",
 		help:    "Returns the value of the parameter.",
@@ -105,6 +110,11 @@ var commands = map[string]command{
 		help:    "Returns the role of the FerretDB instance.",
 		handler: (*Handler).MsgHello,
 	},
+	"killcursors": {
+		name:    "killCursors",
+		help:    "Closes cursors.",
+		handler: (*Handler).MsgKillCursors,
+	},
 	"listcollections": {
 		name:    "listCollections",
 		help:    "Returns the information of the collections and views in the database.",
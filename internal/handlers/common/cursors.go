@@ -0,0 +1,367 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FerretDB/FerretDB/internal/bson"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+)
+
+const (
+	// DefaultCursorTimeout is the time after which idle cursors are closed, like MongoDB's cursorTimeoutMillis.
+	DefaultCursorTimeout = 10 * time.Minute
+
+	// DefaultBatchSize is the number of documents in the first batch if batchSize is not set.
+	DefaultBatchSize = int32(101)
+
+	// maxBatchLen is the maximal total BSON size of batch documents; a single document may exceed it.
+	maxBatchLen = types.MaxDocumentLen
+
+	// maxCursorCheckInterval is the maximal interval between checks for idle cursors.
+	maxCursorCheckInterval = 4 * time.Second
+)
+
+// CursorIterator represents a stream of documents returned by a server-side cursor.
+//
+// It is used by a single goroutine at a time.
+type CursorIterator interface {
+	// Next returns the next document, or nil if there are no more documents.
+	Next() (*types.Document, error)
+
+	// Close frees resources used by the iterator.
+	// It may be called before all documents are returned.
+	Close()
+}
+
+// CursorOpts represents server-side cursor options.
+type CursorOpts struct {
+	NS          string // database.collection
+	BatchSize   int32  // the number of documents in the first batch
+	SingleBatch bool   // close the cursor after the first batch
+	NoTimeout   bool   // don't close the cursor when idle
+}
+
+// cursor represents a server-side cursor registered in Cursors.
+type cursor struct {
+	ns        string
+	iter      CursorIterator
+	next      *types.Document // document read ahead, nil if not read
+	noTimeout bool
+	lastUsed  time.Time
+	inUse     bool // iterator is used by getMore without holding Cursors lock
+	killed    bool // cursor was removed while in use, so it should be closed after that
+}
+
+// read returns the next document of the cursor, or nil if there are no more documents.
+func (c *cursor) read() (*types.Document, error) {
+	if doc := c.next; doc != nil {
+		c.next = nil
+		return doc, nil
+	}
+
+	return c.iter.Next()
+}
+
+// batch returns up to n documents of the cursor that fit into a reply.
+// It also reads ahead one more document to return true if the cursor is exhausted.
+func (c *cursor) batch(n int32) (*types.Array, bool, error) {
+	docs := new(types.Array)
+
+	var size int
+	for int32(docs.Len()) < n {
+		doc, err := c.read()
+		if err != nil {
+			return nil, false, err
+		}
+		if doc == nil {
+			return docs, true, nil
+		}
+
+		b, err := bson.MustConvertDocument(doc).MarshalBinary()
+		if err != nil {
+			return nil, false, lazyerrors.Error(err)
+		}
+
+		if docs.Len() != 0 && size+len(b) > maxBatchLen {
+			c.next = doc
+			return docs, false, nil
+		}
+		size += len(b)
+
+		if err = docs.Append(doc); err != nil {
+			return nil, false, lazyerrors.Error(err)
+		}
+	}
+
+	doc, err := c.read()
+	if err != nil {
+		return nil, false, err
+	}
+	c.next = doc
+
+	return docs, doc == nil, nil
+}
+
+// Cursors is a registry of server-side cursors shared by all client connections.
+//
+// Cursors are closed when exhausted, killed, idle for longer than a timeout (see Run),
+// or when the registry is closed.
+type Cursors struct {
+	l       *zap.Logger
+	timeout time.Duration
+
+	rw      sync.Mutex
+	cursors map[int64]*cursor
+	closed  bool
+}
+
+// NewCursors returns a new cursor registry that closes cursors idle for longer than timeout.
+func NewCursors(l *zap.Logger, timeout time.Duration) *Cursors {
+	return &Cursors{
+		l:       l,
+		timeout: timeout,
+		cursors: map[int64]*cursor{},
+	}
+}
+
+// newID returns a new random positive cursor id that is not used yet.
+//
+// It should be called with held lock.
+func (cs *Cursors) newID() int64 {
+	var b [8]byte
+	for {
+		if _, err := rand.Read(b[:]); err != nil {
+			panic(err)
+		}
+
+		id := int64(binary.BigEndian.Uint64(b[:]) & math.MaxInt64)
+		if _, ok := cs.cursors[id]; id != 0 && !ok {
+			return id
+		}
+	}
+}
+
+// FirstBatch returns the first batch of documents of a new cursor over iter.
+//
+// If there are more documents, the cursor is registered and its id is returned.
+// Otherwise, iter is closed and returned id is 0. In both cases, iter should not be used by the caller anymore.
+func (cs *Cursors) FirstBatch(iter CursorIterator, opts *CursorOpts) (*types.Array, int64, error) {
+	c := &cursor{
+		ns:        opts.NS,
+		iter:      iter,
+		noTimeout: opts.NoTimeout,
+	}
+
+	docs, exhausted, err := c.batch(opts.BatchSize)
+	if err != nil || exhausted || opts.SingleBatch {
+		iter.Close()
+		return docs, 0, err
+	}
+
+	cs.rw.Lock()
+	defer cs.rw.Unlock()
+
+	if cs.closed {
+		iter.Close()
+		return nil, 0, lazyerrors.New("cursors registry is closed")
+	}
+
+	id := cs.newID()
+	c.lastUsed = time.Now()
+	cs.cursors[id] = c
+
+	return docs, id, nil
+}
+
+// GetMore returns the next batch of documents of the cursor with the given id.
+//
+// The cursor should belong to the given namespace. Zero batchSize means as many documents as fit into a reply.
+// Returned id is 0 if the cursor is exhausted and closed.
+func (cs *Cursors) GetMore(id int64, ns string, batchSize int32) (*types.Array, int64, error) {
+	cs.rw.Lock()
+
+	c, ok := cs.cursors[id]
+	switch {
+	case !ok:
+		cs.rw.Unlock()
+		return nil, 0, NewError(ErrCursorNotFound, fmt.Errorf("cursor id %d not found", id))
+
+	case c.ns != ns:
+		cs.rw.Unlock()
+		err := fmt.Errorf("Requested getMore on namespace '%s', but cursor belongs to a different namespace %s", ns, c.ns)
+		return nil, 0, NewError(ErrUnauthorized, err)
+
+	case c.inUse:
+		cs.rw.Unlock()
+		return nil, 0, NewError(ErrCursorInUse, fmt.Errorf("cursor id %d is already in use", id))
+	}
+
+	c.inUse = true
+	cs.rw.Unlock()
+
+	if batchSize == 0 {
+		batchSize = math.MaxInt32
+	}
+
+	docs, exhausted, err := c.batch(batchSize)
+
+	cs.rw.Lock()
+	defer cs.rw.Unlock()
+
+	c.inUse = false
+	c.lastUsed = time.Now()
+
+	if err == nil && !exhausted && !c.killed {
+		return docs, id, nil
+	}
+
+	if !c.killed {
+		delete(cs.cursors, id)
+	}
+	c.iter.Close()
+
+	return docs, 0, err
+}
+
+// Kill closes cursors with the given ids that belong to the given namespace, or to any namespace if it is empty.
+//
+// It returns ids of killed cursors and ids of cursors that were not found.
+func (cs *Cursors) Kill(ns string, ids []int64) (killed, notFound []int64) {
+	var iters []CursorIterator
+
+	cs.rw.Lock()
+	for _, id := range ids {
+		c, ok := cs.cursors[id]
+		if !ok || (ns != "" && c.ns != ns) {
+			notFound = append(notFound, id)
+			continue
+		}
+
+		delete(cs.cursors, id)
+		killed = append(killed, id)
+
+		// cursor in use is closed by GetMore
+		if c.inUse {
+			c.killed = true
+			continue
+		}
+		iters = append(iters, c.iter)
+	}
+	cs.rw.Unlock()
+
+	for _, iter := range iters {
+		iter.Close()
+	}
+
+	return
+}
+
+// Run closes cursors that are idle for longer than the timeout until ctx is canceled.
+// Cursors with noCursorTimeout option are not closed.
+func (cs *Cursors) Run(ctx context.Context) {
+	interval := cs.timeout
+	if interval > maxCursorCheckInterval {
+		interval = maxCursorCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			cs.closeIdle(now)
+		}
+	}
+}
+
+// closeIdle closes cursors that are idle for longer than the timeout at the given time.
+func (cs *Cursors) closeIdle(now time.Time) {
+	var ids []int64
+
+	cs.rw.Lock()
+	for id, c := range cs.cursors {
+		if !c.inUse && !c.noTimeout && now.Sub(c.lastUsed) > cs.timeout {
+			ids = append(ids, id)
+		}
+	}
+	cs.rw.Unlock()
+
+	if len(ids) == 0 {
+		return
+	}
+
+	killed, _ := cs.Kill("", ids)
+	cs.l.Debug("Closed idle cursors.", zap.Int64s("ids", killed))
+}
+
+// Close closes all cursors; new cursors are not registered after that.
+func (cs *Cursors) Close() {
+	cs.rw.Lock()
+	ids := make([]int64, 0, len(cs.cursors))
+	for id := range cs.cursors {
+		ids = append(ids, id)
+	}
+	cs.closed = true
+	cs.rw.Unlock()
+
+	cs.Kill("", ids)
+}
+
+// GetBatchSize returns the value of the document's batchSize field, or def if it is not set.
+func GetBatchSize(document *types.Document, def int32) (int32, error) {
+	value, err := document.Get("batchSize")
+	if err != nil {
+		return def, nil
+	}
+
+	var batchSize int64
+	switch value := value.(type) {
+	case int32:
+		batchSize = int64(value)
+	case int64:
+		batchSize = value
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return 0, NewError(ErrBadValue, fmt.Errorf("BatchSize value must be a finite number, but received: %v", value))
+		}
+		batchSize = int64(math.Max(math.Min(value, math.MaxInt32), math.MinInt32))
+	default:
+		return 0, NewError(ErrTypeMismatch, fmt.Errorf("Field 'batchSize' must be a number, but received: %s", TypeAlias(value)))
+	}
+
+	if batchSize < 0 {
+		return 0, NewError(ErrBadValue, fmt.Errorf("BatchSize value must be non-negative, but received: %d", batchSize))
+	}
+
+	if batchSize > math.MaxInt32 {
+		batchSize = math.MaxInt32
+	}
+
+	return int32(batchSize), nil
+}
@@ -0,0 +1,428 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+)
+
+// fakeIterator is a CursorIterator over documents with _id 1, 2, ..., n.
+type fakeIterator struct {
+	n       int
+	pos     int
+	err     error         // returned after all documents if set
+	wait    chan struct{} // if set, the next call to Next blocks until it is closed
+	entered chan struct{} // receives a value when Next starts waiting
+	closed  int32         // number of Close calls; accessed atomically
+}
+
+// newFakeIterator returns a new iterator over n documents.
+func newFakeIterator(n int) *fakeIterator {
+	return &fakeIterator{
+		n:       n,
+		entered: make(chan struct{}, 1),
+	}
+}
+
+// Next implements CursorIterator.
+func (it *fakeIterator) Next() (*types.Document, error) {
+	if it.wait != nil {
+		it.entered <- struct{}{}
+		<-it.wait
+		it.wait = nil
+	}
+
+	if it.pos == it.n {
+		return nil, it.err
+	}
+
+	it.pos++
+	return must.NotFail(types.NewDocument("_id", int32(it.pos))), nil
+}
+
+// Close implements CursorIterator.
+func (it *fakeIterator) Close() {
+	atomic.AddInt32(&it.closed, 1)
+}
+
+// closeCount returns the number of Close calls.
+func (it *fakeIterator) closeCount() int32 {
+	return atomic.LoadInt32(&it.closed)
+}
+
+// batchIDs returns _id values of batch documents.
+func batchIDs(t *testing.T, batch *types.Array) []int32 {
+	t.Helper()
+
+	res := make([]int32, batch.Len())
+	for i := range res {
+		doc := must.NotFail(batch.Get(i)).(*types.Document)
+		res[i] = must.NotFail(doc.Get("_id")).(int32)
+	}
+
+	return res
+}
+
+// newTestCursors returns a new cursor registry that is closed at the end of the test.
+func newTestCursors(t *testing.T, timeout time.Duration) *Cursors {
+	t.Helper()
+
+	cs := NewCursors(zaptest.NewLogger(t), timeout)
+	t.Cleanup(cs.Close)
+
+	return cs
+}
+
+func TestCursors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("GetMore", func(t *testing.T) {
+		t.Parallel()
+
+		cs := newTestCursors(t, DefaultCursorTimeout)
+		iter := newFakeIterator(5)
+
+		batch, id, err := cs.FirstBatch(iter, &CursorOpts{NS: "db.c", BatchSize: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []int32{1, 2}, batchIDs(t, batch))
+		require.NotZero(t, id)
+
+		batch, nextID, err := cs.GetMore(id, "db.c", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []int32{3, 4}, batchIDs(t, batch))
+		assert.Equal(t, id, nextID)
+		assert.Zero(t, iter.closeCount())
+
+		// zero batch size returns all remaining documents
+		batch, nextID, err = cs.GetMore(id, "db.c", 0)
+		require.NoError(t, err)
+		assert.Equal(t, []int32{5}, batchIDs(t, batch))
+		assert.Zero(t, nextID)
+		assert.Equal(t, int32(1), iter.closeCount())
+
+		_, _, err = cs.GetMore(id, "db.c", 2)
+		requireErrorCode(t, ErrCursorNotFound, err)
+	})
+
+	t.Run("ExhaustedOnBatchBoundary", func(t *testing.T) {
+		t.Parallel()
+
+		cs := newTestCursors(t, DefaultCursorTimeout)
+		iter := newFakeIterator(4)
+
+		_, id, err := cs.FirstBatch(iter, &CursorOpts{NS: "db.c", BatchSize: 2})
+		require.NoError(t, err)
+		require.NotZero(t, id)
+
+		// read ahead detects that the cursor is exhausted
+		batch, nextID, err := cs.GetMore(id, "db.c", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []int32{3, 4}, batchIDs(t, batch))
+		assert.Zero(t, nextID)
+		assert.Equal(t, int32(1), iter.closeCount())
+	})
+
+	t.Run("FirstBatchExhausted", func(t *testing.T) {
+		t.Parallel()
+
+		cs := newTestCursors(t, DefaultCursorTimeout)
+		iter := newFakeIterator(2)
+
+		batch, id, err := cs.FirstBatch(iter, &CursorOpts{NS: "db.c", BatchSize: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []int32{1, 2}, batchIDs(t, batch))
+		assert.Zero(t, id)
+		assert.Equal(t, int32(1), iter.closeCount())
+	})
+
+	t.Run("SingleBatch", func(t *testing.T) {
+		t.Parallel()
+
+		cs := newTestCursors(t, DefaultCursorTimeout)
+		iter := newFakeIterator(5)
+
+		batch, id, err := cs.FirstBatch(iter, &CursorOpts{NS: "db.c", BatchSize: 2, SingleBatch: true})
+		require.NoError(t, err)
+		assert.Equal(t, []int32{1, 2}, batchIDs(t, batch))
+		assert.Zero(t, id)
+		assert.Equal(t, int32(1), iter.closeCount())
+	})
+
+	t.Run("IteratorError", func(t *testing.T) {
+		t.Parallel()
+
+		cs := newTestCursors(t, DefaultCursorTimeout)
+		iter := newFakeIterator(3)
+		iter.err = errors.New("iterator error")
+
+		_, id, err := cs.FirstBatch(iter, &CursorOpts{NS: "db.c", BatchSize: 1})
+		require.NoError(t, err)
+		require.NotZero(t, id)
+
+		_, nextID, err := cs.GetMore(id, "db.c", 5)
+		assert.Equal(t, iter.err, err)
+		assert.Zero(t, nextID)
+		assert.Equal(t, int32(1), iter.closeCount())
+
+		_, _, err = cs.GetMore(id, "db.c", 1)
+		requireErrorCode(t, ErrCursorNotFound, err)
+	})
+
+	t.Run("OtherNamespace", func(t *testing.T) {
+		t.Parallel()
+
+		cs := newTestCursors(t, DefaultCursorTimeout)
+		iter := newFakeIterator(5)
+
+		_, id, err := cs.FirstBatch(iter, &CursorOpts{NS: "db.c", BatchSize: 1})
+		require.NoError(t, err)
+		require.NotZero(t, id)
+
+		_, _, err = cs.GetMore(id, "db.other", 1)
+		requireErrorCode(t, ErrUnauthorized, err)
+
+		killed, notFound := cs.Kill("db.other", []int64{id})
+		assert.Empty(t, killed)
+		assert.Equal(t, []int64{id}, notFound)
+		assert.Zero(t, iter.closeCount())
+	})
+
+	t.Run("Kill", func(t *testing.T) {
+		t.Parallel()
+
+		cs := newTestCursors(t, DefaultCursorTimeout)
+		iter := newFakeIterator(5)
+
+		_, id, err := cs.FirstBatch(iter, &CursorOpts{NS: "db.c", BatchSize: 1})
+		require.NoError(t, err)
+		require.NotZero(t, id)
+
+		killed, notFound := cs.Kill("db.c", []int64{id, 42})
+		assert.Equal(t, []int64{id}, killed)
+		assert.Equal(t, []int64{42}, notFound)
+		assert.Equal(t, int32(1), iter.closeCount())
+
+		_, _, err = cs.GetMore(id, "db.c", 1)
+		requireErrorCode(t, ErrCursorNotFound, err)
+	})
+
+	t.Run("KillInUse", func(t *testing.T) {
+		t.Parallel()
+
+		cs := newTestCursors(t, DefaultCursorTimeout)
+		iter := newFakeIterator(5)
+
+		_, id, err := cs.FirstBatch(iter, &CursorOpts{NS: "db.c", BatchSize: 1})
+		require.NoError(t, err)
+		require.NotZero(t, id)
+
+		wait := make(chan struct{})
+		iter.wait = wait
+
+		type result struct {
+			batch *types.Array
+			id    int64
+			err   error
+		}
+		done := make(chan result)
+		go func() {
+			var res result
+			res.batch, res.id, res.err = cs.GetMore(id, "db.c", 2)
+			done <- res
+		}()
+
+		<-iter.entered
+
+		_, _, err = cs.GetMore(id, "db.c", 1)
+		requireErrorCode(t, ErrCursorInUse, err)
+
+		// the cursor is removed, but its iterator is closed by GetMore that uses it
+		killed, notFound := cs.Kill("db.c", []int64{id})
+		assert.Equal(t, []int64{id}, killed)
+		assert.Empty(t, notFound)
+		assert.Zero(t, iter.closeCount())
+
+		_, _, err = cs.GetMore(id, "db.c", 1)
+		requireErrorCode(t, ErrCursorNotFound, err)
+
+		close(wait)
+		res := <-done
+		require.NoError(t, res.err)
+		assert.Equal(t, []int32{2, 3}, batchIDs(t, res.batch))
+		assert.Zero(t, res.id)
+		assert.Equal(t, int32(1), iter.closeCount())
+	})
+
+	t.Run("IdleTimeout", func(t *testing.T) {
+		t.Parallel()
+
+		timeout := time.Minute
+		cs := newTestCursors(t, timeout)
+
+		idle := newFakeIterator(5)
+		_, idleID, err := cs.FirstBatch(idle, &CursorOpts{NS: "db.c", BatchSize: 1})
+		require.NoError(t, err)
+
+		noTimeout := newFakeIterator(5)
+		_, noTimeoutID, err := cs.FirstBatch(noTimeout, &CursorOpts{NS: "db.c", BatchSize: 1, NoTimeout: true})
+		require.NoError(t, err)
+
+		inUse := newFakeIterator(5)
+		_, inUseID, err := cs.FirstBatch(inUse, &CursorOpts{NS: "db.c", BatchSize: 1})
+		require.NoError(t, err)
+
+		wait := make(chan struct{})
+		inUse.wait = wait
+		done := make(chan error)
+		go func() {
+			_, _, err := cs.GetMore(inUseID, "db.c", 1)
+			done <- err
+		}()
+		<-inUse.entered
+
+		cs.closeIdle(time.Now())
+		assert.Zero(t, idle.closeCount())
+
+		cs.closeIdle(time.Now().Add(timeout + time.Second))
+		assert.Equal(t, int32(1), idle.closeCount())
+		assert.Zero(t, noTimeout.closeCount())
+		assert.Zero(t, inUse.closeCount())
+
+		_, _, err = cs.GetMore(idleID, "db.c", 1)
+		requireErrorCode(t, ErrCursorNotFound, err)
+
+		_, id, err := cs.GetMore(noTimeoutID, "db.c", 1)
+		require.NoError(t, err)
+		assert.Equal(t, noTimeoutID, id)
+
+		// getMore resets idle time
+		close(wait)
+		require.NoError(t, <-done)
+		cs.closeIdle(time.Now().Add(timeout / 2))
+		_, id, err = cs.GetMore(inUseID, "db.c", 1)
+		require.NoError(t, err)
+		assert.Equal(t, inUseID, id)
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		t.Parallel()
+
+		cs := newTestCursors(t, 10*time.Millisecond)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		go cs.Run(ctx)
+
+		iter := newFakeIterator(5)
+		_, id, err := cs.FirstBatch(iter, &CursorOpts{NS: "db.c", BatchSize: 1})
+		require.NoError(t, err)
+		require.NotZero(t, id)
+
+		assert.Eventually(t, func() bool { return iter.closeCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+		_, _, err = cs.GetMore(id, "db.c", 1)
+		requireErrorCode(t, ErrCursorNotFound, err)
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		t.Parallel()
+
+		cs := NewCursors(zaptest.NewLogger(t), DefaultCursorTimeout)
+
+		iter := newFakeIterator(5)
+		_, id, err := cs.FirstBatch(iter, &CursorOpts{NS: "db.c", BatchSize: 1})
+		require.NoError(t, err)
+		require.NotZero(t, id)
+
+		cs.Close()
+		assert.Equal(t, int32(1), iter.closeCount())
+
+		// new cursors are not registered
+		iter = newFakeIterator(5)
+		_, id, err = cs.FirstBatch(iter, &CursorOpts{NS: "db.c", BatchSize: 1})
+		assert.Error(t, err)
+		assert.Zero(t, id)
+		assert.Equal(t, int32(1), iter.closeCount())
+	})
+}
+
+func TestGetBatchSize(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		value    any // not set if nil
+		expected int32
+		err      ErrorCode
+	}{
+		"NotSet": {
+			expected: 42,
+		},
+		"Int32": {
+			value:    int32(2),
+			expected: 2,
+		},
+		"Int64Large": {
+			value:    int64(1 << 40),
+			expected: 1<<31 - 1,
+		},
+		"Double": {
+			value:    float64(2.9),
+			expected: 2,
+		},
+		"NaN": {
+			value: math.NaN(),
+			err:   ErrBadValue,
+		},
+		"Negative": {
+			value: int32(-1),
+			err:   ErrBadValue,
+		},
+		"String": {
+			value: "1",
+			err:   ErrTypeMismatch,
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			doc := must.NotFail(types.NewDocument())
+			if tc.value != nil {
+				must.NoError(doc.Set("batchSize", tc.value))
+			}
+
+			actual, err := GetBatchSize(doc, 42)
+			if tc.err != 0 {
+				requireErrorCode(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -30,12 +30,22 @@ const (
 	// For ProtocolError only.
 	errInternalError = ErrorCode(1) // InternalError
 
-	ErrBadValue          = ErrorCode(2)     // BadValue
-	ErrNamespaceNotFound = ErrorCode(26)    // NamespaceNotFound
-	ErrNamespaceExists   = ErrorCode(48)    // NamespaceExists
-	ErrCommandNotFound   = ErrorCode(59)    // CommandNotFound
-	ErrNotImplemented    = ErrorCode(238)   // NotImplemented
-	ErrRegexOptions      = ErrorCode(51075) // Location51075
+	ErrBadValue           = ErrorCode(2)     // BadValue
+	ErrUnauthorized       = ErrorCode(13)    // Unauthorized
+	ErrTypeMismatch       = ErrorCode(14)    // TypeMismatch
+	ErrProtocolError      = ErrorCode(17)    // ProtocolError
+	ErrNamespaceNotFound  = ErrorCode(26)    // NamespaceNotFound
+	ErrPathNotViable      = ErrorCode(28)    // PathNotViable
+	ErrCursorNotFound     = ErrorCode(43)    // CursorNotFound
+	ErrNamespaceExists    = ErrorCode(48)    // NamespaceExists
+	ErrInvalidIDField     = ErrorCode(53)    // InvalidIdField
+	ErrEmptyFieldName     = ErrorCode(56)    // EmptyFieldName
+	ErrCommandNotFound    = ErrorCode(59)    // CommandNotFound
+	ErrInvalidNamespace   = ErrorCode(73)    // InvalidNamespace
+	ErrNotImplemented     = ErrorCode(238)   // NotImplemented
+	ErrCursorInUse        = ErrorCode(292)   // CursorInUse
+	ErrBSONObjectTooLarge = ErrorCode(10334) // BSONObjectTooLarge
+	ErrRegexOptions       = ErrorCode(51075) // Location51075
 )
 
 // Error represents wire protocol error.
@@ -65,6 +75,11 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%[1]s (%[1]d): %[2]v", e.code, e.err)
 }
 
+// Code returns wire protocol error code.
+func (e *Error) Code() ErrorCode {
+	return e.code
+}
+
 // Unwrap implements standard error unwrapping interface.
 func (e *Error) Unwrap() error {
 	return e.err
@@ -80,9 +95,19 @@ func (e *Error) Document() *types.Document {
 	)
 }
 
+// QueryFailureDocument returns wire protocol error document for OP_REPLY with QueryFailure flag.
+func (e *Error) QueryFailureDocument() *types.Document {
+	return types.MustNewDocument(
+		"$err", e.err.Error(),
+		"code", int32(e.code),
+		"ok", float64(0),
+	)
+}
+
 // ProtocolError converts any error to wire protocol error.
 //
 // Nil panics, *Error (possibly wrapped) is returned unwrapped with true,
+// *types.PathError (possibly wrapped) is converted to *Error with the same code and returned with true,
 // any other value is wrapped with InternalError and returned with false.
 func ProtocolError(err error) (*Error, bool) {
 	if err == nil {
@@ -94,6 +119,11 @@ func ProtocolError(err error) (*Error, bool) {
 		return e, true
 	}
 
+	var pe *types.PathError
+	if errors.As(err, &pe) {
+		return NewError(ErrorCode(pe.Code()), pe.Unwrap()).(*Error), true
+	}
+
 	return NewError(errInternalError, err).(*Error), false
 }
 
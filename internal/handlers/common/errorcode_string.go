@@ -10,42 +10,48 @@ func _() {
 	var x [1]struct{}
 	_ = x[errInternalError-1]
 	_ = x[ErrBadValue-2]
+	_ = x[ErrUnauthorized-13]
+	_ = x[ErrTypeMismatch-14]
+	_ = x[ErrProtocolError-17]
 	_ = x[ErrNamespaceNotFound-26]
+	_ = x[ErrPathNotViable-28]
+	_ = x[ErrCursorNotFound-43]
 	_ = x[ErrNamespaceExists-48]
+	_ = x[ErrInvalidIDField-53]
+	_ = x[ErrEmptyFieldName-56]
 	_ = x[ErrCommandNotFound-59]
+	_ = x[ErrInvalidNamespace-73]
 	_ = x[ErrNotImplemented-238]
+	_ = x[ErrCursorInUse-292]
+	_ = x[ErrBSONObjectTooLarge-10334]
 	_ = x[ErrRegexOptions-51075]
 }
 
-const (
-	_ErrorCode_name_0 = "InternalErrorBadValue"
-	_ErrorCode_name_1 = "NamespaceNotFound"
-	_ErrorCode_name_2 = "NamespaceExists"
-	_ErrorCode_name_3 = "CommandNotFound"
-	_ErrorCode_name_4 = "NotImplemented"
-	_ErrorCode_name_5 = "Location51075"
-)
+const _ErrorCode_name = "InternalErrorBadValueUnauthorizedTypeMismatchProtocolErrorNamespaceNotFoundPathNotViableCursorNotFoundNamespaceExistsInvalidIdFieldEmptyFieldNameCommandNotFoundInvalidNamespaceNotImplementedCursorInUseBSONObjectTooLargeLocation51075"
 
-var (
-	_ErrorCode_index_0 = [...]uint8{0, 13, 21}
-)
+var _ErrorCode_map = map[ErrorCode]string{
+	1:     _ErrorCode_name[0:13],
+	2:     _ErrorCode_name[13:21],
+	13:    _ErrorCode_name[21:33],
+	14:    _ErrorCode_name[33:45],
+	17:    _ErrorCode_name[45:58],
+	26:    _ErrorCode_name[58:75],
+	28:    _ErrorCode_name[75:88],
+	43:    _ErrorCode_name[88:102],
+	48:    _ErrorCode_name[102:117],
+	53:    _ErrorCode_name[117:131],
+	56:    _ErrorCode_name[131:145],
+	59:    _ErrorCode_name[145:160],
+	73:    _ErrorCode_name[160:176],
+	238:   _ErrorCode_name[176:190],
+	292:   _ErrorCode_name[190:201],
+	10334: _ErrorCode_name[201:219],
+	51075: _ErrorCode_name[219:232],
+}
 
 func (i ErrorCode) String() string {
-	switch {
-	case 1 <= i && i <= 2:
-		i -= 1
-		return _ErrorCode_name_0[_ErrorCode_index_0[i]:_ErrorCode_index_0[i+1]]
-	case i == 26:
-		return _ErrorCode_name_1
-	case i == 48:
-		return _ErrorCode_name_2
-	case i == 59:
-		return _ErrorCode_name_3
-	case i == 238:
-		return _ErrorCode_name_4
-	case i == 51075:
-		return _ErrorCode_name_5
-	default:
-		return "ErrorCode(" + strconv.FormatInt(int64(i), 10) + ")"
+	if str, ok := _ErrorCode_map[i]; ok {
+		return str
 	}
+	return "ErrorCode(" + strconv.FormatInt(int64(i), 10) + ")"
 }
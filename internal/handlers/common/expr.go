@@ -0,0 +1,614 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+)
+
+// EvalExpr evaluates aggregation expression like {$gt: ["$spent", "$budget"]} for the given document.
+//
+// Missing fields and expressions that evaluate to them return nil.
+func EvalExpr(expr any, doc *types.Document) (any, error) {
+	switch expr := expr.(type) {
+	case string:
+		if strings.HasPrefix(expr, "$") {
+			return fieldPathValue(expr, doc)
+		}
+		return expr, nil
+
+	case *types.Document:
+		keys := expr.Keys()
+		if len(keys) != 0 && strings.HasPrefix(keys[0], "$") {
+			if len(keys) != 1 {
+				return nil, NewError(ErrBadValue, fmt.Errorf(
+					"an expression specification must contain exactly one field, the name of the expression. Found %d fields",
+					len(keys),
+				))
+			}
+			return evalOperator(keys[0], must.NotFail(expr.Get(keys[0])), doc)
+		}
+
+		res := types.MustNewDocument()
+		for _, key := range keys {
+			v, err := EvalExpr(must.NotFail(expr.Get(key)), doc)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				continue
+			}
+			if err = res.Set(key, v); err != nil {
+				return nil, NewError(ErrBadValue, err)
+			}
+		}
+		return res, nil
+
+	case *types.Array:
+		res := types.MakeArray(expr.Len())
+		for i := 0; i < expr.Len(); i++ {
+			v, err := EvalExpr(must.NotFail(expr.Get(i)), doc)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				v = types.Null
+			}
+			must.NoError(res.Append(v))
+		}
+		return res, nil
+
+	default:
+		return expr, nil
+	}
+}
+
+// ExprTruthy returns true if the value returned by EvalExpr is considered true.
+func ExprTruthy(value any) bool {
+	return value != nil && Truthy(value)
+}
+
+// CompareExprValues compares values returned by EvalExpr using MongoDB comparison order.
+// Missing values are less than any other value.
+func CompareExprValues(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	default:
+		return types.Compare(a, b)
+	}
+}
+
+// fieldPathValue returns the value of the field path like "$a.b" or "$$ROOT.a".
+//
+// Arrays in the path produce arrays of the field values of their elements.
+func fieldPathValue(path string, doc *types.Document) (any, error) {
+	var v any = doc
+	var rest string
+
+	switch {
+	case path == "$":
+		return nil, NewError(ErrBadValue, fmt.Errorf("'$' by itself is not a valid FieldPath"))
+	case strings.HasPrefix(path, "$$"):
+		name, fields, _ := strings.Cut(path[2:], ".")
+		if name != "ROOT" && name != "CURRENT" {
+			return nil, NewError(ErrBadValue, fmt.Errorf("Use of undefined variable: %s", name))
+		}
+		if fields == "" {
+			return doc, nil
+		}
+		rest = fields
+	default:
+		rest = path[1:]
+	}
+
+	for _, key := range strings.Split(rest, ".") {
+		if key == "" {
+			return nil, NewError(ErrBadValue, fmt.Errorf("FieldPath field names may not be empty strings."))
+		}
+		if v = fieldPathStep(v, key); v == nil {
+			return nil, nil
+		}
+	}
+
+	return v, nil
+}
+
+// fieldPathStep returns the value of the field with the given key, or nil if it is missing.
+func fieldPathStep(v any, key string) any {
+	switch v := v.(type) {
+	case *types.Document:
+		res, err := v.Get(key)
+		if err != nil {
+			return nil
+		}
+		return res
+
+	case *types.Array:
+		res := types.MakeArray(v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if el := fieldPathStep(must.NotFail(v.Get(i)), key); el != nil {
+				must.NoError(res.Append(el))
+			}
+		}
+		return res
+
+	default:
+		return nil
+	}
+}
+
+// evalArgs evaluates operator arguments: array elements, or a single value.
+func evalArgs(arg any, doc *types.Document) ([]any, error) {
+	arr, ok := arg.(*types.Array)
+	if !ok {
+		v, err := EvalExpr(arg, doc)
+		if err != nil {
+			return nil, err
+		}
+		return []any{v}, nil
+	}
+
+	res := make([]any, arr.Len())
+	for i := range res {
+		v, err := EvalExpr(must.NotFail(arr.Get(i)), doc)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = v
+	}
+
+	return res, nil
+}
+
+// checkArgs checks the number of operator arguments.
+func checkArgs(op string, args []any, n int) error {
+	if len(args) != n {
+		return NewError(ErrBadValue, fmt.Errorf("Expression %s takes exactly %d arguments. %d were passed in.", op, n, len(args)))
+	}
+	return nil
+}
+
+// isNullish returns true for missing, null and undefined values.
+func isNullish(v any) bool {
+	switch v.(type) {
+	case nil, types.NullType, types.UndefinedType:
+		return true
+	default:
+		return false
+	}
+}
+
+// evalOperator evaluates expression operator op with the given argument.
+func evalOperator(op string, arg any, doc *types.Document) (any, error) {
+	switch op {
+	case "$literal":
+		return arg, nil
+	case "$cond":
+		return evalCond(arg, doc)
+	}
+
+	args, err := evalArgs(arg, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "$eq", "$ne", "$gt", "$gte", "$lt", "$lte", "$cmp":
+		if err = checkArgs(op, args, 2); err != nil {
+			return nil, err
+		}
+
+		c := CompareExprValues(args[0], args[1])
+		switch op {
+		case "$eq":
+			return c == 0, nil
+		case "$ne":
+			return c != 0, nil
+		case "$gt":
+			return c > 0, nil
+		case "$gte":
+			return c >= 0, nil
+		case "$lt":
+			return c < 0, nil
+		case "$lte":
+			return c <= 0, nil
+		default:
+			return int32(c), nil
+		}
+
+	case "$and":
+		for _, v := range args {
+			if !ExprTruthy(v) {
+				return false, nil
+			}
+		}
+		return true, nil
+
+	case "$or":
+		for _, v := range args {
+			if ExprTruthy(v) {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case "$not":
+		if err = checkArgs(op, args, 1); err != nil {
+			return nil, err
+		}
+		return !ExprTruthy(args[0]), nil
+
+	case "$add", "$subtract", "$multiply", "$divide", "$mod", "$abs":
+		return evalArithmetic(op, args)
+
+	case "$ifNull":
+		if len(args) < 2 {
+			return nil, NewError(ErrBadValue, fmt.Errorf("$ifNull needs at least two arguments, had: %d", len(args)))
+		}
+		for _, v := range args[:len(args)-1] {
+			if !isNullish(v) {
+				return v, nil
+			}
+		}
+		return args[len(args)-1], nil
+
+	case "$in":
+		if err = checkArgs(op, args, 2); err != nil {
+			return nil, err
+		}
+		arr, ok := args[1].(*types.Array)
+		if !ok {
+			return nil, NewError(ErrBadValue, fmt.Errorf("$in requires an array as a second argument, found: %s", exprTypeAlias(args[1])))
+		}
+		for i := 0; i < arr.Len(); i++ {
+			if CompareExprValues(args[0], must.NotFail(arr.Get(i))) == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case "$size":
+		if err = checkArgs(op, args, 1); err != nil {
+			return nil, err
+		}
+		arr, ok := args[0].(*types.Array)
+		if !ok {
+			err = fmt.Errorf("The argument to $size must be an array. Type of argument is %s", exprTypeAlias(args[0]))
+			return nil, NewError(ErrBadValue, err)
+		}
+		return int32(arr.Len()), nil
+
+	case "$type":
+		if err = checkArgs(op, args, 1); err != nil {
+			return nil, err
+		}
+		return exprTypeAlias(args[0]), nil
+
+	case "$concat":
+		var res string
+		for _, v := range args {
+			if isNullish(v) {
+				return types.Null, nil
+			}
+			s, ok := v.(string)
+			if !ok {
+				return nil, NewError(ErrTypeMismatch, fmt.Errorf("$concat only supports strings, not %s", exprTypeAlias(v)))
+			}
+			res += s
+		}
+		return res, nil
+
+	case "$toLower", "$toUpper":
+		if err = checkArgs(op, args, 1); err != nil {
+			return nil, err
+		}
+		if isNullish(args[0]) {
+			return "", nil
+		}
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, NewError(ErrTypeMismatch, fmt.Errorf("%s only supports strings, not %s", op, exprTypeAlias(args[0])))
+		}
+		if op == "$toLower" {
+			return strings.ToLower(s), nil
+		}
+		return strings.ToUpper(s), nil
+
+	default:
+		return nil, NewError(ErrNotImplemented, fmt.Errorf("expression %s is not implemented yet", op))
+	}
+}
+
+// exprTypeAlias returns type alias of the value returned by EvalExpr.
+func exprTypeAlias(v any) string {
+	if v == nil {
+		return "missing"
+	}
+	return TypeAlias(v)
+}
+
+// evalCond evaluates {$cond: [if, then, else]} and {$cond: {if: ..., then: ..., else: ...}};
+// only the selected branch is evaluated.
+func evalCond(arg any, doc *types.Document) (any, error) {
+	var ifExpr, thenExpr, elseExpr any
+
+	switch arg := arg.(type) {
+	case *types.Array:
+		if arg.Len() != 3 {
+			return nil, NewError(ErrBadValue, fmt.Errorf("Expression $cond takes exactly 3 arguments. %d were passed in.", arg.Len()))
+		}
+		ifExpr, thenExpr, elseExpr = must.NotFail(arg.Get(0)), must.NotFail(arg.Get(1)), must.NotFail(arg.Get(2))
+
+	case *types.Document:
+		m := arg.Map()
+		for _, key := range arg.Keys() {
+			if key != "if" && key != "then" && key != "else" {
+				return nil, NewError(ErrBadValue, fmt.Errorf("Unrecognized parameter to $cond: %s", key))
+			}
+		}
+		for _, key := range []string{"if", "then", "else"} {
+			if _, ok := m[key]; !ok {
+				return nil, NewError(ErrBadValue, fmt.Errorf("Missing '%s' parameter to $cond", key))
+			}
+		}
+		ifExpr, thenExpr, elseExpr = m["if"], m["then"], m["else"]
+
+	default:
+		return nil, NewError(ErrBadValue, fmt.Errorf("Expression $cond takes exactly 3 arguments. 1 were passed in."))
+	}
+
+	cond, err := EvalExpr(ifExpr, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if ExprTruthy(cond) {
+		return EvalExpr(thenExpr, doc)
+	}
+	return EvalExpr(elseExpr, doc)
+}
+
+// evalArithmetic evaluates arithmetic expression operators.
+//
+// Integer results that overflow their type are widened to int64, then to double, like MongoDB does.
+// Decimal128 arithmetic is not supported yet: any decimal argument returns NotImplemented error,
+// not TypeMismatch as for non-numeric values.
+func evalArithmetic(op string, args []any) (any, error) {
+	for _, v := range args {
+		if _, ok := v.(types.Decimal128); ok {
+			return nil, NewError(ErrNotImplemented, fmt.Errorf("%s for decimal values is not implemented yet", op))
+		}
+	}
+
+	switch op {
+	case "$add":
+		var res any = int32(0)
+		var date *time.Time
+		for _, v := range args {
+			if isNullish(v) {
+				return types.Null, nil
+			}
+			if t, ok := v.(time.Time); ok {
+				if date != nil {
+					return nil, NewError(ErrTypeMismatch, fmt.Errorf("only one date allowed in an $add expression"))
+				}
+				date = &t
+				continue
+			}
+			if !isNumber(v) {
+				return nil, NewError(ErrTypeMismatch, fmt.Errorf("$add only supports numeric or date types, not %s", exprTypeAlias(v)))
+			}
+			res = addNumbers(res, v)
+		}
+		if date != nil {
+			return date.Add(time.Duration(math.Round(toFloat64(res))) * time.Millisecond), nil
+		}
+		return res, nil
+
+	case "$multiply":
+		var res any = int32(1)
+		for _, v := range args {
+			if isNullish(v) {
+				return types.Null, nil
+			}
+			if !isNumber(v) {
+				return nil, NewError(ErrTypeMismatch, fmt.Errorf("$multiply only supports numeric types, not %s", exprTypeAlias(v)))
+			}
+			res = multiplyNumbers(res, v)
+		}
+		return res, nil
+
+	case "$abs":
+		if err := checkArgs(op, args, 1); err != nil {
+			return nil, err
+		}
+		switch v := args[0].(type) {
+		case int32:
+			if v == math.MinInt32 {
+				return -int64(v), nil
+			}
+			if v < 0 {
+				v = -v
+			}
+			return v, nil
+		case int64:
+			if v == math.MinInt64 {
+				return nil, NewError(ErrBadValue, fmt.Errorf("can't take $abs of long long min"))
+			}
+			if v < 0 {
+				v = -v
+			}
+			return v, nil
+		case float64:
+			return math.Abs(v), nil
+		default:
+			if isNullish(v) {
+				return types.Null, nil
+			}
+			return nil, NewError(ErrTypeMismatch, fmt.Errorf("$abs only supports numeric types, not %s", exprTypeAlias(v)))
+		}
+	}
+
+	// binary operators
+	if err := checkArgs(op, args, 2); err != nil {
+		return nil, err
+	}
+	a, b := args[0], args[1]
+	if isNullish(a) || isNullish(b) {
+		return types.Null, nil
+	}
+
+	if op == "$subtract" {
+		ta, aIsDate := a.(time.Time)
+		tb, bIsDate := b.(time.Time)
+		switch {
+		case aIsDate && bIsDate:
+			return ta.Sub(tb).Milliseconds(), nil
+		case aIsDate && isNumber(b):
+			return ta.Add(-time.Duration(math.Round(toFloat64(b))) * time.Millisecond), nil
+		case isNumber(a) && isNumber(b):
+			return addNumbers(a, negateNumber(b)), nil
+		}
+		err := fmt.Errorf("can't $subtract %s from %s", exprTypeAlias(b), exprTypeAlias(a))
+		return nil, NewError(ErrTypeMismatch, err)
+	}
+
+	if !isNumber(a) || !isNumber(b) {
+		err := fmt.Errorf("%s only supports numeric types, not %s and %s", op, exprTypeAlias(a), exprTypeAlias(b))
+		return nil, NewError(ErrTypeMismatch, err)
+	}
+
+	if toFloat64(b) == 0 {
+		return nil, NewError(ErrBadValue, fmt.Errorf("can't %s by zero", op))
+	}
+
+	if op == "$divide" {
+		return toFloat64(a) / toFloat64(b), nil
+	}
+
+	// $mod
+	ia, aIsInt := toInt64(a)
+	ib, bIsInt := toInt64(b)
+	if !aIsInt || !bIsInt {
+		return math.Mod(toFloat64(a), toFloat64(b)), nil
+	}
+	if ib == -1 {
+		// avoid MinInt64 % -1 overflow
+		ib = 1
+	}
+	return narrowInt(ia%ib, a, b), nil
+}
+
+// isNumber returns true for int32, int64 and float64 values.
+// Decimal128 values are rejected by evalArithmetic before that check.
+func isNumber(v any) bool {
+	switch v.(type) {
+	case int32, int64, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// toInt64 returns int32 or int64 value as int64.
+func toInt64(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// toFloat64 returns int32, int64 or float64 value as float64.
+func toFloat64(v any) float64 {
+	switch v := v.(type) {
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		panic(fmt.Sprintf("common.toFloat64: unexpected type %T", v))
+	}
+}
+
+// narrowInt returns int32 result if both integer operands are int32 and the result fits, int64 otherwise.
+func narrowInt(res int64, a, b any) any {
+	_, aIsInt32 := a.(int32)
+	_, bIsInt32 := b.(int32)
+	if aIsInt32 && bIsInt32 && res >= math.MinInt32 && res <= math.MaxInt32 {
+		return int32(res)
+	}
+	return res
+}
+
+// negateNumber returns -v.
+func negateNumber(v any) any {
+	switch v := v.(type) {
+	case int32:
+		if v == math.MinInt32 {
+			return -int64(v)
+		}
+		return -v
+	case int64:
+		if v == math.MinInt64 {
+			return -float64(v)
+		}
+		return -v
+	default:
+		return -toFloat64(v)
+	}
+}
+
+// addNumbers returns a + b.
+func addNumbers(a, b any) any {
+	ia, aIsInt := toInt64(a)
+	ib, bIsInt := toInt64(b)
+	if aIsInt && bIsInt {
+		res := ia + ib
+		if (res > ia) == (ib > 0) {
+			return narrowInt(res, a, b)
+		}
+	}
+	return toFloat64(a) + toFloat64(b)
+}
+
+// multiplyNumbers returns a * b.
+func multiplyNumbers(a, b any) any {
+	ia, aIsInt := toInt64(a)
+	ib, bIsInt := toInt64(b)
+	if aIsInt && bIsInt {
+		res := ia * ib
+		if ia == 0 || (res/ia == ib && !(ia == -1 && ib == math.MinInt64) && !(ib == -1 && ia == math.MinInt64)) {
+			return narrowInt(res, a, b)
+		}
+	}
+	return toFloat64(a) * toFloat64(b)
+}
@@ -0,0 +1,342 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+)
+
+func TestEvalExpr(t *testing.T) {
+	t.Parallel()
+
+	date := time.Date(2021, 11, 1, 10, 18, 42, 0, time.UTC)
+	doc := types.MustNewDocument(
+		"_id", int32(1),
+		"int", int32(42),
+		"long", int64(math.MaxInt64),
+		"double", float64(0.5),
+		"string", "Foo",
+		"null", types.Null,
+		"date", date,
+		"decimal", must.NotFail(types.ParseDecimal128("-1.5")),
+		"array", types.MustNewArray(int32(1), int32(2)),
+		"docs", types.MustNewArray(
+			types.MustNewDocument("a", int32(1)),
+			types.MustNewDocument("b", int32(2)),
+			types.MustNewDocument("a", int32(3)),
+		),
+		"doc", types.MustNewDocument("a", types.MustNewDocument("b", "bar")),
+	)
+
+	for name, tc := range map[string]struct {
+		expr     any
+		expected any
+		err      ErrorCode
+	}{
+		"Literal": {
+			expr:     "foo",
+			expected: "foo",
+		},
+		"FieldPath": {
+			expr:     "$doc.a.b",
+			expected: "bar",
+		},
+		"FieldPathMissing": {
+			expr: "$doc.c",
+		},
+		"FieldPathArray": {
+			expr:     "$docs.a",
+			expected: types.MustNewArray(int32(1), int32(3)),
+		},
+		"FieldPathRoot": {
+			expr:     "$$ROOT.int",
+			expected: int32(42),
+		},
+		"FieldPathEmpty": {
+			expr: "$",
+			err:  ErrBadValue,
+		},
+		"FieldPathEmptyField": {
+			expr: "$doc..a",
+			err:  ErrBadValue,
+		},
+		"UndefinedVariable": {
+			expr: "$$foo",
+			err:  ErrBadValue,
+		},
+		"Document": {
+			expr:     types.MustNewDocument("a", "$int", "b", "$missing"),
+			expected: types.MustNewDocument("a", int32(42)),
+		},
+		"Array": {
+			expr:     types.MustNewArray("$int", "$missing"),
+			expected: types.MustNewArray(int32(42), types.Null),
+		},
+		"TwoOperators": {
+			expr: types.MustNewDocument("$eq", types.MustNewArray(int32(1), int32(1)), "$ne", types.MustNewArray(int32(1), int32(1))),
+			err:  ErrBadValue,
+		},
+		"UnknownOperator": {
+			expr: types.MustNewDocument("$foo", int32(1)),
+			err:  ErrNotImplemented,
+		},
+		"LiteralOperator": {
+			expr:     types.MustNewDocument("$literal", "$int"),
+			expected: "$int",
+		},
+		"EqNumbers": {
+			expr:     types.MustNewDocument("$eq", types.MustNewArray("$int", float64(42))),
+			expected: true,
+		},
+		"GtMissing": {
+			expr:     types.MustNewDocument("$gt", types.MustNewArray("$null", "$missing")),
+			expected: true,
+		},
+		"Cmp": {
+			expr:     types.MustNewDocument("$cmp", types.MustNewArray("$string", "$int")),
+			expected: int32(1),
+		},
+		"CmpArgs": {
+			expr: types.MustNewDocument("$cmp", types.MustNewArray("$string")),
+			err:  ErrBadValue,
+		},
+		"And": {
+			expr:     types.MustNewDocument("$and", types.MustNewArray(true, "$int", "$missing")),
+			expected: false,
+		},
+		"Or": {
+			expr:     types.MustNewDocument("$or", types.MustNewArray(int32(0), "$null", "$string")),
+			expected: true,
+		},
+		"Not": {
+			expr:     types.MustNewDocument("$not", types.MustNewArray("$missing")),
+			expected: true,
+		},
+		"CondArray": {
+			expr:     types.MustNewDocument("$cond", types.MustNewArray("$int", "yes", "$$foo")),
+			expected: "yes",
+		},
+		"CondDocument": {
+			expr:     types.MustNewDocument("$cond", types.MustNewDocument("if", "$null", "then", "$$foo", "else", "no")),
+			expected: "no",
+		},
+		"CondMissingParameter": {
+			expr: types.MustNewDocument("$cond", types.MustNewDocument("if", true, "then", "yes")),
+			err:  ErrBadValue,
+		},
+		"CondUnknownParameter": {
+			expr: types.MustNewDocument("$cond", types.MustNewDocument("if", true, "then", "yes", "else", "no", "foo", int32(1))),
+			err:  ErrBadValue,
+		},
+		"IfNull": {
+			expr:     types.MustNewDocument("$ifNull", types.MustNewArray("$missing", "$null", "$int")),
+			expected: int32(42),
+		},
+		"IfNullArgs": {
+			expr: types.MustNewDocument("$ifNull", types.MustNewArray("$int")),
+			err:  ErrBadValue,
+		},
+		"In": {
+			expr:     types.MustNewDocument("$in", types.MustNewArray(int64(2), "$array")),
+			expected: true,
+		},
+		"InNotArray": {
+			expr: types.MustNewDocument("$in", types.MustNewArray(int32(2), "$int")),
+			err:  ErrBadValue,
+		},
+		"Size": {
+			expr:     types.MustNewDocument("$size", "$docs"),
+			expected: int32(3),
+		},
+		"SizeNotArray": {
+			expr: types.MustNewDocument("$size", "$missing"),
+			err:  ErrBadValue,
+		},
+		"Type": {
+			expr:     types.MustNewDocument("$type", "$date"),
+			expected: "date",
+		},
+		"TypeMissing": {
+			expr:     types.MustNewDocument("$type", types.MustNewArray("$missing")),
+			expected: "missing",
+		},
+		"Concat": {
+			expr:     types.MustNewDocument("$concat", types.MustNewArray("$string", "bar")),
+			expected: "Foobar",
+		},
+		"ConcatNull": {
+			expr:     types.MustNewDocument("$concat", types.MustNewArray("$string", "$missing")),
+			expected: types.Null,
+		},
+		"ConcatNotString": {
+			expr: types.MustNewDocument("$concat", types.MustNewArray("$string", "$int")),
+			err:  ErrTypeMismatch,
+		},
+		"ToLower": {
+			expr:     types.MustNewDocument("$toLower", "$string"),
+			expected: "foo",
+		},
+		"ToUpperNull": {
+			expr:     types.MustNewDocument("$toUpper", "$null"),
+			expected: "",
+		},
+		"AddInt32": {
+			expr:     types.MustNewDocument("$add", types.MustNewArray("$int", int32(1))),
+			expected: int32(43),
+		},
+		"AddInt32Overflow": {
+			expr:     types.MustNewDocument("$add", types.MustNewArray(int32(math.MaxInt32), int32(1))),
+			expected: int64(math.MaxInt32 + 1),
+		},
+		"AddInt64Overflow": {
+			expr:     types.MustNewDocument("$add", types.MustNewArray("$long", int32(1))),
+			expected: float64(math.MaxInt64) + 1,
+		},
+		"AddDouble": {
+			expr:     types.MustNewDocument("$add", types.MustNewArray("$int", "$double")),
+			expected: float64(42.5),
+		},
+		"AddDate": {
+			expr:     types.MustNewDocument("$add", types.MustNewArray("$date", int64(1000))),
+			expected: date.Add(time.Second),
+		},
+		"AddTwoDates": {
+			expr: types.MustNewDocument("$add", types.MustNewArray("$date", "$date")),
+			err:  ErrTypeMismatch,
+		},
+		"AddNull": {
+			expr:     types.MustNewDocument("$add", types.MustNewArray("$int", "$missing")),
+			expected: types.Null,
+		},
+		"AddString": {
+			expr: types.MustNewDocument("$add", types.MustNewArray("$int", "$string")),
+			err:  ErrTypeMismatch,
+		},
+		"Subtract": {
+			expr:     types.MustNewDocument("$subtract", types.MustNewArray(int32(1), "$int")),
+			expected: int32(-41),
+		},
+		"SubtractMinInt32": {
+			expr:     types.MustNewDocument("$subtract", types.MustNewArray(int32(0), int32(math.MinInt32))),
+			expected: int64(-math.MinInt32),
+		},
+		"SubtractDates": {
+			expr:     types.MustNewDocument("$subtract", types.MustNewArray("$date", date.Add(-time.Minute))),
+			expected: int64(60000),
+		},
+		"SubtractFromDate": {
+			expr:     types.MustNewDocument("$subtract", types.MustNewArray("$date", float64(1000))),
+			expected: date.Add(-time.Second),
+		},
+		"SubtractDateFromNumber": {
+			expr: types.MustNewDocument("$subtract", types.MustNewArray(int32(1), "$date")),
+			err:  ErrTypeMismatch,
+		},
+		"Multiply": {
+			expr:     types.MustNewDocument("$multiply", types.MustNewArray("$int", int64(2), "$double")),
+			expected: float64(42),
+		},
+		"MultiplyOverflow": {
+			expr:     types.MustNewDocument("$multiply", types.MustNewArray(int32(math.MaxInt32), int32(2))),
+			expected: int64(math.MaxInt32 * 2),
+		},
+		"Divide": {
+			expr:     types.MustNewDocument("$divide", types.MustNewArray(int32(1), int32(4))),
+			expected: float64(0.25),
+		},
+		"DivideByZero": {
+			expr: types.MustNewDocument("$divide", types.MustNewArray("$int", float64(0))),
+			err:  ErrBadValue,
+		},
+		"DivideArgs": {
+			expr: types.MustNewDocument("$divide", types.MustNewArray("$int")),
+			err:  ErrBadValue,
+		},
+		"Mod": {
+			expr:     types.MustNewDocument("$mod", types.MustNewArray(int32(-7), int32(3))),
+			expected: int32(-1),
+		},
+		"ModDouble": {
+			expr:     types.MustNewDocument("$mod", types.MustNewArray(float64(7.5), int32(2))),
+			expected: float64(1.5),
+		},
+		"ModMinusOne": {
+			expr:     types.MustNewDocument("$mod", types.MustNewArray(int64(math.MinInt64), int32(-1))),
+			expected: int64(0),
+		},
+		"Abs": {
+			expr:     types.MustNewDocument("$abs", int32(math.MinInt32)),
+			expected: int64(-math.MinInt32),
+		},
+		"AbsLongMin": {
+			expr: types.MustNewDocument("$abs", int64(math.MinInt64)),
+			err:  ErrBadValue,
+		},
+		"AddDecimal": {
+			expr: types.MustNewDocument("$add", types.MustNewArray("$int", "$decimal")),
+			err:  ErrNotImplemented,
+		},
+		"AddDateDecimal": {
+			expr: types.MustNewDocument("$add", types.MustNewArray("$date", "$decimal")),
+			err:  ErrNotImplemented,
+		},
+		"SubtractDecimal": {
+			expr: types.MustNewDocument("$subtract", types.MustNewArray("$decimal", int32(1))),
+			err:  ErrNotImplemented,
+		},
+		"MultiplyDecimal": {
+			expr: types.MustNewDocument("$multiply", types.MustNewArray(int32(2), "$decimal")),
+			err:  ErrNotImplemented,
+		},
+		"DivideDecimal": {
+			expr: types.MustNewDocument("$divide", types.MustNewArray("$decimal", int32(2))),
+			err:  ErrNotImplemented,
+		},
+		"ModDecimal": {
+			expr: types.MustNewDocument("$mod", types.MustNewArray("$int", "$decimal")),
+			err:  ErrNotImplemented,
+		},
+		"AbsDecimal": {
+			expr: types.MustNewDocument("$abs", "$decimal"),
+			err:  ErrNotImplemented,
+		},
+		"AbsNull": {
+			expr:     types.MustNewDocument("$abs", "$missing"),
+			expected: types.Null,
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := EvalExpr(tc.expr, doc)
+			if tc.err != 0 {
+				requireErrorCode(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
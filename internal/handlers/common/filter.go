@@ -0,0 +1,611 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+)
+
+// FilterDocument returns true if the document matches the query filter.
+//
+// It implements MongoDB query language in Go for filters that can't be translated to SQL.
+func FilterDocument(doc *types.Document, filter *types.Document) (bool, error) {
+	return filterDocument(doc, filter, true)
+}
+
+// filterDocument returns true if the document matches the query filter.
+// Top is false for documents matched by $elemMatch.
+func filterDocument(doc *types.Document, filter *types.Document, top bool) (bool, error) {
+	for _, key := range filter.Keys() {
+		matches, err := filterPair(doc, key, must.NotFail(filter.Get(key)), top)
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// filterPair returns true if the document matches a single filter field.
+func filterPair(doc *types.Document, key string, value any, top bool) (bool, error) {
+	switch key {
+	case "$and", "$or", "$nor":
+		// {$or: [{expr1}, {expr2}, ...]}
+		// {$and: [{expr1}, {expr2}, ...]}
+		// {$nor: [{expr1}, {expr2}, ...]}
+		arr, ok := value.(*types.Array)
+		if !ok {
+			return false, NewError(ErrBadValue, fmt.Errorf("%s must be an array", key))
+		}
+		if arr.Len() == 0 {
+			return false, NewError(ErrBadValue, fmt.Errorf("$and/$or/$nor must be a nonempty array"))
+		}
+
+		var any bool
+		for i := 0; i < arr.Len(); i++ {
+			expr, ok := must.NotFail(arr.Get(i)).(*types.Document)
+			if !ok {
+				return false, NewError(ErrBadValue, fmt.Errorf("$or/$and/$nor entries need to be full objects"))
+			}
+
+			matches, err := filterDocument(doc, expr, top)
+			if err != nil {
+				return false, err
+			}
+
+			if key == "$and" && !matches {
+				return false, nil
+			}
+			any = any || matches
+		}
+
+		switch key {
+		case "$and":
+			return true, nil
+		case "$or":
+			return any, nil
+		default:
+			return !any, nil
+		}
+
+	case "$expr":
+		// {$expr: expr}
+		if !top {
+			return false, NewError(ErrBadValue, fmt.Errorf("$expr can only be applied to the top-level document"))
+		}
+
+		res, err := EvalExpr(value, doc)
+		if err != nil {
+			return false, err
+		}
+		return ExprTruthy(res), nil
+
+	case "$comment":
+		return true, nil
+	}
+
+	if strings.HasPrefix(key, "$") {
+		return false, NewError(ErrBadValue, fmt.Errorf("unknown top level operator: %s", key))
+	}
+
+	path, err := types.ParsePath(key)
+	if err != nil {
+		return false, err
+	}
+
+	switch value := value.(type) {
+	case *types.Document:
+		if isOperatorDocument(value) {
+			// {field: {expr}}
+			return filterOperators(doc, path, value)
+		}
+
+		// {field: {document}}
+		return filterOperators(doc, path, types.MustNewDocument("$eq", value))
+
+	case types.Regex:
+		// {field: /regex/}
+		return filterOperators(doc, path, types.MustNewDocument("$regex", value))
+
+	default:
+		// {field: value}
+		return filterOperators(doc, path, types.MustNewDocument("$eq", value))
+	}
+}
+
+// isOperatorDocument returns true if the document contains query operators like {$gte: 80, $lt: 85}.
+func isOperatorDocument(doc *types.Document) bool {
+	return doc.Len() != 0 && strings.HasPrefix(doc.Keys()[0], "$")
+}
+
+// fieldValues returns values of the field with the given path following MongoDB array semantics,
+// the same way as SQL filters do:
+//   - arrays in the middle of the path are traversed, so "a.b" matches {a: [{b: 1}, {b: 2}]};
+//   - numeric path elements also select array elements by index, so "a.0" matches {a: [1, 2]};
+//   - if unwrap is true, arrays at the end of the path produce both the array itself and its elements.
+//
+// Missing values are returned as nil.
+func fieldValues(root any, path []string, unwrap bool) []any {
+	values := []any{root}
+
+	for _, key := range path {
+		var next []any
+		for _, v := range values {
+			switch v := v.(type) {
+			case *types.Document:
+				next = append(next, documentValue(v, key))
+
+			case *types.Array:
+				for i := 0; i < v.Len(); i++ {
+					if d, ok := must.NotFail(v.Get(i)).(*types.Document); ok {
+						next = append(next, documentValue(d, key))
+					}
+				}
+
+				if index, ok := types.ArrayIndex(key); ok {
+					var el any
+					if index < v.Len() {
+						el = must.NotFail(v.Get(index))
+					}
+					next = append(next, el)
+				}
+
+			default:
+				next = append(next, nil)
+			}
+		}
+		values = next
+	}
+
+	if !unwrap {
+		return values
+	}
+
+	res := make([]any, 0, len(values))
+	for _, v := range values {
+		res = append(res, v)
+		if arr, ok := v.(*types.Array); ok {
+			for i := 0; i < arr.Len(); i++ {
+				res = append(res, must.NotFail(arr.Get(i)))
+			}
+		}
+	}
+
+	return res
+}
+
+// documentValue returns the value of the document's field, or nil if it is missing.
+func documentValue(doc *types.Document, key string) any {
+	v, err := doc.Get(key)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// anyValue returns true if f returns true for any value.
+func anyValue(values []any, f func(v any) (bool, error)) (bool, error) {
+	for _, v := range values {
+		matches, err := f(v)
+		if err != nil || matches {
+			return matches, err
+		}
+	}
+
+	return false, nil
+}
+
+// isNaN returns true if the value is a NaN number.
+func isNaN(v any) bool {
+	switch v := v.(type) {
+	case float64:
+		return math.IsNaN(v)
+	case types.Decimal128:
+		return v.IsNaN()
+	default:
+		return false
+	}
+}
+
+// equalValues returns true if the field value v equals the query value.
+// Null query value matches null, undefined and missing values.
+func equalValues(v, value any) bool {
+	if _, ok := value.(types.NullType); ok {
+		return isNullish(v)
+	}
+
+	return v != nil && types.Comparable(v, value) && types.Compare(v, value) == 0
+}
+
+// compareValues returns true if the field value v matches comparison query operator op with the given value.
+// Only values of the same type (or numbers of any type) are compared; NaN is not less or greater than anything.
+func compareValues(op string, v, value any) bool {
+	if _, ok := value.(types.NullType); ok {
+		return (op == "$lte" || op == "$gte") && isNullish(v)
+	}
+
+	if v == nil || !types.Comparable(v, value) {
+		return false
+	}
+
+	if isNaN(v) || isNaN(value) {
+		return (op == "$lte" || op == "$gte") && isNaN(v) && isNaN(value)
+	}
+
+	c := types.Compare(v, value)
+	switch op {
+	case "$lt":
+		return c < 0
+	case "$lte":
+		return c <= 0
+	case "$gt":
+		return c > 0
+	case "$gte":
+		return c >= 0
+	default:
+		panic(fmt.Sprintf("unexpected operator %q", op))
+	}
+}
+
+// regexValue returns regular expression for {$regex: value, $options: options} expression.
+func regexValue(expr *types.Document) (types.Regex, error) {
+	m := expr.Map()
+
+	var options string
+	if opts, ok := m["$options"]; ok {
+		if options, ok = opts.(string); !ok {
+			return types.Regex{}, NewError(ErrBadValue, fmt.Errorf("$options has to be a string"))
+		}
+	}
+
+	switch value := m["$regex"].(type) {
+	case string:
+		return types.Regex{Pattern: value, Options: options}, nil
+	case types.Regex:
+		if options != "" {
+			if value.Options != "" {
+				return types.Regex{}, NewError(ErrRegexOptions, fmt.Errorf("options set in both $regex and $options"))
+			}
+			value.Options = options
+		}
+		return value, nil
+	default:
+		return types.Regex{}, NewError(ErrBadValue, fmt.Errorf("$regex has to be a string"))
+	}
+}
+
+// matchRegex returns true if the value is a string matching the regular expression.
+func matchRegex(v any, re types.Regex) (bool, error) {
+	s, ok := v.(string)
+	if !ok {
+		return false, nil
+	}
+
+	compiled, err := CompileRegex(re)
+	if err != nil {
+		return false, err
+	}
+
+	return compiled.MatchString(s), nil
+}
+
+// inArray returns true if the field value v matches any $in operator element.
+func inArray(v any, arr *types.Array) (bool, error) {
+	for i := 0; i < arr.Len(); i++ {
+		el := must.NotFail(arr.Get(i))
+
+		if re, ok := el.(types.Regex); ok {
+			matches, err := matchRegex(v, re)
+			if err != nil || matches {
+				return matches, err
+			}
+			continue
+		}
+
+		if equalValues(v, el) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// bitsMatch returns true if the field value v matches bitwise query operator op with the given bit positions.
+// Only integer numbers and binary data are matched.
+func bitsMatch(op string, v any, positions []int) bool {
+	if b, ok := v.(types.Binary); ok {
+		if len(positions) == 0 {
+			return op == "$bitsAllSet" || op == "$bitsAllClear"
+		}
+
+		var set, clear int
+		for _, pos := range positions {
+			if pos/8 < len(b.B) && b.B[pos/8]&(1<<(pos%8)) != 0 {
+				set++
+			} else {
+				clear++
+			}
+		}
+
+		switch op {
+		case "$bitsAllSet":
+			return clear == 0
+		case "$bitsAnySet":
+			return set != 0
+		case "$bitsAllClear":
+			return set == 0
+		default:
+			return clear != 0
+		}
+	}
+
+	if f, ok := v.(float64); ok && f != math.Trunc(f) {
+		return false
+	}
+
+	n, ok := truncInt64(v)
+	if !ok {
+		return false
+	}
+
+	mask := bitsMask(positions)
+	switch op {
+	case "$bitsAllSet":
+		return n&mask == mask
+	case "$bitsAnySet":
+		return n&mask != 0
+	case "$bitsAllClear":
+		return n&mask == 0
+	default:
+		return n&mask != mask
+	}
+}
+
+// filterOperators returns true if the field with the given path matches all query operators
+// of the expression like {$gte: 80, $lt: 85}.
+//
+// Each operator matches if any of the field values (see fieldValues) matches,
+// while negated operators ($ne, $nin) match if none of them do.
+// Empty path means the root value itself; that is used by $elemMatch with query operators.
+func filterOperators(root any, path []string, expr *types.Document) (bool, error) {
+	// $size and $elemMatch apply to arrays themselves, not to their elements
+	values := fieldValues(root, path, len(path) != 0)
+	arrays := fieldValues(root, path, false)
+
+	for _, op := range expr.Keys() {
+		value := must.NotFail(expr.Get(op))
+
+		var matches bool
+		var err error
+
+		switch op {
+		case "$eq":
+			// {field: {$eq: value}}
+			matches, err = anyValue(values, func(v any) (bool, error) { return equalValues(v, value), nil })
+
+		case "$ne":
+			// {field: {$ne: value}}
+			matches, err = anyValue(values, func(v any) (bool, error) { return equalValues(v, value), nil })
+			matches = !matches
+
+		case "$lt", "$lte", "$gt", "$gte":
+			// {field: {$lt: value}}
+			// ...
+			matches, err = anyValue(values, func(v any) (bool, error) { return compareValues(op, v, value), nil })
+
+		case "$in", "$nin":
+			// {field: {$in: [value1, value2, ...]}}
+			// {field: {$nin: [value1, value2, ...]}}
+			arr, ok := value.(*types.Array)
+			if !ok {
+				return false, NewError(ErrBadValue, fmt.Errorf("%s needs an array", op))
+			}
+
+			matches, err = anyValue(values, func(v any) (bool, error) { return inArray(v, arr) })
+			if op == "$nin" {
+				matches = !matches
+			}
+
+		case "$exists":
+			// {field: {$exists: bool}}
+			matches, err = anyValue(values, func(v any) (bool, error) { return v != nil, nil })
+			matches = matches == Truthy(value)
+
+		case "$type":
+			// {field: {$type: alias}}
+			// {field: {$type: [alias1, alias2, ...]}}
+			var aliases []string
+			if aliases, err = ParseTypes(value); err != nil {
+				return false, err
+			}
+
+			matches, err = anyValue(values, func(v any) (bool, error) {
+				if v == nil {
+					return false, nil
+				}
+				alias := TypeAlias(v)
+				for _, a := range aliases {
+					if a == alias {
+						return true, nil
+					}
+				}
+				return false, nil
+			})
+
+		case "$regex":
+			// {field: {$regex: value, $options: string}}
+			var re types.Regex
+			if re, err = regexValue(expr); err != nil {
+				return false, err
+			}
+
+			matches, err = anyValue(values, func(v any) (bool, error) { return matchRegex(v, re) })
+
+		case "$options":
+			// handled by $regex
+			if _, ok := expr.Map()["$regex"]; !ok {
+				return false, NewError(ErrBadValue, fmt.Errorf("$options needs a $regex"))
+			}
+			continue
+
+		case "$not":
+			// {field: {$not: {expr}}}
+			// {field: {$not: /regex/}}
+			var notExpr *types.Document
+			if notExpr, err = NotExpr(value); err != nil {
+				return false, err
+			}
+
+			matches, err = filterOperators(root, path, notExpr)
+			matches = !matches
+
+		case "$size":
+			// {field: {$size: value}}
+			var size int64
+			if size, err = ParseSize(value); err != nil {
+				return false, err
+			}
+
+			matches, err = anyValue(arrays, func(v any) (bool, error) {
+				arr, ok := v.(*types.Array)
+				return ok && int64(arr.Len()) == size, nil
+			})
+
+		case "$all":
+			// {field: {$all: [value1, value2, ...]}}
+			matches, err = filterAll(root, path, value)
+
+		case "$elemMatch":
+			// {field: {$elemMatch: {field1: expr1, ...}}}
+			// {field: {$elemMatch: {expr1, ...}}}
+			elemExpr, ok := value.(*types.Document)
+			if !ok {
+				return false, NewError(ErrBadValue, fmt.Errorf("$elemMatch needs an Object"))
+			}
+
+			matches, err = anyValue(arrays, func(v any) (bool, error) {
+				arr, ok := v.(*types.Array)
+				if !ok {
+					return false, nil
+				}
+
+				for i := 0; i < arr.Len(); i++ {
+					el := must.NotFail(arr.Get(i))
+
+					var elMatches bool
+					var elErr error
+					switch el := el.(type) {
+					case *types.Document:
+						if isElemMatchOperators(elemExpr) {
+							elMatches, elErr = filterOperators(el, nil, elemExpr)
+						} else {
+							elMatches, elErr = filterDocument(el, elemExpr, false)
+						}
+					default:
+						if isElemMatchOperators(elemExpr) {
+							elMatches, elErr = filterOperators(el, nil, elemExpr)
+						}
+					}
+
+					if elErr != nil || elMatches {
+						return elMatches, elErr
+					}
+				}
+
+				return false, nil
+			})
+
+		case "$mod":
+			// {field: {$mod: [divisor, remainder]}}
+			var divisor, remainder int64
+			if divisor, remainder, err = ParseMod(value); err != nil {
+				return false, err
+			}
+
+			matches, err = anyValue(values, func(v any) (bool, error) {
+				n, ok := truncInt64(v)
+				return ok && n%divisor == remainder, nil
+			})
+
+		case "$bitsAllSet", "$bitsAnySet", "$bitsAllClear", "$bitsAnyClear":
+			// {field: {$bitsAllSet: value}}
+			// ...
+			var positions []int
+			if positions, err = ParseBitmask(op, value); err != nil {
+				return false, err
+			}
+
+			matches, err = anyValue(values, func(v any) (bool, error) { return bitsMatch(op, v, positions), nil })
+
+		default:
+			return false, NewError(ErrBadValue, fmt.Errorf("unknown operator: %s", op))
+		}
+
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// isElemMatchOperators returns true if $elemMatch argument uses query operators form like {$gte: 80, $lt: 85}
+// instead of nested document form like {product: "xyz", qty: {$gte: 5}}.
+func isElemMatchOperators(expr *types.Document) bool {
+	if !isOperatorDocument(expr) {
+		return false
+	}
+
+	switch expr.Keys()[0] {
+	case "$and", "$or", "$nor", "$expr":
+		return false
+	default:
+		return true
+	}
+}
+
+// filterAll returns true if the field matches {$all: [value1, value2, ...]};
+// values may be $elemMatch expressions.
+func filterAll(root any, path []string, value any) (bool, error) {
+	arr, ok := value.(*types.Array)
+	if !ok {
+		return false, NewError(ErrBadValue, fmt.Errorf("$all needs an array"))
+	}
+
+	if arr.Len() == 0 {
+		return false, nil
+	}
+
+	for i := 0; i < arr.Len(); i++ {
+		el := must.NotFail(arr.Get(i))
+
+		expr := types.MustNewDocument("$eq", el)
+		if d, ok := el.(*types.Document); ok && d.Len() != 0 && d.Keys()[0] == "$elemMatch" {
+			expr = d
+		}
+
+		matches, err := filterOperators(root, path, expr)
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,310 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+)
+
+// requireErrorCode checks that err is *Error with the given code.
+func requireErrorCode(t *testing.T, code ErrorCode, err error) {
+	t.Helper()
+
+	var e *Error
+	require.True(t, errors.As(err, &e), "%v", err)
+	assert.Equal(t, code, e.Code(), "%v", err)
+}
+
+func TestFilterDocument(t *testing.T) {
+	t.Parallel()
+
+	doc := types.MustNewDocument(
+		"_id", int32(1),
+		"int", int32(42),
+		"double", float64(42.5),
+		"nan", math.NaN(),
+		"string", "foo",
+		"null", types.Null,
+		"array", types.MustNewArray(int32(1), int32(2), int32(3)),
+		"docs", types.MustNewArray(
+			types.MustNewDocument("a", int32(1), "b", "x"),
+			types.MustNewDocument("a", int32(2), "b", "y"),
+		),
+		"doc", types.MustNewDocument("a", int32(1), "b", types.MustNewDocument("c", "bar")),
+		"binary", types.Binary{Subtype: types.BinaryGeneric, B: []byte{0b101}},
+	)
+
+	tooManyPositions := new(types.Array)
+	for i := 0; i <= maxBitPositions; i++ {
+		require.NoError(t, tooManyPositions.Append(int32(i)))
+	}
+
+	for name, tc := range map[string]struct {
+		filter   *types.Document
+		expected bool
+		err      ErrorCode
+	}{
+		"Empty": {
+			filter:   types.MustNewDocument(),
+			expected: true,
+		},
+		"Eq": {
+			filter:   types.MustNewDocument("int", float64(42)),
+			expected: true,
+		},
+		"EqMissing": {
+			filter: types.MustNewDocument("missing", int32(42)),
+		},
+		"EqNullMissing": {
+			filter:   types.MustNewDocument("missing", types.Null),
+			expected: true,
+		},
+		"EqArrayElement": {
+			filter:   types.MustNewDocument("array", int32(2)),
+			expected: true,
+		},
+		"EqWholeArray": {
+			filter:   types.MustNewDocument("array", types.MustNewArray(int32(1), int32(2), int32(3))),
+			expected: true,
+		},
+		"EqDocument": {
+			filter: types.MustNewDocument("doc", types.MustNewDocument("a", int32(1))),
+		},
+		"DotNotation": {
+			filter:   types.MustNewDocument("doc.b.c", "bar"),
+			expected: true,
+		},
+		"DotNotationArray": {
+			filter:   types.MustNewDocument("docs.b", "y"),
+			expected: true,
+		},
+		"DotNotationIndex": {
+			filter:   types.MustNewDocument("array.1", int32(2)),
+			expected: true,
+		},
+		"Ne": {
+			filter:   types.MustNewDocument("array", types.MustNewDocument("$ne", int32(4))),
+			expected: true,
+		},
+		"NeArrayElement": {
+			filter: types.MustNewDocument("array", types.MustNewDocument("$ne", int32(3))),
+		},
+		"Range": {
+			filter:   types.MustNewDocument("double", types.MustNewDocument("$gt", int32(42), "$lte", int64(43))),
+			expected: true,
+		},
+		"RangeOtherType": {
+			filter: types.MustNewDocument("string", types.MustNewDocument("$gt", int32(0))),
+		},
+		"NaN": {
+			filter:   types.MustNewDocument("nan", types.MustNewDocument("$gte", math.NaN())),
+			expected: true,
+		},
+		"NaNLess": {
+			filter: types.MustNewDocument("nan", types.MustNewDocument("$lt", math.Inf(1))),
+		},
+		"In": {
+			filter:   types.MustNewDocument("string", types.MustNewDocument("$in", types.MustNewArray("bar", "foo"))),
+			expected: true,
+		},
+		"InRegex": {
+			filter: types.MustNewDocument("string", types.MustNewDocument("$in", types.MustNewArray(
+				types.Regex{Pattern: "^F", Options: "i"},
+			))),
+			expected: true,
+		},
+		"Nin": {
+			filter: types.MustNewDocument("array", types.MustNewDocument("$nin", types.MustNewArray(int32(3)))),
+		},
+		"InNotArray": {
+			filter: types.MustNewDocument("string", types.MustNewDocument("$in", "foo")),
+			err:    ErrBadValue,
+		},
+		"Exists": {
+			filter:   types.MustNewDocument("null", types.MustNewDocument("$exists", true)),
+			expected: true,
+		},
+		"NotExists": {
+			filter:   types.MustNewDocument("missing", types.MustNewDocument("$exists", false)),
+			expected: true,
+		},
+		"Type": {
+			filter:   types.MustNewDocument("array", types.MustNewDocument("$type", "int")),
+			expected: true,
+		},
+		"TypeNumber": {
+			filter:   types.MustNewDocument("double", types.MustNewDocument("$type", types.MustNewArray("string", "number"))),
+			expected: true,
+		},
+		"Regex": {
+			filter:   types.MustNewDocument("doc.b.c", types.Regex{Pattern: "^b"}),
+			expected: true,
+		},
+		"RegexOptions": {
+			filter:   types.MustNewDocument("string", types.MustNewDocument("$regex", "^FOO$", "$options", "i")),
+			expected: true,
+		},
+		"RegexOptionsTwice": {
+			filter: types.MustNewDocument("string", types.MustNewDocument(
+				"$regex", types.Regex{Pattern: "foo", Options: "i"}, "$options", "m",
+			)),
+			err: ErrRegexOptions,
+		},
+		"OptionsWithoutRegex": {
+			filter: types.MustNewDocument("string", types.MustNewDocument("$options", "i")),
+			err:    ErrBadValue,
+		},
+		"Not": {
+			filter:   types.MustNewDocument("int", types.MustNewDocument("$not", types.MustNewDocument("$gt", int32(42)))),
+			expected: true,
+		},
+		"NotMissing": {
+			filter:   types.MustNewDocument("missing", types.MustNewDocument("$not", types.MustNewDocument("$eq", int32(1)))),
+			expected: true,
+		},
+		"Size": {
+			filter:   types.MustNewDocument("array", types.MustNewDocument("$size", int32(3))),
+			expected: true,
+		},
+		"SizeNotArray": {
+			filter: types.MustNewDocument("string", types.MustNewDocument("$size", int32(0))),
+		},
+		"All": {
+			filter:   types.MustNewDocument("array", types.MustNewDocument("$all", types.MustNewArray(int32(3), int32(1)))),
+			expected: true,
+		},
+		"AllMissingElement": {
+			filter: types.MustNewDocument("array", types.MustNewDocument("$all", types.MustNewArray(int32(3), int32(4)))),
+		},
+		"AllEmpty": {
+			filter: types.MustNewDocument("array", types.MustNewDocument("$all", types.MustNewArray())),
+		},
+		"ElemMatch": {
+			filter: types.MustNewDocument("docs", types.MustNewDocument("$elemMatch", types.MustNewDocument(
+				"a", int32(2), "b", "y",
+			))),
+			expected: true,
+		},
+		"ElemMatchDifferentElements": {
+			filter: types.MustNewDocument("docs", types.MustNewDocument("$elemMatch", types.MustNewDocument(
+				"a", int32(1), "b", "y",
+			))),
+		},
+		"ElemMatchOperators": {
+			filter: types.MustNewDocument("array", types.MustNewDocument("$elemMatch", types.MustNewDocument(
+				"$gt", int32(1), "$lt", int32(3),
+			))),
+			expected: true,
+		},
+		"ElemMatchExpr": {
+			filter: types.MustNewDocument("docs", types.MustNewDocument("$elemMatch", types.MustNewDocument(
+				"$expr", true,
+			))),
+			err: ErrBadValue,
+		},
+		"Mod": {
+			filter:   types.MustNewDocument("double", types.MustNewDocument("$mod", types.MustNewArray(int32(4), int32(2)))),
+			expected: true,
+		},
+		"BitsAllSet": {
+			filter:   types.MustNewDocument("int", types.MustNewDocument("$bitsAllSet", types.MustNewArray(int32(1), int32(3)))),
+			expected: true,
+		},
+		"BitsAnySetBinary": {
+			filter:   types.MustNewDocument("binary", types.MustNewDocument("$bitsAnySet", types.MustNewArray(int32(1), int32(2)))),
+			expected: true,
+		},
+		"BitsAllClearBinary": {
+			filter: types.MustNewDocument("binary", types.MustNewDocument("$bitsAllClear", int32(0b101))),
+		},
+		"BitsBinDataTooLong": {
+			filter: types.MustNewDocument("binary", types.MustNewDocument(
+				"$bitsAnySet", types.Binary{B: make([]byte, maxBitPositions/8+1)},
+			)),
+			err: ErrNotImplemented,
+		},
+		"BitsTooManyPositions": {
+			filter: types.MustNewDocument("binary", types.MustNewDocument("$bitsAnySet", tooManyPositions)),
+			err:    ErrNotImplemented,
+		},
+		"UnknownOperator": {
+			filter: types.MustNewDocument("int", types.MustNewDocument("$foo", int32(1))),
+			err:    ErrBadValue,
+		},
+		"And": {
+			filter: types.MustNewDocument("$and", types.MustNewArray(
+				types.MustNewDocument("int", int32(42)),
+				types.MustNewDocument("string", "foo"),
+			)),
+			expected: true,
+		},
+		"Or": {
+			filter: types.MustNewDocument("$or", types.MustNewArray(
+				types.MustNewDocument("int", int32(0)),
+				types.MustNewDocument("string", "foo"),
+			)),
+			expected: true,
+		},
+		"Nor": {
+			filter: types.MustNewDocument("$nor", types.MustNewArray(
+				types.MustNewDocument("int", int32(0)),
+				types.MustNewDocument("string", "foo"),
+			)),
+		},
+		"OrEmpty": {
+			filter: types.MustNewDocument("$or", types.MustNewArray()),
+			err:    ErrBadValue,
+		},
+		"OrNotDocument": {
+			filter: types.MustNewDocument("$or", types.MustNewArray(int32(1))),
+			err:    ErrBadValue,
+		},
+		"Expr": {
+			filter: types.MustNewDocument("$expr", types.MustNewDocument(
+				"$gt", types.MustNewArray("$double", "$int"),
+			)),
+			expected: true,
+		},
+		"Comment": {
+			filter:   types.MustNewDocument("$comment", "foo", "int", int32(42)),
+			expected: true,
+		},
+		"UnknownTopLevelOperator": {
+			filter: types.MustNewDocument("$foo", int32(1)),
+			err:    ErrBadValue,
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := FilterDocument(doc, tc.filter)
+			if tc.err != 0 {
+				requireErrorCode(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+)
+
+// PrepareDocumentForInsert validates the document's _id field and returns a document ready for insertion.
+//
+// If _id is missing, a new ObjectID is generated.
+// In both cases _id becomes the first field of the returned document.
+func PrepareDocumentForInsert(doc *types.Document) (*types.Document, error) {
+	m := doc.Map()
+
+	id, ok := m["_id"]
+	if !ok {
+		id = types.NewObjectID()
+	}
+
+	switch id.(type) {
+	case *types.Array:
+		return nil, NewError(ErrInvalidIDField, fmt.Errorf("The '_id' value cannot be of type array"))
+	case types.Regex:
+		return nil, NewError(ErrBadValue, fmt.Errorf("can't use a regex for _id"))
+	}
+
+	res := types.MustNewDocument("_id", id)
+	for _, k := range doc.Keys() {
+		if k == "_id" {
+			continue
+		}
+
+		if err := res.Set(k, m[k]); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,233 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/FerretDB/FerretDB/internal/pg"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+)
+
+// ParseMod parses $mod operator argument [divisor, remainder].
+//
+// Both values are truncated to integers.
+func ParseMod(value any) (divisor, remainder int64, err error) {
+	arr, ok := value.(*types.Array)
+	if !ok {
+		err = NewError(ErrBadValue, fmt.Errorf("malformed mod, needs to be an array"))
+		return
+	}
+
+	switch {
+	case arr.Len() < 2:
+		err = NewError(ErrBadValue, fmt.Errorf("malformed mod, not enough elements"))
+		return
+	case arr.Len() > 2:
+		err = NewError(ErrBadValue, fmt.Errorf("malformed mod, too many elements"))
+		return
+	}
+
+	var ok1, ok2 bool
+	divisor, ok1 = truncInt64(must.NotFail(arr.Get(0)))
+	remainder, ok2 = truncInt64(must.NotFail(arr.Get(1)))
+
+	switch {
+	case !ok1:
+		err = NewError(ErrBadValue, fmt.Errorf("malformed mod, divisor not a number"))
+	case !ok2:
+		err = NewError(ErrBadValue, fmt.Errorf("malformed mod, remainder not a number"))
+	case divisor == 0:
+		err = NewError(ErrBadValue, fmt.Errorf("divisor cannot be 0"))
+	}
+
+	return
+}
+
+// truncInt64 returns the given number truncated to int64.
+// It returns false for non-numbers, NaNs and values out of int64 range.
+func truncInt64(value any) (int64, bool) {
+	switch value := value.(type) {
+	case int32:
+		return int64(value), true
+	case int64:
+		return value, true
+	case float64:
+		if math.IsNaN(value) || value < math.MinInt64 || value >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(value), true
+	default:
+		return 0, false
+	}
+}
+
+// ParseSize parses $size operator argument: a non-negative integer.
+func ParseSize(value any) (int64, error) {
+	var size float64
+	switch value := value.(type) {
+	case int32:
+		size = float64(value)
+	case int64:
+		size = float64(value)
+	case float64:
+		size = value
+	default:
+		return 0, NewError(ErrBadValue, fmt.Errorf("Failed to parse $size. Expected a number in: $size: %v", value))
+	}
+
+	if size != math.Trunc(size) || math.IsInf(size, 0) {
+		return 0, NewError(ErrBadValue, fmt.Errorf("Failed to parse $size. Expected an integer in: $size: %v", value))
+	}
+
+	if size < 0 {
+		err := fmt.Errorf("Failed to parse $size. Expected a non-negative number in: $size: %v", value)
+		return 0, NewError(ErrBadValue, err)
+	}
+
+	return int64(size), nil
+}
+
+// maxBitPositions is the maximal number of bit positions in the argument of bitwise query operators.
+//
+// Storages check every position separately for binary data, so the argument size is limited.
+const maxBitPositions = 512
+
+// ParseBitmask parses the argument of bitwise query operators like $bitsAllSet:
+// a non-negative integer, an array of at most maxBitPositions bit positions,
+// or BinData of at most maxBitPositions bits.
+//
+// It returns a sorted list of unique bit positions.
+func ParseBitmask(op string, value any) ([]int, error) {
+	set := make(map[int]struct{})
+
+	switch value := value.(type) {
+	case int32, int64, float64:
+		if f, ok := value.(float64); ok && f != math.Trunc(f) {
+			return nil, NewError(ErrBadValue, fmt.Errorf("Expected an integer: %s: %v", op, value))
+		}
+
+		mask, ok := truncInt64(value)
+		if !ok || mask < 0 {
+			return nil, NewError(ErrBadValue, fmt.Errorf("Expected a positive number in: %s: %v", op, value))
+		}
+
+		for i := 0; i < 63; i++ {
+			if mask&(1<<i) != 0 {
+				set[i] = struct{}{}
+			}
+		}
+
+	case *types.Array:
+		if value.Len() > maxBitPositions {
+			err := fmt.Errorf("%s: more than %d bit positions are not supported", op, maxBitPositions)
+			return nil, NewError(ErrNotImplemented, err)
+		}
+
+		for i := 0; i < value.Len(); i++ {
+			v := must.NotFail(value.Get(i))
+
+			if f, ok := v.(float64); ok && f != math.Trunc(f) {
+				return nil, NewError(ErrBadValue, fmt.Errorf("bit positions must be an integer but got: %d: %v", i, v))
+			}
+
+			pos, ok := truncInt64(v)
+			switch {
+			case !ok:
+				return nil, NewError(ErrBadValue, fmt.Errorf("bit positions must be an integer but got: %d: %v", i, v))
+			case pos < 0:
+				return nil, NewError(ErrBadValue, fmt.Errorf("bit positions cannot be negative but got: %d: %v", i, v))
+			case pos > math.MaxInt32:
+				err := fmt.Errorf("bit positions cannot be represented as a 32-bit signed integer: %d: %v", i, v)
+				return nil, NewError(ErrBadValue, err)
+			}
+
+			set[int(pos)] = struct{}{}
+		}
+
+	case types.Binary:
+		if len(value.B) > maxBitPositions/8 {
+			err := fmt.Errorf("%s: BinData longer than %d bytes is not supported", op, maxBitPositions/8)
+			return nil, NewError(ErrNotImplemented, err)
+		}
+
+		for i, b := range value.B {
+			for j := 0; j < 8; j++ {
+				if b&(1<<j) != 0 {
+					set[i*8+j] = struct{}{}
+				}
+			}
+		}
+
+	default:
+		err := fmt.Errorf("%s takes an Array, a number, or a BinData but received: %v", op, value)
+		return nil, NewError(ErrBadValue, err)
+	}
+
+	res := make([]int, 0, len(set))
+	for pos := range set {
+		res = append(res, pos)
+	}
+	sort.Ints(res)
+
+	return res, nil
+}
+
+// Int64Expr returns SQL expression that converts the given numeric SQL expression to bigint
+// if it is an integer that can be represented as int64, and to NULL otherwise.
+func Int64Expr(n string) string {
+	return "CASE WHEN " + n + " = trunc(" + n + ") AND " +
+		n + " BETWEEN -9223372036854775808 AND 9223372036854775807 THEN (" + n + ")::bigint END"
+}
+
+// bitsMask returns int64 mask for the given bit positions.
+//
+// Bit positions greater than 63 are the same as the sign bit because of sign extension.
+func bitsMask(positions []int) int64 {
+	var mask int64
+	for _, pos := range positions {
+		if pos > 63 {
+			pos = 63
+		}
+		mask |= 1 << pos
+	}
+
+	return mask
+}
+
+// BitsExpr returns SQL condition for bitwise query operator op applied to the given bigint SQL expression.
+func BitsExpr(op, v string, positions []int, p *pg.Placeholder) (sql string, args []any) {
+	mask := bitsMask(positions)
+
+	m := p.Next()
+	switch op {
+	case "$bitsAllSet":
+		sql = "(" + v + " & " + m + "::bigint) = " + m + "::bigint"
+	case "$bitsAnySet":
+		sql = "(" + v + " & " + m + "::bigint) <> 0"
+	case "$bitsAllClear":
+		sql = "(" + v + " & " + m + "::bigint) = 0"
+	case "$bitsAnyClear":
+		sql = "(" + v + " & " + m + "::bigint) <> " + m + "::bigint"
+	default:
+		panic(fmt.Sprintf("unexpected operator %q", op))
+	}
+
+	args = []any{mask}
+	return
+}
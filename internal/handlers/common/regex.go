@@ -0,0 +1,556 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+)
+
+// maxRepeat is the maximal repetition count supported by PostgreSQL regular expressions.
+const maxRepeat = 255
+
+// regexFlags represents MongoDB regular expression options.
+type regexFlags struct {
+	caseless  bool // i
+	multiline bool // m
+	dotAll    bool // s
+	extended  bool // x
+}
+
+// set sets the flag for the given option.
+func (f *regexFlags) set(o rune) error {
+	switch o {
+	case 'i':
+		f.caseless = true
+	case 'm':
+		f.multiline = true
+	case 's':
+		f.dotAll = true
+	case 'x':
+		f.extended = true
+	case 'u':
+		// PostgreSQL regular expressions always work with Unicode characters
+	default:
+		return NewError(ErrBadValue, fmt.Errorf("invalid flag in regex options: %c", o))
+	}
+
+	return nil
+}
+
+// embedded returns PostgreSQL embedded options for flags.
+//
+// Extended syntax is handled by the caller, so it is not included.
+func (f *regexFlags) embedded() string {
+	var res string
+	if f.caseless {
+		res += "i"
+	}
+
+	// PostgreSQL's dot matches newlines and anchors match only at the string ends by default;
+	// see "Regular Expression Matching Rules" in PostgreSQL documentation.
+	switch {
+	case f.multiline && f.dotAll:
+		res += "w"
+	case f.multiline:
+		res += "n"
+	case f.dotAll:
+		// nothing, that's the default
+	default:
+		res += "p"
+	}
+
+	if res == "" {
+		return ""
+	}
+
+	return "(?" + res + ")"
+}
+
+// regexUnsupported returns BadValue protocol error for unsupported regular expression construct.
+func regexUnsupported(construct string) error {
+	return NewError(ErrBadValue, fmt.Errorf("Regular expression construct %s is not supported", construct))
+}
+
+// RegexPattern converts MongoDB (PCRE) regular expression with options
+// to the PostgreSQL advanced regular expression with embedded options.
+//
+// Leading inline options like (?i) are merged with regex options.
+// Constructs that PostgreSQL can't express return BadValue protocol error.
+func RegexPattern(re types.Regex) (string, error) {
+	var flags regexFlags
+	for _, o := range re.Options {
+		if err := flags.set(o); err != nil {
+			return "", err
+		}
+	}
+
+	pattern := []rune(re.Pattern)
+
+	// leading inline options: (?imsx)
+	for len(pattern) > 2 && pattern[0] == '(' && pattern[1] == '?' {
+		end := 2
+		for end < len(pattern) && strings.ContainsRune("imsxu", pattern[end]) {
+			end++
+		}
+		if end == 2 || end == len(pattern) || pattern[end] != ')' {
+			break
+		}
+
+		for _, o := range pattern[2:end] {
+			if err := flags.set(o); err != nil {
+				return "", err
+			}
+		}
+		pattern = pattern[end+1:]
+	}
+
+	t := regexTranslator{
+		pattern:  pattern,
+		extended: flags.extended,
+	}
+	res, err := t.translate()
+	if err != nil {
+		return "", err
+	}
+
+	return flags.embedded() + res, nil
+}
+
+// CompileRegex compiles MongoDB regular expression with options to Go regular expression.
+//
+// Some PCRE constructs like back references and lookaround assertions are not supported by Go;
+// they return BadValue protocol error.
+func CompileRegex(re types.Regex) (*regexp.Regexp, error) {
+	var flags regexFlags
+	for _, o := range re.Options {
+		if err := flags.set(o); err != nil {
+			return nil, err
+		}
+	}
+
+	pattern := re.Pattern
+	if flags.extended {
+		pattern = stripExtended([]rune(pattern))
+	}
+
+	var goFlags string
+	if flags.caseless {
+		goFlags += "i"
+	}
+	if flags.multiline {
+		goFlags += "m"
+	}
+	if flags.dotAll {
+		goFlags += "s"
+	}
+	if goFlags != "" {
+		pattern = "(?" + goFlags + ")" + pattern
+	}
+
+	res, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, NewError(ErrBadValue, fmt.Errorf("Regular expression is invalid: %w", err))
+	}
+
+	return res, nil
+}
+
+// stripExtended removes whitespace and # comments outside of character classes
+// for the extended syntax (x option).
+func stripExtended(pattern []rune) string {
+	var res strings.Builder
+	var inClass bool
+
+	for i := 0; i < len(pattern); i++ {
+		r := pattern[i]
+
+		switch {
+		case r == '\\' && i+1 < len(pattern):
+			res.WriteRune(r)
+			i++
+			res.WriteRune(pattern[i])
+
+		case inClass:
+			if r == ']' {
+				inClass = false
+			}
+			res.WriteRune(r)
+
+		case r == '[':
+			inClass = true
+			res.WriteRune(r)
+
+			// leading ] is a literal
+			if i+1 < len(pattern) && pattern[i+1] == '^' {
+				i++
+				res.WriteRune(pattern[i])
+			}
+			if i+1 < len(pattern) && pattern[i+1] == ']' {
+				i++
+				res.WriteRune(pattern[i])
+			}
+
+		case r == '#':
+			for i+1 < len(pattern) && pattern[i+1] != '\n' {
+				i++
+			}
+
+		case unicode.IsSpace(r):
+			// ignored
+
+		default:
+			res.WriteRune(r)
+		}
+	}
+
+	return res.String()
+}
+
+// regexTranslator translates PCRE pattern to PostgreSQL advanced regular expression.
+type regexTranslator struct {
+	pattern  []rune
+	pos      int
+	extended bool
+	res      strings.Builder
+}
+
+// peek returns the rune at the given offset from the current position, or 0.
+func (t *regexTranslator) peek(offset int) rune {
+	if i := t.pos + offset; i < len(t.pattern) {
+		return t.pattern[i]
+	}
+	return 0
+}
+
+// hasPrefix returns true if the rest of the pattern starts with s.
+func (t *regexTranslator) hasPrefix(s string) bool {
+	return strings.HasPrefix(string(t.pattern[t.pos:]), s)
+}
+
+// translate translates the whole pattern.
+func (t *regexTranslator) translate() (string, error) {
+	for t.pos < len(t.pattern) {
+		r := t.pattern[t.pos]
+
+		var err error
+		switch {
+		case r == '\\':
+			err = t.escape(false)
+
+		case r == '[':
+			err = t.class()
+
+		case r == '(':
+			err = t.group()
+
+		case r == '{':
+			err = t.repeat()
+
+		case r == '*' || r == '+' || r == '?':
+			t.res.WriteRune(r)
+			t.pos++
+			if t.peek(0) == '+' {
+				err = regexUnsupported("possessive quantifier")
+			}
+
+		case t.extended && r == '#':
+			for t.pos < len(t.pattern) && t.pattern[t.pos] != '\n' {
+				t.pos++
+			}
+
+		case t.extended && unicode.IsSpace(r):
+			t.pos++
+
+		default:
+			t.res.WriteRune(r)
+			t.pos++
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return t.res.String(), nil
+}
+
+// escape translates the escape sequence at the current position.
+func (t *regexTranslator) escape(inClass bool) error {
+	e := t.peek(1)
+	t.pos += 2
+
+	switch {
+	case e == 0:
+		return NewError(ErrBadValue, fmt.Errorf("Regular expression is invalid: \\ at end of pattern"))
+
+	case !unicode.IsLetter(e) && !unicode.IsDigit(e):
+		// escaped literal
+		t.res.WriteRune('\\')
+		t.res.WriteRune(e)
+
+	case strings.ContainsRune("dswnrtfae", e):
+		t.res.WriteRune('\\')
+		t.res.WriteRune(e)
+
+	case strings.ContainsRune("DSW", e):
+		if inClass {
+			return regexUnsupported(`\` + string(e) + " inside character class")
+		}
+		t.res.WriteRune('\\')
+		t.res.WriteRune(e)
+
+	case e == 'b' && inClass:
+		// backspace in both dialects
+		t.res.WriteString(`\b`)
+
+	case e == 'b':
+		t.res.WriteString(`\y`)
+
+	case e == 'B' && !inClass:
+		t.res.WriteString(`\Y`)
+
+	case e == 'A' && !inClass:
+		t.res.WriteString(`\A`)
+
+	case e == 'z' && !inClass:
+		t.res.WriteString(`\Z`)
+
+	case e == 'Z' && !inClass:
+		// end of subject or before the final newline
+		t.res.WriteString(`(?=\n?\Z)`)
+
+	case e >= '1' && e <= '9' && !inClass:
+		// back reference
+		t.res.WriteRune('\\')
+		t.res.WriteRune(e)
+
+	case e == 'x':
+		return t.hex()
+
+	case e == 'c':
+		c := t.peek(0)
+		if c == 0 {
+			return NewError(ErrBadValue, fmt.Errorf("Regular expression is invalid: \\c at end of pattern"))
+		}
+		t.res.WriteString(`\c`)
+		t.res.WriteRune(c)
+		t.pos++
+
+	case e == 'Q':
+		// quoted literal until \E
+		for t.pos < len(t.pattern) && !t.hasPrefix(`\E`) {
+			r := t.pattern[t.pos]
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				t.res.WriteRune('\\')
+			}
+			t.res.WriteRune(r)
+			t.pos++
+		}
+		if t.hasPrefix(`\E`) {
+			t.pos += 2
+		}
+
+	case e == 'E':
+		// \E without \Q is ignored
+
+	default:
+		return regexUnsupported(`\` + string(e))
+	}
+
+	return nil
+}
+
+// hex translates \xhh and \x{hhh..} escapes; \x is already consumed.
+//
+// PostgreSQL's \x consumes any number of hex digits, so the fixed-length \U form is used instead.
+func (t *regexTranslator) hex() error {
+	var digits string
+	if t.peek(0) == '{' {
+		end := t.pos + 1
+		for end < len(t.pattern) && t.pattern[end] != '}' {
+			end++
+		}
+		if end == len(t.pattern) {
+			return NewError(ErrBadValue, fmt.Errorf("Regular expression is invalid: missing terminating } in \\x{}"))
+		}
+		digits = string(t.pattern[t.pos+1 : end])
+		t.pos = end + 1
+	} else {
+		for len(digits) < 2 && strings.ContainsRune("0123456789abcdefABCDEF", t.peek(0)) {
+			digits += string(t.peek(0))
+			t.pos++
+		}
+	}
+
+	if digits == "" {
+		digits = "0"
+	}
+
+	code, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || code == 0 || code > unicode.MaxRune {
+		return NewError(ErrBadValue, fmt.Errorf("Regular expression is invalid: character code point value in \\x{} is invalid"))
+	}
+
+	fmt.Fprintf(&t.res, `\U%08X`, code)
+	return nil
+}
+
+// class translates the character class at the current position.
+func (t *regexTranslator) class() error {
+	t.res.WriteRune('[')
+	t.pos++
+
+	if t.peek(0) == '^' {
+		t.res.WriteRune('^')
+		t.pos++
+	}
+
+	// leading ] is a literal
+	if t.peek(0) == ']' {
+		t.res.WriteRune(']')
+		t.pos++
+	}
+
+	for t.pos < len(t.pattern) {
+		r := t.pattern[t.pos]
+
+		switch {
+		case r == ']':
+			t.res.WriteRune(']')
+			t.pos++
+			return nil
+
+		case r == '\\':
+			if err := t.escape(true); err != nil {
+				return err
+			}
+
+		case t.hasPrefix("[:"):
+			// POSIX class like [:alpha:]
+			end := t.pos + 3
+			for end < len(t.pattern) && !(t.pattern[end-1] == ':' && t.pattern[end] == ']') {
+				end++
+			}
+			if end == len(t.pattern) {
+				return NewError(ErrBadValue, fmt.Errorf("Regular expression is invalid: missing terminating ] for character class"))
+			}
+			t.res.WriteString(string(t.pattern[t.pos : end+1]))
+			t.pos = end + 1
+
+		case r == '[':
+			// literal in PCRE, but may start collating element in PostgreSQL
+			t.res.WriteString(`\[`)
+			t.pos++
+
+		default:
+			t.res.WriteRune(r)
+			t.pos++
+		}
+	}
+
+	return NewError(ErrBadValue, fmt.Errorf("Regular expression is invalid: missing terminating ] for character class"))
+}
+
+// group translates the group start at the current position.
+func (t *regexTranslator) group() error {
+	switch {
+	case t.hasPrefix("(?#"):
+		// comment
+		for t.pos < len(t.pattern) && t.pattern[t.pos] != ')' {
+			t.pos++
+		}
+		if t.pos == len(t.pattern) {
+			return NewError(ErrBadValue, fmt.Errorf("Regular expression is invalid: missing ) after (?# comment"))
+		}
+		t.pos++
+		return nil
+
+	case t.hasPrefix("(*"):
+		return regexUnsupported("(*")
+
+	case !t.hasPrefix("(?"):
+		t.res.WriteRune('(')
+		t.pos++
+		return nil
+	}
+
+	// non-capturing groups and lookaround assertions
+	for _, prefix := range []string{"(?:", "(?=", "(?!", "(?<=", "(?<!"} {
+		if t.hasPrefix(prefix) {
+			t.res.WriteString(prefix)
+			t.pos += len(prefix)
+			return nil
+		}
+	}
+
+	construct := "(?"
+	if next := t.peek(2); next != 0 {
+		construct += string(next)
+	}
+
+	return regexUnsupported(construct)
+}
+
+// repeat translates {n}, {n,} and {n,m} quantifiers at the current position.
+//
+// Like PCRE, it treats braces that don't form a quantifier as literals.
+func (t *regexTranslator) repeat() error {
+	end := t.pos + 1
+	for end < len(t.pattern) && t.pattern[end] != '}' {
+		end++
+	}
+
+	var bounds []string
+	if end < len(t.pattern) {
+		bounds = strings.Split(string(t.pattern[t.pos+1:end]), ",")
+	}
+
+	valid := len(bounds) == 1 || len(bounds) == 2
+	for i, b := range bounds {
+		if b == "" && i == 1 {
+			continue
+		}
+		if b == "" || strings.Trim(b, "0123456789") != "" {
+			valid = false
+		}
+	}
+
+	if !valid {
+		t.res.WriteString(`\{`)
+		t.pos++
+		return nil
+	}
+
+	for _, b := range bounds {
+		if n, err := strconv.Atoi(b); err == nil && n > maxRepeat {
+			return regexUnsupported(fmt.Sprintf("repetition count greater than %d", maxRepeat))
+		}
+	}
+
+	t.res.WriteString(string(t.pattern[t.pos : end+1]))
+	t.pos = end + 1
+
+	if t.peek(0) == '+' {
+		return regexUnsupported("possessive quantifier")
+	}
+
+	return nil
+}
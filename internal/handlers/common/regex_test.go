@@ -0,0 +1,173 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+)
+
+func TestRegexPattern(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		re       types.Regex
+		expected string
+		err      ErrorCode
+	}{
+		"Simple": {
+			re:       types.Regex{Pattern: "^fo+$"},
+			expected: "(?p)^fo+$",
+		},
+		"Caseless": {
+			re:       types.Regex{Pattern: "foo", Options: "i"},
+			expected: "(?ip)foo",
+		},
+		"Multiline": {
+			re:       types.Regex{Pattern: "^foo$", Options: "m"},
+			expected: "(?n)^foo$",
+		},
+		"DotAll": {
+			re:       types.Regex{Pattern: "a.b", Options: "s"},
+			expected: "a.b",
+		},
+		"MultilineDotAll": {
+			re:       types.Regex{Pattern: "a.b", Options: "ms"},
+			expected: "(?w)a.b",
+		},
+		"Unicode": {
+			re:       types.Regex{Pattern: "foo", Options: "u"},
+			expected: "(?p)foo",
+		},
+		"InvalidOption": {
+			re:  types.Regex{Pattern: "foo", Options: "g"},
+			err: ErrBadValue,
+		},
+		"InlineOptions": {
+			re:       types.Regex{Pattern: "(?i)(?s)foo"},
+			expected: "(?i)foo",
+		},
+		"InlineInvalidOption": {
+			re:  types.Regex{Pattern: "(?g)foo"},
+			err: ErrBadValue,
+		},
+		"Extended": {
+			re:       types.Regex{Pattern: "f o # comment\no", Options: "x"},
+			expected: "(?p)foo",
+		},
+		"ExtendedClass": {
+			re:       types.Regex{Pattern: "[ a]", Options: "x"},
+			expected: "(?p)[ a]",
+		},
+		"Escapes": {
+			re:       types.Regex{Pattern: `\d\s\W\.`},
+			expected: `(?p)\d\s\W\.`,
+		},
+		"WordBoundary": {
+			re:       types.Regex{Pattern: `\bfoo\B`},
+			expected: `(?p)\yfoo\Y`,
+		},
+		"Anchors": {
+			re:       types.Regex{Pattern: `\Afoo\z|bar\Z`},
+			expected: `(?p)\Afoo\Z|bar(?=\n?\Z)`,
+		},
+		"Backspace": {
+			re:       types.Regex{Pattern: `[\b]`},
+			expected: `(?p)[\b]`,
+		},
+		"ClassShorthandInClass": {
+			re:  types.Regex{Pattern: `[\D]`},
+			err: ErrBadValue,
+		},
+		"Hex": {
+			re:       types.Regex{Pattern: `\x41\x{263A}`},
+			expected: `(?p)\U00000041\U0000263A`,
+		},
+		"HexInvalid": {
+			re:  types.Regex{Pattern: `\x{110000}`},
+			err: ErrBadValue,
+		},
+		"Quoted": {
+			re:       types.Regex{Pattern: `\Qa.b\Ec`},
+			expected: `(?p)a\.bc`,
+		},
+		"TrailingBackslash": {
+			re:  types.Regex{Pattern: `foo\`},
+			err: ErrBadValue,
+		},
+		"UnsupportedEscape": {
+			re:  types.Regex{Pattern: `\K`},
+			err: ErrBadValue,
+		},
+		"Class": {
+			re:       types.Regex{Pattern: `[]a[:alpha:][]`},
+			expected: `(?p)[]a[:alpha:]\[]`,
+		},
+		"UnterminatedClass": {
+			re:  types.Regex{Pattern: `[a`},
+			err: ErrBadValue,
+		},
+		"Groups": {
+			re:       types.Regex{Pattern: `(a)(?:b)(?=c)(?<!d)(?#comment)`},
+			expected: `(?p)(a)(?:b)(?=c)(?<!d)`,
+		},
+		"NamedGroup": {
+			re:  types.Regex{Pattern: `(?<name>a)`},
+			err: ErrBadValue,
+		},
+		"Verb": {
+			re:  types.Regex{Pattern: `(*UTF8)a`},
+			err: ErrBadValue,
+		},
+		"Repeat": {
+			re:       types.Regex{Pattern: `a{2}b{1,}c{1,3}`},
+			expected: `(?p)a{2}b{1,}c{1,3}`,
+		},
+		"RepeatLiteral": {
+			re:       types.Regex{Pattern: `a{b}{,2}`},
+			expected: `(?p)a\{b}\{,2}`,
+		},
+		"RepeatTooLarge": {
+			re:  types.Regex{Pattern: `a{256}`},
+			err: ErrBadValue,
+		},
+		"Possessive": {
+			re:  types.Regex{Pattern: `a++`},
+			err: ErrBadValue,
+		},
+		"PossessiveRepeat": {
+			re:  types.Regex{Pattern: `a{2}+`},
+			err: ErrBadValue,
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := RegexPattern(tc.re)
+			if tc.err != 0 {
+				requireErrorCode(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
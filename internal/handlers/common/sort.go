@@ -0,0 +1,141 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+)
+
+// sortKey represents a single field of the sort specification.
+type sortKey struct {
+	path []string
+	asc  bool
+}
+
+// parseSort parses sort specification {field1: 1, field2: -1, ...}.
+func parseSort(spec *types.Document) ([]sortKey, error) {
+	m := spec.Map()
+	keys := make([]sortKey, 0, spec.Len())
+	for _, field := range spec.Keys() {
+		var order float64
+		switch v := m[field].(type) {
+		case int32:
+			order = float64(v)
+		case int64:
+			order = float64(v)
+		case float64:
+			order = v
+		default:
+			return nil, NewError(ErrBadValue, fmt.Errorf("illegal key in $sort specification: %s: %v", field, v))
+		}
+
+		switch order {
+		case 1:
+		case -1:
+		default:
+			return nil, NewError(ErrBadValue, fmt.Errorf("$sort key ordering must be 1 (for ascending) or -1 (for descending)"))
+		}
+
+		keys = append(keys, sortKey{
+			path: strings.Split(field, "."),
+			asc:  order > 0,
+		})
+	}
+
+	return keys, nil
+}
+
+// sortValue returns the value of the document's field that is used for sorting.
+//
+// Missing fields are sorted as null.
+// Arrays are sorted by their smallest element in ascending order and by their largest element in descending order;
+// empty arrays are less than null.
+func sortValue(doc *types.Document, key sortKey) any {
+	v, err := doc.GetByPath(key.path...)
+	if err != nil {
+		return types.Null
+	}
+
+	arr, ok := v.(*types.Array)
+	if !ok {
+		return v
+	}
+
+	if arr.Len() == 0 {
+		return types.Undefined
+	}
+
+	res, _ := arr.Get(0)
+	for i := 1; i < arr.Len(); i++ {
+		el, _ := arr.Get(i)
+		c := types.Compare(el, res)
+		if (key.asc && c < 0) || (!key.asc && c > 0) {
+			res = el
+		}
+	}
+
+	return res
+}
+
+// SortDocuments sorts documents in place according to the sort specification {field1: 1, field2: -1, ...}
+// using MongoDB comparison order. Documents with equal sort values keep their original order.
+func SortDocuments(docs []*types.Document, spec *types.Document) error {
+	keys, err := parseSort(spec)
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 || len(docs) < 2 {
+		return nil
+	}
+
+	type sortable struct {
+		doc    *types.Document
+		values []any
+	}
+
+	s := make([]sortable, len(docs))
+	for i, doc := range docs {
+		s[i].doc = doc
+		s[i].values = make([]any, len(keys))
+		for j, key := range keys {
+			s[i].values[j] = sortValue(doc, key)
+		}
+	}
+
+	sort.SliceStable(s, func(i, j int) bool {
+		for k, key := range keys {
+			c := types.Compare(s[i].values[k], s[j].values[k])
+			if c == 0 {
+				continue
+			}
+			if key.asc {
+				return c < 0
+			}
+			return c > 0
+		}
+		return false
+	})
+
+	for i := range s {
+		docs[i] = s[i].doc
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+)
+
+// typeCodes maps $type operator aliases to BSON type codes.
+var typeCodes = map[string]int32{
+	"double":              1,
+	"string":              2,
+	"object":              3,
+	"array":               4,
+	"binData":             5,
+	"undefined":           6,
+	"objectId":            7,
+	"bool":                8,
+	"date":                9,
+	"null":                10,
+	"regex":               11,
+	"dbPointer":           12,
+	"javascript":          13,
+	"symbol":              14,
+	"javascriptWithScope": 15,
+	"int":                 16,
+	"timestamp":           17,
+	"long":                18,
+	"decimal":             19,
+	"minKey":              -1,
+	"maxKey":              127,
+}
+
+// numberAliases contains type aliases matched by the "number" alias.
+var numberAliases = []string{"double", "int", "long", "decimal"}
+
+// ParseTypes parses $type operator argument: a type alias, a numeric type code, or an array of them.
+//
+// It returns a sorted list of unique type aliases; the "number" alias is expanded.
+func ParseTypes(value any) ([]string, error) {
+	set := make(map[string]struct{})
+
+	if arr, ok := value.(*types.Array); ok {
+		if arr.Len() == 0 {
+			return nil, NewError(ErrBadValue, fmt.Errorf("$type must match at least one type"))
+		}
+
+		for i := 0; i < arr.Len(); i++ {
+			v, err := arr.Get(i)
+			if err != nil {
+				return nil, err
+			}
+
+			if err = parseType(v, set); err != nil {
+				return nil, err
+			}
+		}
+	} else if err := parseType(value, set); err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(set))
+	for alias := range set {
+		res = append(res, alias)
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// parseType adds type aliases for a single $type operator value to the set.
+func parseType(value any, set map[string]struct{}) error {
+	var code float64
+	switch value := value.(type) {
+	case string:
+		if value == "number" {
+			for _, alias := range numberAliases {
+				set[alias] = struct{}{}
+			}
+			return nil
+		}
+
+		if _, ok := typeCodes[value]; !ok {
+			return NewError(ErrBadValue, fmt.Errorf("Unknown type name alias: %s", value))
+		}
+
+		set[value] = struct{}{}
+		return nil
+
+	case int32:
+		code = float64(value)
+	case int64:
+		code = float64(value)
+	case float64:
+		code = value
+	default:
+		return NewError(ErrTypeMismatch, fmt.Errorf("type must be represented as a number or a string"))
+	}
+
+	if code == math.Trunc(code) {
+		for alias, c := range typeCodes {
+			if float64(c) == code {
+				set[alias] = struct{}{}
+				return nil
+			}
+		}
+	}
+
+	return NewError(ErrBadValue, fmt.Errorf("Invalid numerical type code: %v", value))
+}
+
+// TypeAlias returns $type operator alias for the given value.
+func TypeAlias(value any) string {
+	switch value.(type) {
+	case float64:
+		return "double"
+	case string, types.CString:
+		return "string"
+	case *types.Document:
+		return "object"
+	case *types.Array:
+		return "array"
+	case types.Binary:
+		return "binData"
+	case types.UndefinedType:
+		return "undefined"
+	case types.ObjectID:
+		return "objectId"
+	case bool:
+		return "bool"
+	case time.Time:
+		return "date"
+	case types.NullType:
+		return "null"
+	case types.Regex:
+		return "regex"
+	case types.DBPointer:
+		return "dbPointer"
+	case types.JavaScript:
+		return "javascript"
+	case types.Symbol:
+		return "symbol"
+	case types.JavaScriptScope:
+		return "javascriptWithScope"
+	case int32:
+		return "int"
+	case types.Timestamp:
+		return "timestamp"
+	case int64:
+		return "long"
+	case types.Decimal128:
+		return "decimal"
+	case types.MinKeyType:
+		return "minKey"
+	case types.MaxKeyType:
+		return "maxKey"
+	default:
+		panic(fmt.Sprintf("common.TypeAlias: unhandled type %T", value))
+	}
+}
+
+// Truthy returns true if the given value is considered true by MongoDB:
+// false, null, undefined and numeric zeros are false, everything else is true.
+func Truthy(value any) bool {
+	switch value := value.(type) {
+	case bool:
+		return value
+	case types.NullType, types.UndefinedType:
+		return false
+	case int32:
+		return value != 0
+	case int64:
+		return value != 0
+	case float64:
+		return value != 0
+	case types.Decimal128:
+		r := value.Rat()
+		return r == nil || r.Sign() != 0
+	default:
+		return true
+	}
+}
@@ -15,6 +15,8 @@
 package common
 
 import (
+	"fmt"
+
 	"github.com/FerretDB/FerretDB/internal/pg"
 	"github.com/FerretDB/FerretDB/internal/types"
 	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
@@ -22,7 +24,7 @@ import (
 
 type wherePair func(key string, value any, p *pg.Placeholder) (sql string, args []any, err error)
 
-func LogicExpr(op string, exprs *types.Array, p *pg.Placeholder, wherePair wherePair) (sql string, args []any, err error) {
+func LogicExpr(op string, value any, p *pg.Placeholder, wherePair wherePair) (sql string, args []any, err error) {
 	if op == "$nor" {
 		sql = "NOT ("
 	}
@@ -32,6 +34,12 @@ func LogicExpr(op string, exprs *types.Array, p *pg.Placeholder, wherePair where
 		// {$or: [{expr1}, {expr2}, ...]}
 		// {$and: [{expr1}, {expr2}, ...]}
 		// {$nor: [{expr1}, {expr2}, ...]}
+		exprs, ok := value.(*types.Array)
+		if !ok {
+			err = NewError(ErrBadValue, fmt.Errorf("%s must be an array", op))
+			return
+		}
+
 		for i := 0; i < exprs.Len(); i++ {
 			if i != 0 {
 				switch op {
@@ -72,7 +80,7 @@ func LogicExpr(op string, exprs *types.Array, p *pg.Placeholder, wherePair where
 		}
 
 	default:
-		err = lazyerrors.Errorf("logicExpr: unhandled op %q", op)
+		err = NewError(ErrBadValue, fmt.Errorf("unknown top level operator: %s", op))
 	}
 
 	if op == "$nor" {
@@ -82,6 +90,23 @@ func LogicExpr(op string, exprs *types.Array, p *pg.Placeholder, wherePair where
 	return
 }
 
+// NotExpr returns the expression negated by $not operator.
+//
+// Regular expression is converted to {$regex: value} expression.
+func NotExpr(value any) (*types.Document, error) {
+	switch value := value.(type) {
+	case types.Regex:
+		return types.MustNewDocument("$regex", value), nil
+	case *types.Document:
+		if len(value.Keys()) == 0 {
+			return nil, NewError(ErrBadValue, fmt.Errorf("$not cannot be empty"))
+		}
+		return value, nil
+	default:
+		return nil, NewError(ErrBadValue, fmt.Errorf("$not needs a regex or a document"))
+	}
+}
+
 type scalar func(v any, p *pg.Placeholder) (sql string, args []any, err error)
 
 func InArray(a *types.Array, p *pg.Placeholder, scalar scalar) (sql string, args []any, err error) {
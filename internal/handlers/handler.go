@@ -16,6 +16,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync/atomic"
 	"time"
@@ -24,6 +25,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
+	"github.com/FerretDB/FerretDB/internal/bson"
 	"github.com/FerretDB/FerretDB/internal/handlers/common"
 	"github.com/FerretDB/FerretDB/internal/pg"
 	"github.com/FerretDB/FerretDB/internal/types"
@@ -39,6 +41,7 @@ type Handler struct {
 	l             *zap.Logger
 	sql           common.Storage
 	jsonb1        common.Storage
+	cursors       *common.Cursors
 	metrics       *Metrics
 	lastRequestID int32
 	startTime     time.Time
@@ -50,6 +53,7 @@ type NewOpts struct {
 	Logger        *zap.Logger
 	SQLStorage    common.Storage
 	JSONB1Storage common.Storage
+	Cursors       *common.Cursors
 	Metrics       *Metrics
 	PeerAddr      string
 	StartTime     time.Time
@@ -62,6 +66,7 @@ func New(opts *NewOpts) *Handler {
 		l:         opts.Logger,
 		sql:       opts.SQLStorage,
 		jsonb1:    opts.JSONB1Storage,
+		cursors:   opts.Cursors,
 		metrics:   opts.Metrics,
 		peerAddr:  opts.PeerAddr,
 		startTime: opts.StartTime,
@@ -76,6 +81,12 @@ func New(opts *NewOpts) *Handler {
 //  * return any other error - it will be returned to the client as InternalError before terminating connection;
 //  * panic - that will terminate the connection without a response.
 //
+// For OP_MSG requests with moreToCome flag and legacy OP_INSERT, OP_UPDATE, OP_DELETE and OP_KILL_CURSORS requests
+// the command is handled as usual, but nil response is returned as the client does not expect it.
+// For getMore requests with exhaustAllowed flag the response has moreToCome flag set
+// while the cursor is not exhausted; the caller should then call Handle again with the same request
+// and the header's RequestID set to the previous response's RequestID.
+//
 //nolint:lll // arguments are long
 func (h *Handler) Handle(ctx context.Context, reqHeader *wire.MsgHeader, reqBody wire.MsgBody) (resHeader *wire.MsgHeader, resBody wire.MsgBody, closeConn bool) {
 	var cmdLabel string
@@ -86,11 +97,17 @@ func (h *Handler) Handle(ctx context.Context, reqHeader *wire.MsgHeader, reqBody
 		if resLabel == nil {
 			resLabel = pointer.To("panic")
 		}
-		h.metrics.responses.WithLabelValues(resHeader.OpCode.String(), cmdLabel, *resLabel).Inc()
+
+		var opcodeLabel string // empty if response is not sent
+		if resHeader != nil {
+			opcodeLabel = resHeader.OpCode.String()
+		}
+		h.metrics.responses.WithLabelValues(opcodeLabel, cmdLabel, *resLabel).Inc()
 	}()
 
 	resHeader = new(wire.MsgHeader)
 	var err error
+	var noReply bool
 	switch reqHeader.OpCode {
 	case wire.OP_MSG:
 		// count requests even if msg's document is invalid
@@ -102,6 +119,8 @@ func (h *Handler) Handle(ctx context.Context, reqHeader *wire.MsgHeader, reqBody
 		}
 		requests.WithLabelValues(cmdLabel).Inc()
 
+		noReply = msg.FlagBits.FlagSet(wire.OpMsgMoreToCome)
+
 		if err == nil {
 			resHeader.OpCode = wire.OP_MSG
 			resBody, err = h.handleOpMsg(ctx, msg, cmdLabel)
@@ -109,25 +128,55 @@ func (h *Handler) Handle(ctx context.Context, reqHeader *wire.MsgHeader, reqBody
 
 	case wire.OP_QUERY:
 		query := reqBody.(*wire.OpQuery)
-		cmdLabel = query.Query.Command()
+		cmdLabel = "find"
+		if isCommandNamespace(query.FullCollectionName) {
+			if document, _ := UnwrapQuery(query.Query); document.Len() != 0 {
+				cmdLabel = document.Command()
+			}
+		}
 		requests.WithLabelValues(cmdLabel).Inc()
 
 		resHeader.OpCode = wire.OP_REPLY
-		resBody, err = h.handleOpQuery(ctx, query, cmdLabel)
+		resBody, err = h.handleOpQuery(ctx, query)
 
-	case wire.OP_REPLY:
-		fallthrough
-	case wire.OP_UPDATE:
-		fallthrough
 	case wire.OP_INSERT:
-		fallthrough
-	case wire.OP_GET_BY_OID:
-		fallthrough
-	case wire.OP_GET_MORE:
-		fallthrough
+		cmdLabel = "insert"
+		requests.WithLabelValues(cmdLabel).Inc()
+
+		noReply = true
+		err = h.handleOpInsert(ctx, reqBody.(*wire.OpInsert))
+
+	case wire.OP_UPDATE:
+		cmdLabel = "update"
+		requests.WithLabelValues(cmdLabel).Inc()
+
+		noReply = true
+		err = h.handleOpUpdate(ctx, reqBody.(*wire.OpUpdate))
+
 	case wire.OP_DELETE:
-		fallthrough
+		cmdLabel = "delete"
+		requests.WithLabelValues(cmdLabel).Inc()
+
+		noReply = true
+		err = h.handleOpDelete(ctx, reqBody.(*wire.OpDelete))
+
+	case wire.OP_GET_MORE:
+		cmdLabel = "getmore"
+		requests.WithLabelValues(cmdLabel).Inc()
+
+		resHeader.OpCode = wire.OP_REPLY
+		resBody, err = h.handleOpGetMore(ctx, reqBody.(*wire.OpGetMore))
+
 	case wire.OP_KILL_CURSORS:
+		cmdLabel = "killcursors"
+		requests.WithLabelValues(cmdLabel).Inc()
+
+		noReply = true
+		err = h.handleOpKillCursors(ctx, reqBody.(*wire.OpKillCursors))
+
+	case wire.OP_REPLY:
+		fallthrough
+	case wire.OP_GET_BY_OID:
 		fallthrough
 	case wire.OP_COMPRESSED:
 		fallthrough
@@ -136,22 +185,65 @@ func (h *Handler) Handle(ctx context.Context, reqHeader *wire.MsgHeader, reqBody
 		panic(fmt.Sprintf("unexpected OpCode %s", reqHeader.OpCode))
 	}
 
-	if err != nil {
-		if resHeader.OpCode != wire.OP_MSG {
-			panic(err)
+	// the client does not expect a response, so errors are only logged
+	if noReply {
+		resHeader = nil
+		resLabel = pointer.To("ok")
+
+		if err != nil {
+			h.l.Warn("Failed to handle request without response.", zap.String("command", cmdLabel), zap.Error(err))
+
+			protoErr, recoverable := common.ProtocolError(err)
+			resLabel = pointer.To(protoErr.Error())
+			closeConn = !recoverable
 		}
 
+		return
+	}
+
+	if err != nil {
 		protoErr, recoverable := common.ProtocolError(err)
 		resLabel = pointer.To(protoErr.Error())
 		closeConn = !recoverable
-		var res wire.OpMsg
-		err = res.SetSections(wire.OpMsgSection{
-			Documents: []*types.Document{protoErr.Document()},
-		})
-		if err != nil {
+
+		switch resHeader.OpCode {
+		case wire.OP_MSG:
+			var res wire.OpMsg
+			err = res.SetSections(wire.OpMsgSection{
+				Documents: []*types.Document{protoErr.Document()},
+			})
+			if err != nil {
+				panic(err)
+			}
+			resBody = &res
+
+		case wire.OP_REPLY:
+			// commands reply with error document, queries - with query failure
+			res := &wire.OpReply{
+				NumberReturned: 1,
+				Documents:      []*types.Document{protoErr.Document()},
+			}
+			if query, ok := reqBody.(*wire.OpQuery); !ok || !isCommandNamespace(query.FullCollectionName) {
+				res.ResponseFlags = wire.OpReplyFlags(wire.OpReplyQueryFailure)
+				res.Documents = []*types.Document{protoErr.QueryFailureDocument()}
+			}
+			resBody = res
+
+		default:
 			panic(err)
 		}
-		resBody = &res
+	}
+
+	// keep replying to getMore with exhaustAllowed flag until the cursor is exhausted
+	if msg, ok := reqBody.(*wire.OpMsg); ok && msg.FlagBits.FlagSet(wire.OpMsgExhaustAllowed) {
+		if cmdLabel == "getmore" && cursorID(resBody) != 0 {
+			resBody.(*wire.OpMsg).FlagBits |= wire.OpMsgFlags(wire.OpMsgMoreToCome)
+		}
+	}
+
+	// reply with checksum if the client sent one
+	if msg, ok := reqBody.(*wire.OpMsg); ok && msg.FlagBits.FlagSet(wire.OpMsgChecksumPresent) {
+		resBody.(*wire.OpMsg).FlagBits |= wire.OpMsgFlags(wire.OpMsgChecksumPresent)
 	}
 
 	resHeader.ResponseTo = reqHeader.RequestID
@@ -173,6 +265,85 @@ func (h *Handler) Handle(ctx context.Context, reqHeader *wire.MsgHeader, reqBody
 	return
 }
 
+// cursorID returns cursor.id field of the OP_MSG response document, or 0.
+func cursorID(resBody wire.MsgBody) int64 {
+	msg, ok := resBody.(*wire.OpMsg)
+	if !ok {
+		return 0
+	}
+
+	document, err := msg.Document()
+	if err != nil {
+		return 0
+	}
+
+	cursor, ok := document.Map()["cursor"].(*types.Document)
+	if !ok {
+		return 0
+	}
+
+	id, _ := cursor.Map()["id"].(int64)
+	return id
+}
+
+// HandleValidationError returns a response for the request that was read completely,
+// but failed validation (see wire.ValidationError).
+//
+// It returns nil response if there is no way to reply to the request with such opcode.
+func (h *Handler) HandleValidationError(reqHeader *wire.MsgHeader, validationErr error) (*wire.MsgHeader, wire.MsgBody) {
+	h.metrics.requests.WithLabelValues(reqHeader.OpCode.String(), "").Inc()
+
+	var ve *wire.ValidationError
+	if errors.As(validationErr, &ve) {
+		validationErr = ve.Unwrap()
+	}
+	code := common.ErrProtocolError
+	if errors.Is(validationErr, bson.ErrDocumentTooLarge) {
+		code = common.ErrBSONObjectTooLarge
+	}
+	protoErr := common.NewError(code, validationErr).(*common.Error)
+
+	resHeader := new(wire.MsgHeader)
+	var resBody wire.MsgBody
+	switch reqHeader.OpCode {
+	case wire.OP_MSG:
+		var res wire.OpMsg
+		if err := res.SetSections(wire.OpMsgSection{
+			Documents: []*types.Document{protoErr.Document()},
+		}); err != nil {
+			panic(err)
+		}
+
+		resHeader.OpCode = wire.OP_MSG
+		resBody = &res
+
+	case wire.OP_QUERY:
+		resHeader.OpCode = wire.OP_REPLY
+		resBody = &wire.OpReply{
+			ResponseFlags:  wire.OpReplyFlags(wire.OpReplyQueryFailure),
+			NumberReturned: 1,
+			Documents:      []*types.Document{protoErr.QueryFailureDocument()},
+		}
+
+	default:
+		h.metrics.responses.WithLabelValues("", "", protoErr.Error()).Inc()
+		return nil, nil
+	}
+
+	b, err := resBody.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+
+	resHeader.MessageLength = int32(wire.MsgHeaderLen + len(b))
+	resHeader.RequestID = atomic.AddInt32(&h.lastRequestID, 1)
+	resHeader.ResponseTo = reqHeader.RequestID
+
+	h.metrics.responses.WithLabelValues(resHeader.OpCode.String(), "", protoErr.Error()).Inc()
+
+	return resHeader, resBody
+}
+
 func (h *Handler) handleOpMsg(ctx context.Context, msg *wire.OpMsg, cmd string) (*wire.OpMsg, error) {
 	// special case to avoid circular dependency
 	if cmd == "listcommands" {
@@ -194,13 +365,17 @@ func (h *Handler) handleOpMsg(ctx context.Context, msg *wire.OpMsg, cmd string)
 	return nil, common.NewError(common.ErrCommandNotFound, fmt.Errorf("no such command: '%s'", cmd))
 }
 
-func (h *Handler) handleOpQuery(ctx context.Context, query *wire.OpQuery, cmd string) (*wire.OpReply, error) {
-	if query.FullCollectionName == "admin.$cmd" {
-		return h.QueryCmd(ctx, query)
+func (h *Handler) handleOpQuery(ctx context.Context, query *wire.OpQuery) (*wire.OpReply, error) {
+	db, collection, err := splitNamespace(query.FullCollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	if collection == "$cmd" {
+		return h.QueryCmd(ctx, db, query)
 	}
 
-	err := fmt.Errorf("handleOpQuery: unhandled collection %q", query.FullCollectionName)
-	return nil, common.NewError(common.ErrNotImplemented, err)
+	return h.handleOpQueryFind(ctx, db, collection, query)
 }
 
 func (h *Handler) msgStorage(ctx context.Context, msg *wire.OpMsg) (common.Storage, error) {
@@ -16,6 +16,8 @@ package handlers
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"os"
 	"runtime"
 	"strconv"
@@ -23,10 +25,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
 
+	"github.com/FerretDB/FerretDB/internal/handlers/common"
 	"github.com/FerretDB/FerretDB/internal/handlers/jsonb1"
 	"github.com/FerretDB/FerretDB/internal/handlers/sql"
 	"github.com/FerretDB/FerretDB/internal/pg"
@@ -42,19 +46,37 @@ func setup(t *testing.T, poolOpts *testutil.PoolOpts) (context.Context, *Handler
 
 	ctx := testutil.Ctx(t)
 	pool := testutil.Pool(ctx, t, poolOpts)
+	handler := newHandler(ctx, t, pool, common.DefaultCursorTimeout)
+
+	return ctx, handler, pool
+}
+
+// newHandler returns a new handler with its own cursor registry closing cursors idle for longer than cursorTimeout.
+func newHandler(ctx context.Context, t *testing.T, pool *pg.Pool, cursorTimeout time.Duration) *Handler {
+	t.Helper()
+
 	l := zaptest.NewLogger(t)
-	sql := sql.NewStorage(pool, l.Sugar())
-	jsonb1 := jsonb1.NewStorage(pool, l)
-	handler := New(&NewOpts{
+
+	// close open cursors before the pool is closed
+	cursors := common.NewCursors(l, cursorTimeout)
+	cursorsCtx, cancel := context.WithCancel(ctx)
+	go cursors.Run(cursorsCtx)
+	t.Cleanup(func() {
+		cancel()
+		cursors.Close()
+	})
+
+	sql := sql.NewStorage(pool, l.Sugar(), cursors)
+	jsonb1 := jsonb1.NewStorage(pool, l, cursors)
+	return New(&NewOpts{
 		PgPool:        pool,
 		Logger:        l,
 		PeerAddr:      "127.0.0.1:12345",
 		SQLStorage:    sql,
 		JSONB1Storage: jsonb1,
+		Cursors:       cursors,
 		Metrics:       NewMetrics(),
 	})
-
-	return ctx, handler, pool
 }
 
 func handle(ctx context.Context, t *testing.T, handler *Handler, req *types.Document) *types.Document {
@@ -362,6 +384,66 @@ func TestFind(t *testing.T) {
 				),
 			),
 		},
+		"ModBits": {
+			req: types.MustNewDocument(
+				"find", "actor",
+				"filter", types.MustNewDocument(
+					"actor_id", types.MustNewDocument(
+						"$mod", types.MustNewArray(int32(100), int32(2)),
+						"$bitsAllSet", types.MustNewArray(int32(1)),
+						"$bitsAllClear", int32(1),
+					),
+				),
+				"sort", types.MustNewDocument(
+					"actor_id", int32(1),
+				),
+				"limit", int32(1),
+			),
+			resp: types.MustNewArray(
+				types.MustNewDocument(
+					"_id", types.ObjectID{0x61, 0x2e, 0xc2, 0x80, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x02},
+					"actor_id", int32(2),
+					"first_name", "NICK",
+					"last_name", "WAHLBERG",
+					"last_update", lastUpdate,
+				),
+			),
+		},
+		"ExistsType": {
+			req: types.MustNewDocument(
+				"find", "actor",
+				"filter", types.MustNewDocument(
+					"actor_id", types.MustNewDocument(
+						"$type", "int",
+					),
+					"first_name", types.MustNewDocument(
+						"$exists", true,
+						"$type", types.MustNewArray("string", int32(10)),
+					),
+					"last_name", types.MustNewDocument(
+						"$not", types.MustNewDocument(
+							"$type", "number",
+						),
+					),
+					"middle_name", types.MustNewDocument(
+						"$exists", false,
+					),
+				),
+				"sort", types.MustNewDocument(
+					"actor_id", int32(1),
+				),
+				"limit", int32(1),
+			),
+			resp: types.MustNewArray(
+				types.MustNewDocument(
+					"_id", types.ObjectID{0x61, 0x2e, 0xc2, 0x80, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01},
+					"actor_id", int32(1),
+					"first_name", "PENELOPE",
+					"last_name", "GUINESS",
+					"last_update", lastUpdate,
+				),
+			),
+		},
 	}
 
 	for name, tc := range testCases { //nolint:paralleltest // false positive
@@ -971,3 +1053,2080 @@ func TestCreateListDropCollection(t *testing.T) {
 		assert.Equal(t, expected, actual)
 	})
 }
+
+func TestMoreToCome(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db := testutil.Schema(ctx, t, pool)
+	collection := testutil.CreateTable(ctx, t, pool, db)
+
+	var reqMsg wire.OpMsg
+	err := reqMsg.SetSections(wire.OpMsgSection{
+		Documents: []*types.Document{types.MustNewDocument(
+			"insert", collection,
+			"documents", types.MustNewArray(
+				types.MustNewDocument("_id", types.ObjectID{0x62, 0x56, 0xc5, 0xba, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01}),
+			),
+			"writeConcern", types.MustNewDocument("w", int32(0)),
+			"$db", db,
+		)},
+	})
+	require.NoError(t, err)
+	reqMsg.FlagBits = wire.OpMsgFlags(wire.OpMsgMoreToCome)
+
+	resHeader, resBody, closeConn := handler.Handle(ctx, &wire.MsgHeader{RequestID: 1, OpCode: wire.OP_MSG}, &reqMsg)
+	assert.False(t, closeConn)
+	assert.Nil(t, resHeader)
+	assert.Nil(t, resBody)
+
+	actual := handle(ctx, t, handler, types.MustNewDocument(
+		"count", collection,
+		"$db", db,
+	))
+	expected := types.MustNewDocument(
+		"n", int32(1),
+		"ok", float64(1),
+	)
+	assert.Equal(t, expected, actual)
+}
+
+func TestLegacyOpcodes(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db := testutil.Schema(ctx, t, pool)
+	collection := testutil.CreateTable(ctx, t, pool, db)
+
+	count := func(t *testing.T) *types.Document {
+		t.Helper()
+		return handle(ctx, t, handler, types.MustNewDocument(
+			"count", collection,
+			"$db", db,
+		))
+	}
+
+	id := types.ObjectID{0x62, 0x56, 0xc5, 0xba, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x02}
+
+	resHeader, resBody, closeConn := handler.Handle(ctx, &wire.MsgHeader{RequestID: 1, OpCode: wire.OP_INSERT}, &wire.OpInsert{
+		FullCollectionName: db + "." + collection,
+		Documents:          []*types.Document{types.MustNewDocument("_id", id, "v", "foo")},
+	})
+	assert.False(t, closeConn)
+	assert.Nil(t, resHeader)
+	assert.Nil(t, resBody)
+	assert.Equal(t, types.MustNewDocument("n", int32(1), "ok", float64(1)), count(t))
+
+	resHeader, resBody, closeConn = handler.Handle(ctx, &wire.MsgHeader{RequestID: 2, OpCode: wire.OP_DELETE}, &wire.OpDelete{
+		FullCollectionName: db + "." + collection,
+		Selector:           types.MustNewDocument("_id", id),
+	})
+	assert.False(t, closeConn)
+	assert.Nil(t, resHeader)
+	assert.Nil(t, resBody)
+	assert.Equal(t, types.MustNewDocument("n", int32(0), "ok", float64(1)), count(t))
+
+	resHeader, resBody, closeConn = handler.Handle(ctx, &wire.MsgHeader{RequestID: 3, OpCode: wire.OP_GET_MORE}, &wire.OpGetMore{
+		FullCollectionName: db + "." + collection,
+		CursorID:           42,
+	})
+	assert.False(t, closeConn)
+	require.NotNil(t, resHeader)
+	assert.Equal(t, wire.OP_REPLY, resHeader.OpCode)
+	assert.Equal(t, int32(3), resHeader.ResponseTo)
+	assert.True(t, resBody.(*wire.OpReply).ResponseFlags.FlagSet(wire.OpReplyCursorNotFound))
+}
+
+func TestOpUpdate(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db := testutil.Schema(ctx, t, pool)
+
+	for name, tc := range map[string]struct {
+		selector *types.Document
+		flags    wire.OpUpdateFlags
+		updated  int32 // number of documents with x field
+		total    int32
+	}{
+		"None": {
+			selector: types.MustNewDocument("v", "a"),
+			updated:  1,
+			total:    3,
+		},
+		"NoMatch": {
+			selector: types.MustNewDocument("_id", int32(4), "v", "c"),
+			updated:  0,
+			total:    3,
+		},
+		"Multi": {
+			selector: types.MustNewDocument("v", "a"),
+			flags:    wire.OpUpdateFlags(wire.OpUpdateMultiUpdate),
+			updated:  2,
+			total:    3,
+		},
+		"Upsert": {
+			selector: types.MustNewDocument("v", "a"),
+			flags:    wire.OpUpdateFlags(wire.OpUpdateUpsert),
+			updated:  1,
+			total:    3,
+		},
+		"UpsertInsert": {
+			selector: types.MustNewDocument("_id", int32(4), "v", "c"),
+			flags:    wire.OpUpdateFlags(wire.OpUpdateUpsert),
+			updated:  1,
+			total:    4,
+		},
+		"UpsertMulti": {
+			selector: types.MustNewDocument("v", "a"),
+			flags:    wire.OpUpdateFlags(wire.OpUpdateUpsert | wire.OpUpdateMultiUpdate),
+			updated:  2,
+			total:    3,
+		},
+		"UpsertMultiInsert": {
+			selector: types.MustNewDocument("_id", int32(4), "v", "c"),
+			flags:    wire.OpUpdateFlags(wire.OpUpdateUpsert | wire.OpUpdateMultiUpdate),
+			updated:  1,
+			total:    4,
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			collection := testutil.CreateTable(ctx, t, pool, db)
+
+			actual := handle(ctx, t, handler, types.MustNewDocument(
+				"insert", collection,
+				"documents", types.MustNewArray(
+					types.MustNewDocument("_id", int32(1), "v", "a"),
+					types.MustNewDocument("_id", int32(2), "v", "a"),
+					types.MustNewDocument("_id", int32(3), "v", "b"),
+				),
+				"$db", db,
+			))
+			require.Equal(t, types.MustNewDocument("n", int32(3), "ok", float64(1)), actual)
+
+			resHeader, resBody, closeConn := handler.Handle(ctx, &wire.MsgHeader{RequestID: 1, OpCode: wire.OP_UPDATE}, &wire.OpUpdate{
+				FullCollectionName: db + "." + collection,
+				Flags:              tc.flags,
+				Selector:           tc.selector,
+				Update:             types.MustNewDocument("$set", types.MustNewDocument("x", int32(1))),
+			})
+			assert.False(t, closeConn)
+			assert.Nil(t, resHeader)
+			assert.Nil(t, resBody)
+
+			count := func(query *types.Document) any {
+				return handle(ctx, t, handler, types.MustNewDocument(
+					"count", collection,
+					"query", query,
+					"$db", db,
+				)).Map()["n"]
+			}
+			assert.Equal(t, tc.total, count(types.MustNewDocument()))
+			assert.Equal(t, tc.updated, count(types.MustNewDocument("x", int32(1))))
+			assert.Equal(t, int32(1), count(types.MustNewDocument("_id", int32(3), "v", "b")))
+
+			if tc.total == 4 {
+				actual = handle(ctx, t, handler, types.MustNewDocument(
+					"find", collection,
+					"filter", types.MustNewDocument("_id", int32(4)),
+					"$db", db,
+				))
+				firstBatch := actual.Map()["cursor"].(*types.Document).Map()["firstBatch"].(*types.Array)
+				assert.Equal(t, types.MustNewArray(types.MustNewDocument("_id", int32(4), "v", "c", "x", int32(1))), firstBatch)
+			}
+		})
+	}
+}
+
+func TestOpQuery(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db := testutil.Schema(ctx, t, pool)
+	collection := testutil.CreateTable(ctx, t, pool, db)
+
+	query := func(t *testing.T, q *wire.OpQuery) *wire.OpReply {
+		t.Helper()
+		resHeader, resBody, closeConn := handler.Handle(ctx, &wire.MsgHeader{RequestID: 1, OpCode: wire.OP_QUERY}, q)
+		require.False(t, closeConn)
+		require.NotNil(t, resHeader)
+		assert.Equal(t, wire.OP_REPLY, resHeader.OpCode)
+		return resBody.(*wire.OpReply)
+	}
+
+	doc := types.MustNewDocument(
+		"_id", types.ObjectID{0x62, 0x56, 0xc5, 0xba, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x03},
+		"v", "foo",
+	)
+	handle(ctx, t, handler, types.MustNewDocument(
+		"insert", collection,
+		"documents", types.MustNewArray(doc),
+		"$db", db,
+	))
+
+	t.Run("Command", func(t *testing.T) {
+		t.Parallel()
+
+		reply := query(t, &wire.OpQuery{
+			FullCollectionName: db + ".$cmd",
+			NumberToReturn:     -1,
+			Query: types.MustNewDocument(
+				"$query", types.MustNewDocument("count", collection),
+				"$readPreference", types.MustNewDocument("mode", "primary"),
+			),
+		})
+		assert.Equal(t, int32(1), reply.NumberReturned)
+		assert.Equal(t, []*types.Document{types.MustNewDocument("n", int32(1), "ok", float64(1))}, reply.Documents)
+	})
+
+	t.Run("CommandError", func(t *testing.T) {
+		t.Parallel()
+
+		reply := query(t, &wire.OpQuery{
+			FullCollectionName: db + ".$cmd",
+			NumberToReturn:     -1,
+			Query:              types.MustNewDocument("noSuchCommand", int32(1)),
+		})
+		assert.False(t, reply.ResponseFlags.FlagSet(wire.OpReplyQueryFailure))
+		require.Len(t, reply.Documents, 1)
+		assert.Equal(t, float64(0), reply.Documents[0].Map()["ok"])
+		assert.Equal(t, int32(common.ErrCommandNotFound), reply.Documents[0].Map()["code"])
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		t.Parallel()
+
+		reply := query(t, &wire.OpQuery{
+			FullCollectionName: db + "." + collection,
+			Query:              types.MustNewDocument("v", "foo"),
+		})
+		assert.Equal(t, int32(1), reply.NumberReturned)
+		assert.Equal(t, []*types.Document{doc}, reply.Documents)
+	})
+
+	t.Run("FindError", func(t *testing.T) {
+		t.Parallel()
+
+		reply := query(t, &wire.OpQuery{
+			FullCollectionName: db + "." + collection,
+			NumberToReturn:     -1,
+			Query:              types.MustNewDocument("v", types.MustNewDocument("$regex", int32(1))),
+		})
+		assert.True(t, reply.ResponseFlags.FlagSet(wire.OpReplyQueryFailure))
+		require.Len(t, reply.Documents, 1)
+		assert.Equal(t, int32(common.ErrBadValue), reply.Documents[0].Map()["code"])
+		assert.Contains(t, reply.Documents[0].Map(), "$err")
+	})
+}
+
+func TestDecimal128(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db := testutil.Schema(ctx, t, pool)
+	collection := testutil.CreateTable(ctx, t, pool, db)
+
+	dec := func(s string) types.Decimal128 {
+		return must.NotFail(types.ParseDecimal128(s))
+	}
+
+	actual := handle(ctx, t, handler, types.MustNewDocument(
+		"insert", collection,
+		"documents", types.MustNewArray(
+			types.MustNewDocument("_id", int32(1), "v", dec("10.50")),
+			types.MustNewDocument("_id", int32(2), "v", dec("9.99")),
+			types.MustNewDocument("_id", int32(3), "v", int32(10)),
+			types.MustNewDocument("_id", int32(4), "v", dec("-1E+3")),
+		),
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(4), "ok", float64(1)), actual)
+
+	ids := func(t *testing.T, filter *types.Document) *types.Array {
+		t.Helper()
+		actual := handle(ctx, t, handler, types.MustNewDocument(
+			"find", collection,
+			"filter", filter,
+			"sort", types.MustNewDocument("v", int32(1)),
+			"projection", types.MustNewDocument("_id", int32(1)),
+			"$db", db,
+		))
+		cursor := actual.Map()["cursor"].(*types.Document)
+		firstBatch := cursor.Map()["firstBatch"].(*types.Array)
+		res := types.MakeArray(firstBatch.Len())
+		for i := 0; i < firstBatch.Len(); i++ {
+			doc := must.NotFail(firstBatch.Get(i)).(*types.Document)
+			require.NoError(t, res.Append(doc.Map()["_id"]))
+		}
+		return res
+	}
+
+	assert.Equal(t, types.MustNewArray(int32(4), int32(2), int32(3), int32(1)), ids(t, types.MustNewDocument()))
+	assert.Equal(t, types.MustNewArray(int32(3), int32(1)), ids(t, types.MustNewDocument(
+		"v", types.MustNewDocument("$gt", dec("9.999")),
+	)))
+	assert.Equal(t, types.MustNewArray(int32(1)), ids(t, types.MustNewDocument(
+		"v", dec("10.5"),
+	)))
+	assert.Equal(t, types.MustNewArray(int32(4), int32(2)), ids(t, types.MustNewDocument(
+		"v", types.MustNewDocument("$lt", dec("10")),
+	)))
+}
+
+func TestMinMaxKey(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db := testutil.Schema(ctx, t, pool)
+	collection := testutil.CreateTable(ctx, t, pool, db)
+
+	actual := handle(ctx, t, handler, types.MustNewDocument(
+		"insert", collection,
+		"documents", types.MustNewArray(
+			types.MustNewDocument("_id", int32(1), "v", types.MaxKey),
+			types.MustNewDocument("_id", int32(2), "v", "foo"),
+			types.MustNewDocument("_id", int32(3), "v", types.MinKey),
+			types.MustNewDocument("_id", int32(4), "v", int32(42)),
+			types.MustNewDocument("_id", int32(5), "v", types.Symbol("bar")),
+		),
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(5), "ok", float64(1)), actual)
+
+	for order, expected := range map[int32]*types.Array{
+		1:  types.MustNewArray(int32(3), int32(4), int32(5), int32(2), int32(1)),
+		-1: types.MustNewArray(int32(1), int32(2), int32(5), int32(4), int32(3)),
+	} {
+		actual := handle(ctx, t, handler, types.MustNewDocument(
+			"find", collection,
+			"sort", types.MustNewDocument("v", order),
+			"projection", types.MustNewDocument("_id", int32(1)),
+			"$db", db,
+		))
+		firstBatch := must.NotFail(actual.GetByPath("cursor", "firstBatch")).(*types.Array)
+		require.Equal(t, expected.Len(), firstBatch.Len())
+		for i := 0; i < expected.Len(); i++ {
+			doc := must.NotFail(firstBatch.Get(i)).(*types.Document)
+			assert.Equal(t, must.NotFail(expected.Get(i)), doc.Map()["_id"], "order %d, index %d", order, i)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db := testutil.Schema(ctx, t, pool)
+	collection := testutil.CreateTable(ctx, t, pool, db)
+
+	actual := handle(ctx, t, handler, types.MustNewDocument(
+		"insert", collection,
+		"documents", types.MustNewArray(
+			types.MustNewDocument("_id", int32(1), "v", float64(42.5)),
+			types.MustNewDocument("_id", int32(2), "v", "foo"),
+			types.MustNewDocument("_id", int32(3), "v", int64(42)),
+			types.MustNewDocument("_id", int32(4)),
+			types.MustNewDocument("_id", int32(5), "v", types.MustNewArray(int32(50), int32(-1))),
+			types.MustNewDocument("_id", int32(6), "v", types.MustNewDocument("a", int32(1))),
+			types.MustNewDocument("_id", int32(7), "v", int32(43)),
+		),
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(7), "ok", float64(1)), actual)
+
+	ids := func(t *testing.T, sort *types.Document, limit int32) []any {
+		t.Helper()
+		actual := handle(ctx, t, handler, types.MustNewDocument(
+			"find", collection,
+			"sort", sort,
+			"limit", limit,
+			"projection", types.MustNewDocument("_id", int32(1)),
+			"$db", db,
+		))
+		firstBatch := must.NotFail(actual.GetByPath("cursor", "firstBatch")).(*types.Array)
+		res := make([]any, firstBatch.Len())
+		for i := range res {
+			res[i] = must.NotFail(firstBatch.Get(i)).(*types.Document).Map()["_id"]
+		}
+		return res
+	}
+
+	// arrays are sorted by the smallest element in ascending order and by the largest one in descending order
+	expected := []any{int32(4), int32(5), int32(3), int32(1), int32(7), int32(2), int32(6)}
+	assert.Equal(t, expected, ids(t, types.MustNewDocument("v", int32(1)), 0))
+	expected = []any{int32(6), int32(2), int32(5), int32(7), int32(1), int32(3), int32(4)}
+	assert.Equal(t, expected, ids(t, types.MustNewDocument("v", float64(-1)), 0))
+	assert.Equal(t, expected[:2], ids(t, types.MustNewDocument("v", int64(-1)), 2))
+
+	actual = handle(ctx, t, handler, types.MustNewDocument(
+		"find", collection,
+		"sort", types.MustNewDocument("v", int32(2)),
+		"$db", db,
+	))
+	assert.Equal(t, int32(common.ErrBadValue), actual.Map()["code"])
+}
+
+func TestSortScalars(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db := testutil.Schema(ctx, t, pool)
+	collection := testutil.CreateTable(ctx, t, pool, db)
+
+	// all values are scalars, so documents are sorted in SQL; there are no ties
+	docs := []*types.Document{
+		types.MustNewDocument("_id", int32(1), "v", float64(42.5)),
+		types.MustNewDocument("_id", int32(2), "v", "foo"),
+		types.MustNewDocument("_id", int32(3), "v", int64(42)),
+		types.MustNewDocument("_id", int32(4)),
+		types.MustNewDocument("_id", int32(5), "v", math.NaN()),
+		types.MustNewDocument("_id", int32(6), "v", math.Inf(1)),
+		types.MustNewDocument("_id", int32(7), "v", math.Inf(-1)),
+		types.MustNewDocument("_id", int32(8), "v", must.NotFail(types.ParseDecimal128("42.25"))),
+		types.MustNewDocument("_id", int32(9), "v", types.MinKey),
+		types.MustNewDocument("_id", int32(10), "v", types.MaxKey),
+		types.MustNewDocument("_id", int32(11), "v", types.Symbol("bar")),
+		types.MustNewDocument("_id", int32(12), "v", types.ObjectID{0x62, 0x01}),
+		types.MustNewDocument("_id", int32(13), "v", types.ObjectID{0x0a, 0x02}),
+		types.MustNewDocument("_id", int32(14), "v", true),
+		types.MustNewDocument("_id", int32(15), "v", false),
+		types.MustNewDocument("_id", int32(16), "v", time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)),
+		types.MustNewDocument("_id", int32(17), "v", time.Date(1969, 4, 1, 0, 0, 0, 0, time.UTC)),
+		types.MustNewDocument("_id", int32(18), "v", int32(-5)),
+		types.MustNewDocument("_id", int32(19), "v", "Foo"),
+		types.MustNewDocument("_id", int32(20), "v", "éclair"),
+		types.MustNewDocument("_id", int32(21), "v", int64(math.MaxInt64)),
+		types.MustNewDocument("_id", int32(22), "v", int64(math.MaxInt64-1)),
+	}
+
+	insert := new(types.Array)
+	for _, doc := range docs {
+		require.NoError(t, insert.Append(doc))
+	}
+	actual := handle(ctx, t, handler, types.MustNewDocument(
+		"insert", collection,
+		"documents", insert,
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(len(docs)), "ok", float64(1)), actual)
+
+	for name, tc := range map[string]struct {
+		sort   *types.Document
+		filter *types.Document
+		limit  int32
+	}{
+		"Asc": {
+			sort: types.MustNewDocument("v", int32(1)),
+		},
+		"Desc": {
+			sort: types.MustNewDocument("v", int32(-1)),
+		},
+		"Limit": {
+			sort:  types.MustNewDocument("v", float64(1)),
+			limit: 5,
+		},
+		"ResidualLimit": {
+			sort: types.MustNewDocument("v", int64(-1)),
+			filter: types.MustNewDocument("$expr", types.MustNewDocument(
+				"$ne", types.MustNewArray("$_id", int32(10)),
+			)),
+			limit: 5,
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// sort in Go for comparison
+			var expectedDocs []*types.Document
+			for _, doc := range docs {
+				if tc.filter != nil && doc.Map()["_id"] == int32(10) {
+					continue
+				}
+				expectedDocs = append(expectedDocs, doc)
+			}
+			require.NoError(t, common.SortDocuments(expectedDocs, tc.sort))
+			if tc.limit != 0 {
+				expectedDocs = expectedDocs[:tc.limit]
+			}
+
+			expected := make([]any, len(expectedDocs))
+			for i, doc := range expectedDocs {
+				expected[i] = doc.Map()["_id"]
+			}
+
+			req := types.MustNewDocument(
+				"find", collection,
+				"sort", tc.sort,
+				"limit", tc.limit,
+				"$db", db,
+			)
+			if tc.filter != nil {
+				require.NoError(t, req.Set("filter", tc.filter))
+			}
+
+			actual, id := cursorBatch(t, handle(ctx, t, handler, req))
+			assert.Equal(t, expected, actual)
+			assert.Zero(t, id)
+		})
+	}
+}
+
+func TestProjection(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db := testutil.Schema(ctx, t, pool)
+	collection := testutil.CreateTable(ctx, t, pool, db)
+
+	actual := handle(ctx, t, handler, types.MustNewDocument(
+		"insert", collection,
+		"documents", types.MustNewArray(
+			types.MustNewDocument("_id", int32(1), "a", int32(1), "b", "x"),
+			types.MustNewDocument("_id", int32(2), "a", int32(2)),
+			types.MustNewDocument("_id", int32(3), "a", types.MustNewArray(int32(3)), "b", "z"),
+		),
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(3), "ok", float64(1)), actual)
+
+	for name, tc := range map[string]struct {
+		filter   *types.Document
+		sort     *types.Document
+		expected *types.Array
+	}{
+		"SQLSort": {
+			filter: types.MustNewDocument("_id", types.MustNewDocument("$lt", int32(3))),
+			sort:   types.MustNewDocument("a", int32(-1)),
+			expected: types.MustNewArray(
+				types.MustNewDocument("b", types.Null),
+				types.MustNewDocument("b", "x"),
+			),
+		},
+		"GoSort": {
+			sort: types.MustNewDocument("a", int32(-1)),
+			expected: types.MustNewArray(
+				types.MustNewDocument("b", "z"),
+				types.MustNewDocument("b", types.Null),
+				types.MustNewDocument("b", "x"),
+			),
+		},
+		"Residual": {
+			filter: types.MustNewDocument("$expr", types.MustNewDocument("$eq", types.MustNewArray("$a", int32(1)))),
+			expected: types.MustNewArray(
+				types.MustNewDocument("b", "x"),
+			),
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := types.MustNewDocument(
+				"find", collection,
+				"projection", types.MustNewDocument("b", int32(1)),
+				"$db", db,
+			)
+			if tc.filter != nil {
+				require.NoError(t, req.Set("filter", tc.filter))
+			}
+			if tc.sort != nil {
+				require.NoError(t, req.Set("sort", tc.sort))
+			}
+
+			actual := handle(ctx, t, handler, req)
+			assert.Equal(t, tc.expected, must.NotFail(actual.GetByPath("cursor", "firstBatch")))
+		})
+	}
+}
+
+func TestInsertID(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db := testutil.Schema(ctx, t, pool)
+	collection := testutil.CreateTable(ctx, t, pool, db)
+
+	actual := handle(ctx, t, handler, types.MustNewDocument(
+		"insert", collection,
+		"documents", types.MustNewArray(
+			types.MustNewDocument("v", "foo"),
+			types.MustNewDocument("v", "bar", "_id", int32(2)),
+		),
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(2), "ok", float64(1)), actual)
+
+	actual = handle(ctx, t, handler, types.MustNewDocument(
+		"find", collection,
+		"sort", types.MustNewDocument("v", int32(1)),
+		"$db", db,
+	))
+	firstBatch := must.NotFail(actual.GetByPath("cursor", "firstBatch")).(*types.Array)
+	require.Equal(t, 2, firstBatch.Len())
+
+	// _id is always the first field
+	doc := must.NotFail(firstBatch.Get(0)).(*types.Document)
+	assert.Equal(t, types.MustNewDocument("_id", int32(2), "v", "bar"), doc)
+	doc = must.NotFail(firstBatch.Get(1)).(*types.Document)
+	assert.Equal(t, []string{"_id", "v"}, doc.Keys())
+	assert.IsType(t, types.ObjectID{}, doc.Map()["_id"])
+
+	for name, tc := range map[string]struct {
+		id   any
+		code common.ErrorCode
+	}{
+		"Array": {id: types.MustNewArray(int32(1)), code: common.ErrInvalidIDField},
+		"Regex": {id: types.Regex{Pattern: "foo"}, code: common.ErrBadValue},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := handle(ctx, t, handler, types.MustNewDocument(
+				"insert", collection,
+				"documents", types.MustNewArray(types.MustNewDocument("_id", tc.id)),
+				"$db", db,
+			))
+			assert.Equal(t, int32(tc.code), actual.Map()["code"])
+		})
+	}
+}
+
+// createSQLTable creates SQL storage table with the given columns definition in the given schema.
+func createSQLTable(ctx context.Context, t *testing.T, pool *pg.Pool, db, columns string) string {
+	t.Helper()
+
+	table := testutil.TableName(t)
+	_, err := pool.Exec(ctx, fmt.Sprintf("CREATE TABLE %s (%s)", pgx.Identifier{db, table}.Sanitize(), columns))
+	require.NoError(t, err)
+
+	return table
+}
+
+func TestSQLInsertID(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db := testutil.Schema(ctx, t, pool)
+
+	t.Run("IDColumn", func(t *testing.T) {
+		t.Parallel()
+
+		collection := createSQLTable(ctx, t, pool, db, "_id bytea, v text")
+		id := types.ObjectID{0x62, 0x56, 0xc5, 0xba, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01}
+
+		actual := handle(ctx, t, handler, types.MustNewDocument(
+			"insert", collection,
+			"documents", types.MustNewArray(
+				types.MustNewDocument("_id", id, "v", "foo"),
+				types.MustNewDocument("v", "bar"),
+			),
+			"$db", db,
+		))
+		assert.Equal(t, types.MustNewDocument("n", int32(2), "ok", float64(1)), actual)
+
+		var stored []byte
+		sql := fmt.Sprintf("SELECT _id FROM %s WHERE v = 'foo'", pgx.Identifier{db, collection}.Sanitize())
+		require.NoError(t, pool.QueryRow(ctx, sql).Scan(&stored))
+		assert.Equal(t, id[:], stored)
+
+		// generated _id is stored too
+		sql = fmt.Sprintf("SELECT _id FROM %s WHERE v = 'bar'", pgx.Identifier{db, collection}.Sanitize())
+		require.NoError(t, pool.QueryRow(ctx, sql).Scan(&stored))
+		assert.Len(t, stored, 12)
+	})
+
+	t.Run("NoIDColumn", func(t *testing.T) {
+		t.Parallel()
+
+		collection := createSQLTable(ctx, t, pool, db, "v text")
+
+		actual := handle(ctx, t, handler, types.MustNewDocument(
+			"insert", collection,
+			"documents", types.MustNewArray(types.MustNewDocument("v", "bar")),
+			"$db", db,
+		))
+		assert.Equal(t, types.MustNewDocument("n", int32(1), "ok", float64(1)), actual)
+
+		actual = handle(ctx, t, handler, types.MustNewDocument(
+			"insert", collection,
+			"documents", types.MustNewArray(types.MustNewDocument("_id", int32(1), "v", "foo")),
+			"$db", db,
+		))
+		assert.Equal(t, int32(common.ErrNotImplemented), actual.Map()["code"])
+	})
+}
+
+func TestSQLExistsTypeFilter(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db := testutil.Schema(ctx, t, pool)
+
+	collection := createSQLTable(ctx, t, pool, db, "_id int4, i2 int2, f4 float4, n numeric, b bytea")
+	sql := fmt.Sprintf(
+		`INSERT INTO %s VALUES (1, 1, 1.5, 2.5, '\x01'), (2, NULL, NULL, 'NaN', NULL)`,
+		pgx.Identifier{db, collection}.Sanitize(),
+	)
+	_, err := pool.Exec(ctx, sql)
+	require.NoError(t, err)
+
+	// values are converted to types listed in columnTypes
+	actual := handle(ctx, t, handler, types.MustNewDocument(
+		"find", collection,
+		"sort", types.MustNewDocument("_id", int32(1)),
+		"$db", db,
+	))
+	expected := types.MustNewArray(
+		types.MustNewDocument(
+			"_id", int32(1),
+			"i2", int32(1),
+			"f4", float64(1.5),
+			"n", must.NotFail(types.ParseDecimal128("2.5")),
+			"b", types.Binary{Subtype: types.BinaryGeneric, B: []byte{0x01}},
+		),
+		types.MustNewDocument(
+			"_id", int32(2),
+			"i2", types.Null,
+			"f4", types.Null,
+			"n", must.NotFail(types.ParseDecimal128("NaN")),
+			"b", types.Null,
+		),
+	)
+	assert.Equal(t, expected, must.NotFail(actual.GetByPath("cursor", "firstBatch")))
+
+	for name, tc := range map[string]struct {
+		filter   *types.Document
+		expected []any
+	}{
+		"Exists": {
+			filter:   types.MustNewDocument("i2", types.MustNewDocument("$exists", true)),
+			expected: []any{int32(1), int32(2)},
+		},
+		"ExistsMissing": {
+			filter:   types.MustNewDocument("missing", types.MustNewDocument("$exists", true)),
+			expected: []any{},
+		},
+		"NotExistsMissing": {
+			filter:   types.MustNewDocument("missing", types.MustNewDocument("$exists", false)),
+			expected: []any{int32(1), int32(2)},
+		},
+		"Int2": {
+			filter:   types.MustNewDocument("i2", types.MustNewDocument("$type", "int")),
+			expected: []any{int32(1)},
+		},
+		"Int2Long": {
+			filter:   types.MustNewDocument("i2", types.MustNewDocument("$type", "long")),
+			expected: []any{},
+		},
+		"Float4": {
+			filter:   types.MustNewDocument("f4", types.MustNewDocument("$type", "double")),
+			expected: []any{int32(1)},
+		},
+		"Numeric": {
+			filter:   types.MustNewDocument("n", types.MustNewDocument("$type", "decimal")),
+			expected: []any{int32(1), int32(2)},
+		},
+		"NumericNumber": {
+			filter:   types.MustNewDocument("n", types.MustNewDocument("$type", "number")),
+			expected: []any{int32(1), int32(2)},
+		},
+		"Bytea": {
+			filter:   types.MustNewDocument("b", types.MustNewDocument("$type", "binData")),
+			expected: []any{int32(1)},
+		},
+		"ByteaNull": {
+			filter:   types.MustNewDocument("b", types.MustNewDocument("$type", types.MustNewArray("null", "string"))),
+			expected: []any{int32(2)},
+		},
+		"NumericMod": {
+			filter:   types.MustNewDocument("n", types.MustNewDocument("$mod", types.MustNewArray(int32(2), int32(0)))),
+			expected: []any{int32(1)},
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ids, code := findIDs(ctx, t, handler, db, collection, tc.filter)
+			require.Zero(t, code)
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+// insertDocuments inserts documents into the collection.
+func insertDocuments(ctx context.Context, t *testing.T, handler *Handler, db, collection string,
+	docs ...*types.Document,
+) {
+	t.Helper()
+
+	arr := types.MakeArray(len(docs))
+	for _, doc := range docs {
+		require.NoError(t, arr.Append(doc))
+	}
+
+	actual := handle(ctx, t, handler, types.MustNewDocument(
+		"insert", collection,
+		"documents", arr,
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(len(docs)), "ok", float64(1)), actual)
+}
+
+// setupCollection creates a new jsonb1 collection in a new schema with the given documents
+// and returns schema and collection names.
+func setupCollection(ctx context.Context, t *testing.T, handler *Handler, pool *pg.Pool,
+	docs ...*types.Document,
+) (string, string) {
+	t.Helper()
+
+	db := testutil.Schema(ctx, t, pool)
+	collection := testutil.CreateTable(ctx, t, pool, db)
+	insertDocuments(ctx, t, handler, db, collection, docs...)
+
+	return db, collection
+}
+
+// findIDs returns _id values of the collection's documents matching the filter, sorted by _id,
+// or the error code if the query failed.
+//
+// Additional find command fields like "limit", int32(1) may be given as key-value pairs.
+func findIDs(ctx context.Context, t *testing.T, handler *Handler, db, collection string,
+	filter *types.Document, fields ...any,
+) ([]any, common.ErrorCode) {
+	t.Helper()
+
+	pairs := []any{
+		"find", collection,
+		"filter", filter,
+		"sort", types.MustNewDocument("_id", int32(1)),
+	}
+	pairs = append(pairs, fields...)
+	pairs = append(pairs, "$db", db)
+
+	actual := handle(ctx, t, handler, must.NotFail(types.NewDocument(pairs...)))
+	if code, ok := actual.Map()["code"].(int32); ok {
+		return nil, common.ErrorCode(code)
+	}
+
+	firstBatch := must.NotFail(actual.GetByPath("cursor", "firstBatch")).(*types.Array)
+	ids := make([]any, firstBatch.Len())
+	for i := range ids {
+		ids[i] = must.NotFail(firstBatch.Get(i)).(*types.Document).Map()["_id"]
+	}
+
+	return ids, 0
+}
+
+func TestDotNotationFilter(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+
+	db, collection := setupCollection(ctx, t, handler, pool,
+		types.MustNewDocument("_id", int32(1), "address", types.MustNewDocument("city", "Paris", "zip", "75001")),
+		types.MustNewDocument("_id", int32(2), "address", types.MustNewDocument("city", "Berlin", "zip", "10115")),
+		types.MustNewDocument("_id", int32(3), "tags", types.MustNewArray("foo", types.MustNewDocument("name", "bar"))),
+		types.MustNewDocument("_id", int32(4), "address", types.MustNewDocument("0", "Paris")),
+		types.MustNewDocument("_id", int32(5), "v", types.MustNewDocument("n", types.MustNewDocument("d", int32(42)))),
+	)
+
+	for name, tc := range map[string]struct {
+		filter   *types.Document
+		expected []any
+	}{
+		"Field": {
+			filter:   types.MustNewDocument("address.city", "Paris"),
+			expected: []any{int32(1)},
+		},
+		"Operator": {
+			filter:   types.MustNewDocument("address.zip", types.MustNewDocument("$gt", "5")),
+			expected: []any{int32(1)},
+		},
+		"Regex": {
+			filter:   types.MustNewDocument("address.city", types.Regex{Pattern: "^b", Options: "i"}),
+			expected: []any{int32(2)},
+		},
+		"In": {
+			filter: types.MustNewDocument("address.city", types.MustNewDocument(
+				"$in", types.MustNewArray("Paris", "Berlin"),
+			)),
+			expected: []any{int32(1), int32(2)},
+		},
+		"Deep": {
+			filter:   types.MustNewDocument("v.n.d", int32(42)),
+			expected: []any{int32(5)},
+		},
+		"ArrayIndex": {
+			filter:   types.MustNewDocument("tags.0", "foo"),
+			expected: []any{int32(3)},
+		},
+		"ArrayIndexField": {
+			filter:   types.MustNewDocument("tags.1.name", "bar"),
+			expected: []any{int32(3)},
+		},
+		"DocumentNumericKey": {
+			filter:   types.MustNewDocument("address.0", "Paris"),
+			expected: []any{int32(4)},
+		},
+		"Missing": {
+			filter:   types.MustNewDocument("address.country", "France"),
+			expected: []any{},
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ids, code := findIDs(ctx, t, handler, db, collection, tc.filter)
+			require.Zero(t, code)
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+
+	actual := handle(ctx, t, handler, types.MustNewDocument(
+		"find", collection,
+		"filter", types.MustNewDocument("address..city", "Paris"),
+		"$db", db,
+	))
+	assert.Equal(t, int32(common.ErrEmptyFieldName), actual.Map()["code"])
+}
+
+func TestArrayFilter(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+
+	db, collection := setupCollection(ctx, t, handler, pool,
+		types.MustNewDocument("_id", int32(1), "tags", types.MustNewArray("red", "blue")),
+		types.MustNewDocument("_id", int32(2), "tags", "red"),
+		types.MustNewDocument("_id", int32(3), "tags", types.MustNewArray(types.MustNewArray("red"), "green")),
+		types.MustNewDocument("_id", int32(4), "v", types.MustNewArray(int32(1), int32(10))),
+		types.MustNewDocument("_id", int32(5), "items", types.MustNewArray(
+			types.MustNewDocument("name", "foo", "qty", int32(5)),
+			types.MustNewDocument("name", "bar", "qty", int32(20)),
+		)),
+		types.MustNewDocument("_id", int32(6), "v", int32(7)),
+	)
+
+	for name, tc := range map[string]struct {
+		filter   *types.Document
+		expected []any
+	}{
+		"Element": {
+			filter:   types.MustNewDocument("tags", "red"),
+			expected: []any{int32(1), int32(2)},
+		},
+		"WholeArray": {
+			filter:   types.MustNewDocument("tags", types.MustNewDocument("$eq", types.MustNewArray("red"))),
+			expected: []any{int32(3)},
+		},
+		"Gt": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$gt", int32(5))),
+			expected: []any{int32(4), int32(6)},
+		},
+		"Range": {
+			// each condition may be satisfied by a different element
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$gt", int32(5), "$lt", int32(2))),
+			expected: []any{int32(4)},
+		},
+		"In": {
+			filter:   types.MustNewDocument("tags", types.MustNewDocument("$in", types.MustNewArray("blue", "green"))),
+			expected: []any{int32(1), int32(3)},
+		},
+		"Nin": {
+			filter:   types.MustNewDocument("tags", types.MustNewDocument("$nin", types.MustNewArray("blue", "green"))),
+			expected: []any{int32(2), int32(4), int32(5), int32(6)},
+		},
+		"Ne": {
+			filter:   types.MustNewDocument("tags", types.MustNewDocument("$ne", "red")),
+			expected: []any{int32(3), int32(4), int32(5), int32(6)},
+		},
+		"NestedField": {
+			filter:   types.MustNewDocument("items.name", "bar"),
+			expected: []any{int32(5)},
+		},
+		"NestedFieldGte": {
+			filter:   types.MustNewDocument("items.qty", types.MustNewDocument("$gte", int32(20))),
+			expected: []any{int32(5)},
+		},
+		"NestedIndex": {
+			filter:   types.MustNewDocument("items.1.name", "foo"),
+			expected: []any{},
+		},
+		"Regex": {
+			filter:   types.MustNewDocument("tags", types.Regex{Pattern: "^gr"}),
+			expected: []any{int32(3)},
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ids, code := findIDs(ctx, t, handler, db, collection, tc.filter)
+			require.Zero(t, code)
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+func TestExistsTypeFilter(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+
+	db, collection := setupCollection(ctx, t, handler, pool,
+		types.MustNewDocument("_id", int32(1), "v", float64(42.5)),
+		types.MustNewDocument("_id", int32(2), "v", "foo"),
+		types.MustNewDocument("_id", int32(3), "v", int64(42)),
+		types.MustNewDocument("_id", int32(4)),
+		types.MustNewDocument("_id", int32(5), "v", types.MustNewArray(int32(1), "bar")),
+		types.MustNewDocument("_id", int32(6), "v", types.MustNewDocument("a", true)),
+		types.MustNewDocument("_id", int32(7), "v", types.Null),
+		types.MustNewDocument("_id", int32(8), "v", types.JavaScript("x")),
+		types.MustNewDocument("_id", int32(9), "v", types.MinKey),
+	)
+
+	for name, tc := range map[string]struct {
+		filter   *types.Document
+		expected []any
+		code     common.ErrorCode
+	}{
+		"Exists": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$exists", true)),
+			expected: []any{int32(1), int32(2), int32(3), int32(5), int32(6), int32(7), int32(8), int32(9)},
+		},
+		"NotExists": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$exists", int32(0))),
+			expected: []any{int32(4)},
+		},
+		"ExistsNested": {
+			filter:   types.MustNewDocument("v.a", types.MustNewDocument("$exists", true)),
+			expected: []any{int32(6)},
+		},
+		"String": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$type", "string")),
+			expected: []any{int32(2), int32(5)},
+		},
+		"Number": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$type", "number")),
+			expected: []any{int32(1), int32(3), int32(5)},
+		},
+		"Codes": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$type", types.MustNewArray(int32(18), float64(10)))),
+			expected: []any{int32(3), int32(7)},
+		},
+		"Array": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$type", "array")),
+			expected: []any{int32(5)},
+		},
+		"Object": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$type", "object")),
+			expected: []any{int32(6)},
+		},
+		"Deprecated": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$type", types.MustNewArray("javascript", "minKey"))),
+			expected: []any{int32(8), int32(9)},
+		},
+		"UnknownAlias": {
+			filter: types.MustNewDocument("v", types.MustNewDocument("$type", "foo")),
+			code:   common.ErrBadValue,
+		},
+		"UnknownCode": {
+			filter: types.MustNewDocument("v", types.MustNewDocument("$type", int32(42))),
+			code:   common.ErrBadValue,
+		},
+		"WrongType": {
+			filter: types.MustNewDocument("v", types.MustNewDocument("$type", true)),
+			code:   common.ErrTypeMismatch,
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ids, code := findIDs(ctx, t, handler, db, collection, tc.filter)
+			assert.Equal(t, tc.code, code)
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+func TestArrayOperators(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+
+	db, collection := setupCollection(ctx, t, handler, pool,
+		types.MustNewDocument("_id", int32(1), "items", types.MustNewArray(
+			types.MustNewDocument("product", "xyz", "qty", int32(5)),
+			types.MustNewDocument("product", "abc", "qty", int32(1)),
+		), "scores", types.MustNewArray(int32(82), int32(90)), "tags", types.MustNewArray("a", "b", "c")),
+		types.MustNewDocument("_id", int32(2), "items", types.MustNewArray(
+			types.MustNewDocument("product", "xyz", "qty", int32(1)),
+			types.MustNewDocument("product", "abc", "qty", int32(10)),
+		), "scores", types.MustNewArray(int32(75), int32(88)), "tags", types.MustNewArray("b")),
+		types.MustNewDocument("_id", int32(3), "items", types.MustNewArray(), "scores", int32(84), "tags", "a"),
+	)
+
+	for name, tc := range map[string]struct {
+		filter   *types.Document
+		expected []any
+		code     common.ErrorCode
+	}{
+		"ElemMatchDocument": {
+			filter: types.MustNewDocument("items", types.MustNewDocument("$elemMatch", types.MustNewDocument(
+				"product", "xyz",
+				"qty", types.MustNewDocument("$gte", int32(5)),
+			))),
+			expected: []any{int32(1)},
+		},
+		"DocumentWithoutElemMatch": {
+			// conditions may be satisfied by different elements
+			filter: types.MustNewDocument(
+				"items.product", "xyz",
+				"items.qty", types.MustNewDocument("$gte", int32(5)),
+			),
+			expected: []any{int32(1), int32(2)},
+		},
+		"ElemMatchOperators": {
+			filter: types.MustNewDocument("scores", types.MustNewDocument("$elemMatch", types.MustNewDocument(
+				"$gte", int32(80),
+				"$lt", int32(85),
+			))),
+			expected: []any{int32(1)},
+		},
+		"ElemMatchScalar": {
+			// $elemMatch does not match non-arrays
+			filter: types.MustNewDocument("scores", types.MustNewDocument("$elemMatch", types.MustNewDocument(
+				"$eq", int32(84),
+			))),
+			expected: []any{},
+		},
+		"ElemMatchOr": {
+			filter: types.MustNewDocument("items", types.MustNewDocument("$elemMatch", types.MustNewDocument(
+				"$or", types.MustNewArray(
+					types.MustNewDocument("qty", int32(10)),
+					types.MustNewDocument("product", "nope"),
+				),
+			))),
+			expected: []any{int32(2)},
+		},
+		"Size": {
+			filter:   types.MustNewDocument("tags", types.MustNewDocument("$size", int32(3))),
+			expected: []any{int32(1)},
+		},
+		"SizeZero": {
+			filter:   types.MustNewDocument("items", types.MustNewDocument("$size", float64(0))),
+			expected: []any{int32(3)},
+		},
+		"All": {
+			filter:   types.MustNewDocument("tags", types.MustNewDocument("$all", types.MustNewArray("b", "a"))),
+			expected: []any{int32(1)},
+		},
+		"AllScalar": {
+			filter:   types.MustNewDocument("tags", types.MustNewDocument("$all", types.MustNewArray("a"))),
+			expected: []any{int32(1), int32(3)},
+		},
+		"AllEmpty": {
+			filter:   types.MustNewDocument("tags", types.MustNewDocument("$all", types.MustNewArray())),
+			expected: []any{},
+		},
+		"AllElemMatch": {
+			filter: types.MustNewDocument("items", types.MustNewDocument("$all", types.MustNewArray(
+				types.MustNewDocument("$elemMatch", types.MustNewDocument("qty", int32(1))),
+				types.MustNewDocument("$elemMatch", types.MustNewDocument("product", "xyz", "qty", int32(5))),
+			))),
+			expected: []any{int32(1)},
+		},
+		"SizeNegative": {
+			filter: types.MustNewDocument("tags", types.MustNewDocument("$size", int32(-1))),
+			code:   common.ErrBadValue,
+		},
+		"SizeFraction": {
+			filter: types.MustNewDocument("tags", types.MustNewDocument("$size", float64(1.5))),
+			code:   common.ErrBadValue,
+		},
+		"ElemMatchNotDocument": {
+			filter: types.MustNewDocument("tags", types.MustNewDocument("$elemMatch", int32(1))),
+			code:   common.ErrBadValue,
+		},
+		"AllNotArray": {
+			filter: types.MustNewDocument("tags", types.MustNewDocument("$all", "a")),
+			code:   common.ErrBadValue,
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ids, code := findIDs(ctx, t, handler, db, collection, tc.filter)
+			assert.Equal(t, tc.code, code)
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+func TestModBitsFilter(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+
+	db, collection := setupCollection(ctx, t, handler, pool,
+		types.MustNewDocument("_id", int32(1), "v", int32(13)),                           // 0b1101
+		types.MustNewDocument("_id", int32(2), "v", int64(-4)),                           // ...11100
+		types.MustNewDocument("_id", int32(3), "v", float64(10.5)),                       // not an integer
+		types.MustNewDocument("_id", int32(4), "v", float64(6)),                          // 0b0110
+		types.MustNewDocument("_id", int32(5), "v", "13"),                                // not a number
+		types.MustNewDocument("_id", int32(6), "v", types.Binary{B: []byte{0x05, 0x80}}), // bits 0, 2, 15
+	)
+
+	for name, tc := range map[string]struct {
+		filter   *types.Document
+		expected []any
+		code     common.ErrorCode
+	}{
+		"Mod": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$mod", types.MustNewArray(int32(4), int32(1)))),
+			expected: []any{int32(1)},
+		},
+		"ModNegative": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$mod", types.MustNewArray(int32(3), int32(-1)))),
+			expected: []any{int32(2)},
+		},
+		"ModTruncated": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$mod", types.MustNewArray(float64(5.9), int64(0)))),
+			expected: []any{int32(3)},
+		},
+		"AllSetNumber": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$bitsAllSet", int32(5))),
+			expected: []any{int32(1), int32(6)},
+		},
+		"AllSetPositions": {
+			// high bits of negative numbers are set because of sign extension
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$bitsAllSet", types.MustNewArray(int32(2), int32(100)))),
+			expected: []any{int32(2)},
+		},
+		"AnySetBinData": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$bitsAnySet", types.Binary{B: []byte{0x02}})),
+			expected: []any{int32(4)},
+		},
+		"AllClear": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$bitsAllClear", types.MustNewArray(int32(0), int32(1)))),
+			expected: []any{int32(2)},
+		},
+		"AnyClear": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$bitsAnyClear", types.MustNewArray(int32(0), int32(15)))),
+			expected: []any{int32(1), int32(2), int32(4)},
+		},
+		"ModZero": {
+			filter: types.MustNewDocument("v", types.MustNewDocument("$mod", types.MustNewArray(int32(0), int32(1)))),
+			code:   common.ErrBadValue,
+		},
+		"ModMalformed": {
+			filter: types.MustNewDocument("v", types.MustNewDocument("$mod", types.MustNewArray(int32(2)))),
+			code:   common.ErrBadValue,
+		},
+		"BitsNegative": {
+			filter: types.MustNewDocument("v", types.MustNewDocument("$bitsAllSet", int32(-1))),
+			code:   common.ErrBadValue,
+		},
+		"BitsString": {
+			filter: types.MustNewDocument("v", types.MustNewDocument("$bitsAllSet", "1")),
+			code:   common.ErrBadValue,
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ids, code := findIDs(ctx, t, handler, db, collection, tc.filter)
+			assert.Equal(t, tc.code, code)
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+func TestScalarComparisons(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+
+	date := func(year int) time.Time {
+		return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	db, collection := setupCollection(ctx, t, handler, pool,
+		types.MustNewDocument("_id", int32(1), "v", int32(1)),
+		types.MustNewDocument("_id", int32(2), "v", int64(1)),
+		types.MustNewDocument("_id", int32(3), "v", float64(1)),
+		types.MustNewDocument("_id", int32(4), "v", must.NotFail(types.ParseDecimal128("1.0"))),
+		types.MustNewDocument("_id", int32(5), "v", float64(2.5)),
+		types.MustNewDocument("_id", int32(6), "v", math.NaN()),
+		types.MustNewDocument("_id", int32(7), "v", "1"),
+		types.MustNewDocument("_id", int32(8), "v", true),
+		types.MustNewDocument("_id", int32(9), "v", date(2021)),
+		types.MustNewDocument("_id", int32(10), "v", types.Null),
+		types.MustNewDocument("_id", int32(11)),
+		types.MustNewDocument("_id", int32(12), "v", date(2022)),
+	)
+
+	for name, tc := range map[string]struct {
+		filter   *types.Document
+		expected []any
+		code     common.ErrorCode
+	}{
+		"EqInt32": {
+			filter:   types.MustNewDocument("v", int32(1)),
+			expected: []any{int32(1), int32(2), int32(3), int32(4)},
+		},
+		"EqDouble": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$eq", float64(1))),
+			expected: []any{int32(1), int32(2), int32(3), int32(4)},
+		},
+		"Gt": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$gt", int64(1))),
+			expected: []any{int32(5)},
+		},
+		"Lte": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$lte", must.NotFail(types.ParseDecimal128("1")))),
+			expected: []any{int32(1), int32(2), int32(3), int32(4)},
+		},
+		"EqNaN": {
+			filter:   types.MustNewDocument("v", math.NaN()),
+			expected: []any{int32(6)},
+		},
+		"EqString": {
+			filter:   types.MustNewDocument("v", "1"),
+			expected: []any{int32(7)},
+		},
+		"EqBool": {
+			filter:   types.MustNewDocument("v", true),
+			expected: []any{int32(8)},
+		},
+		"DateLt": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$lt", date(2022))),
+			expected: []any{int32(9)},
+		},
+		"DateGte": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$gte", date(2022))),
+			expected: []any{int32(12)},
+		},
+		"EqNull": {
+			filter:   types.MustNewDocument("v", types.Null),
+			expected: []any{int32(10), int32(11)},
+		},
+		"NeNull": {
+			filter: types.MustNewDocument("v", types.MustNewDocument("$ne", types.Null)),
+			expected: []any{
+				int32(1), int32(2), int32(3), int32(4), int32(5), int32(6), int32(7), int32(8), int32(9), int32(12),
+			},
+		},
+		"In": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$in", types.MustNewArray(float64(2.5), "1", types.Null))),
+			expected: []any{int32(5), int32(7), int32(10), int32(11)},
+		},
+		"Nin": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$nin", types.MustNewArray(int64(1), types.Null))),
+			expected: []any{int32(5), int32(6), int32(7), int32(8), int32(9), int32(12)},
+		},
+		"InNotArray": {
+			filter: types.MustNewDocument("v", types.MustNewDocument("$in", int32(1))),
+			code:   common.ErrBadValue,
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ids, code := findIDs(ctx, t, handler, db, collection, tc.filter)
+			assert.Equal(t, tc.code, code)
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+func TestRegexFilter(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+
+	db, collection := setupCollection(ctx, t, handler, pool,
+		types.MustNewDocument("_id", int32(1), "v", "error: disk full\nwarning: retrying"),
+		types.MustNewDocument("_id", int32(2), "v", "Warning: low memory"),
+		types.MustNewDocument("_id", int32(3), "v", "info: a.b"),
+		types.MustNewDocument("_id", int32(4), "v", int32(42)),
+		types.MustNewDocument("_id", int32(5)),
+	)
+
+	for name, tc := range map[string]struct {
+		filter   *types.Document
+		expected []any
+		code     common.ErrorCode
+	}{
+		"Anchor": {
+			filter:   types.MustNewDocument("v", types.Regex{Pattern: "^warning"}),
+			expected: []any{},
+		},
+		"Multiline": {
+			filter:   types.MustNewDocument("v", types.Regex{Pattern: "^warning", Options: "m"}),
+			expected: []any{int32(1)},
+		},
+		"MultilineCaseless": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$regex", "^warning", "$options", "mi")),
+			expected: []any{int32(1), int32(2)},
+		},
+		"Dot": {
+			filter:   types.MustNewDocument("v", types.Regex{Pattern: "full.warning"}),
+			expected: []any{},
+		},
+		"DotAll": {
+			filter:   types.MustNewDocument("v", types.Regex{Pattern: "full.warning", Options: "s"}),
+			expected: []any{int32(1)},
+		},
+		"Extended": {
+			filter:   types.MustNewDocument("v", types.Regex{Pattern: "a \\. b  # literal dot", Options: "x"}),
+			expected: []any{int32(3)},
+		},
+		"InlineOptions": {
+			filter:   types.MustNewDocument("v", types.Regex{Pattern: "(?i)^WARNING"}),
+			expected: []any{int32(2)},
+		},
+		"WordBoundary": {
+			filter:   types.MustNewDocument("v", types.Regex{Pattern: `\bmemory\b`, Options: "u"}),
+			expected: []any{int32(2)},
+		},
+		"Not": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$not", types.Regex{Pattern: "^w", Options: "i"})),
+			expected: []any{int32(1), int32(3), int32(4), int32(5)},
+		},
+		"NotRegexDocument": {
+			filter:   types.MustNewDocument("v", types.MustNewDocument("$not", types.MustNewDocument("$regex", "info"))),
+			expected: []any{int32(1), int32(2), int32(4), int32(5)},
+		},
+		"NotString": {
+			filter: types.MustNewDocument("v", types.MustNewDocument("$not", "info")),
+			code:   common.ErrBadValue,
+		},
+		"UnknownOption": {
+			filter: types.MustNewDocument("v", types.Regex{Pattern: "info", Options: "z"}),
+			code:   common.ErrBadValue,
+		},
+		"NamedGroup": {
+			filter: types.MustNewDocument("v", types.Regex{Pattern: "(?<level>info)"}),
+			code:   common.ErrBadValue,
+		},
+		"Possessive": {
+			filter: types.MustNewDocument("v", types.Regex{Pattern: "in++fo"}),
+			code:   common.ErrBadValue,
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ids, code := findIDs(ctx, t, handler, db, collection, tc.filter)
+			assert.Equal(t, tc.code, code)
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+// exprDocuments returns documents used by $expr tests.
+func exprDocuments() []*types.Document {
+	return []*types.Document{
+		types.MustNewDocument("_id", int32(1), "spent", int32(150), "budget", float64(100)),
+		types.MustNewDocument("_id", int32(2), "spent", int64(50), "budget", int32(100)),
+		types.MustNewDocument("_id", int32(3), "spent", "a lot", "budget", int32(100)), // strings are greater than numbers
+		types.MustNewDocument("_id", int32(4), "budget", int32(10)),                    // missing values are less than anything
+		types.MustNewDocument("_id", int32(5), "spent", float64(100), "budget", int64(100)),
+	}
+}
+
+func TestExprFilter(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db, collection := setupCollection(ctx, t, handler, pool, exprDocuments()...)
+
+	gtFields := types.MustNewDocument("$gt", types.MustNewArray("$spent", "$budget"))
+
+	for name, tc := range map[string]struct {
+		filter   *types.Document
+		limit    int32
+		expected []any
+		code     common.ErrorCode
+	}{
+		"GtFields": {
+			filter:   types.MustNewDocument("$expr", gtFields),
+			expected: []any{int32(1), int32(3)},
+		},
+		"EqFields": {
+			filter:   types.MustNewDocument("$expr", types.MustNewDocument("$eq", types.MustNewArray("$spent", "$budget"))),
+			expected: []any{int32(5)},
+		},
+		"WithQuery": {
+			filter: types.MustNewDocument(
+				"budget", int32(100),
+				"$expr", types.MustNewDocument("$lt", types.MustNewArray("$spent", "$budget")),
+			),
+			expected: []any{int32(2)},
+		},
+		"Arithmetic": {
+			filter: types.MustNewDocument("$expr", types.MustNewDocument("$gt", types.MustNewArray(
+				types.MustNewDocument("$multiply", types.MustNewArray("$budget", float64(1.2))),
+				"$spent",
+			))),
+			expected: []any{int32(2), int32(4), int32(5)},
+		},
+		"Cond": {
+			filter: types.MustNewDocument("$expr", types.MustNewDocument("$eq", types.MustNewArray(
+				types.MustNewDocument("$cond", types.MustNewArray(
+					types.MustNewDocument("$gte", types.MustNewArray("$spent", int32(100))), "over", "under",
+				)),
+				"over",
+			))),
+			expected: []any{int32(1), int32(3), int32(5)},
+		},
+		"Limit": {
+			filter:   types.MustNewDocument("$expr", gtFields),
+			limit:    1,
+			expected: []any{int32(1)},
+		},
+		"TypeMismatch": {
+			filter: types.MustNewDocument("$expr", types.MustNewDocument("$add", types.MustNewArray("$spent", int32(1)))),
+			code:   common.ErrTypeMismatch,
+		},
+		"DivideByZero": {
+			filter: types.MustNewDocument("$expr", types.MustNewDocument("$divide", types.MustNewArray("$budget", int32(0)))),
+			code:   common.ErrBadValue,
+		},
+		"ElemMatch": {
+			filter: types.MustNewDocument("v", types.MustNewDocument("$elemMatch", types.MustNewDocument("$expr", gtFields))),
+			code:   common.ErrBadValue,
+		},
+		"UnknownTopLevel": {
+			filter: types.MustNewDocument("$foo", types.MustNewArray()),
+			code:   common.ErrBadValue,
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ids, code := findIDs(ctx, t, handler, db, collection, tc.filter, "limit", tc.limit)
+			assert.Equal(t, tc.code, code)
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+func TestExprWrite(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db, collection := setupCollection(ctx, t, handler, pool, exprDocuments()...)
+
+	gtFields := types.MustNewDocument("$expr", types.MustNewDocument("$gt", types.MustNewArray("$spent", "$budget")))
+
+	actual := handle(ctx, t, handler, types.MustNewDocument(
+		"count", collection,
+		"query", gtFields,
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(2), "ok", float64(1)), actual)
+
+	actual = handle(ctx, t, handler, types.MustNewDocument(
+		"update", collection,
+		"updates", types.MustNewArray(types.MustNewDocument(
+			"q", gtFields,
+			"u", types.MustNewDocument("$set", types.MustNewDocument("over", true)),
+			"multi", true,
+		)),
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(2), "nModified", int32(2), "ok", float64(1)), actual)
+
+	actual = handle(ctx, t, handler, types.MustNewDocument(
+		"count", collection,
+		"query", types.MustNewDocument("over", true),
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(2), "ok", float64(1)), actual)
+
+	actual = handle(ctx, t, handler, types.MustNewDocument(
+		"delete", collection,
+		"deletes", types.MustNewArray(types.MustNewDocument(
+			"q", gtFields,
+			"limit", int32(1),
+		)),
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(1), "ok", float64(1)), actual)
+
+	actual = handle(ctx, t, handler, types.MustNewDocument(
+		"delete", collection,
+		"deletes", types.MustNewArray(types.MustNewDocument(
+			"q", types.MustNewDocument("$expr", types.MustNewDocument("$lte", types.MustNewArray("$spent", "$budget"))),
+			"limit", int32(0),
+		)),
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(3), "ok", float64(1)), actual)
+
+	actual = handle(ctx, t, handler, types.MustNewDocument(
+		"count", collection,
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(1), "ok", float64(1)), actual)
+}
+
+func TestFilterFallback(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+
+	db, collection := setupCollection(ctx, t, handler, pool,
+		types.MustNewDocument("_id", int32(1), "v", int32(10), "d", types.MustNewDocument("x", int32(1))),
+		types.MustNewDocument("_id", int32(2), "v", int32(20), "d", types.MustNewDocument("x", int32(2))),
+		types.MustNewDocument("_id", int32(3), "v", int32(30), "d", types.MustNewArray(types.MustNewDocument("x", int32(1)))),
+		types.MustNewDocument("_id", int32(4), "v", int32(40), "d", "x"),
+	)
+
+	// $expr inside $or and comparisons with documents can't be translated to SQL
+	orExpr := types.MustNewDocument("$or", types.MustNewArray(
+		types.MustNewDocument("$expr", types.MustNewDocument("$gt", types.MustNewArray("$v", int32(25)))),
+		types.MustNewDocument("_id", int32(1)),
+	))
+
+	for name, tc := range map[string]struct {
+		filter   *types.Document
+		limit    int32
+		expected []any
+		code     common.ErrorCode
+	}{
+		"OrExpr": {
+			filter:   orExpr,
+			expected: []any{int32(1), int32(3), int32(4)},
+		},
+		"DocumentEq": {
+			filter:   types.MustNewDocument("d", types.MustNewDocument("x", int32(1))),
+			expected: []any{int32(1), int32(3)},
+		},
+		"DocumentGt": {
+			filter:   types.MustNewDocument("d", types.MustNewDocument("$gt", types.MustNewDocument("x", int32(1)))),
+			expected: []any{int32(2)},
+		},
+		"WithQuery": {
+			filter: types.MustNewDocument(
+				"v", types.MustNewDocument("$lt", int32(40)),
+				"d", types.MustNewDocument("$gte", types.MustNewDocument("x", int32(1))),
+			),
+			expected: []any{int32(1), int32(2), int32(3)},
+		},
+		"NotDocument": {
+			filter: types.MustNewDocument("d", types.MustNewDocument(
+				"$not", types.MustNewDocument("$lte", types.MustNewDocument("x", int32(1))),
+			)),
+			expected: []any{int32(2), int32(4)},
+		},
+		"Limit": {
+			filter:   orExpr,
+			limit:    2,
+			expected: []any{int32(1), int32(3)},
+		},
+		"UnknownOperator": {
+			filter: types.MustNewDocument("v", types.MustNewDocument("$foo", int32(1))),
+			code:   common.ErrBadValue,
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ids, code := findIDs(ctx, t, handler, db, collection, tc.filter, "limit", tc.limit)
+			assert.Equal(t, tc.code, code)
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+
+	t.Run("Write", func(t *testing.T) {
+		collection := testutil.CreateTable(ctx, t, pool, db)
+		insertDocuments(ctx, t, handler, db, collection,
+			types.MustNewDocument("_id", int32(1), "d", types.MustNewDocument("x", int32(1))),
+			types.MustNewDocument("_id", int32(2), "d", types.MustNewDocument("x", int32(2))),
+			types.MustNewDocument("_id", int32(3), "d", types.MustNewDocument("x", int32(3))),
+		)
+
+		gtDocument := types.MustNewDocument("d", types.MustNewDocument("$gt", types.MustNewDocument("x", int32(1))))
+
+		actual := handle(ctx, t, handler, types.MustNewDocument(
+			"count", collection,
+			"query", gtDocument,
+			"$db", db,
+		))
+		assert.Equal(t, types.MustNewDocument("n", int32(2), "ok", float64(1)), actual)
+
+		actual = handle(ctx, t, handler, types.MustNewDocument(
+			"update", collection,
+			"updates", types.MustNewArray(types.MustNewDocument(
+				"q", gtDocument,
+				"u", types.MustNewDocument("$set", types.MustNewDocument("big", true)),
+				"multi", true,
+			)),
+			"$db", db,
+		))
+		assert.Equal(t, types.MustNewDocument("n", int32(2), "nModified", int32(2), "ok", float64(1)), actual)
+
+		actual = handle(ctx, t, handler, types.MustNewDocument(
+			"delete", collection,
+			"deletes", types.MustNewArray(types.MustNewDocument(
+				"q", gtDocument,
+				"limit", int32(0),
+			)),
+			"$db", db,
+		))
+		assert.Equal(t, types.MustNewDocument("n", int32(2), "ok", float64(1)), actual)
+
+		actual = handle(ctx, t, handler, types.MustNewDocument(
+			"count", collection,
+			"$db", db,
+		))
+		assert.Equal(t, types.MustNewDocument("n", int32(1), "ok", float64(1)), actual)
+	})
+}
+
+// cursorBatch returns ids of the documents of the cursor reply's first or next batch and the cursor id.
+func cursorBatch(t *testing.T, actual *types.Document) ([]any, int64) {
+	t.Helper()
+
+	cursor, ok := actual.Map()["cursor"].(*types.Document)
+	require.True(t, ok, "%v", actual)
+
+	batch, ok := cursor.Map()["firstBatch"].(*types.Array)
+	if !ok {
+		batch = cursor.Map()["nextBatch"].(*types.Array)
+	}
+
+	ids := make([]any, batch.Len())
+	for i := range ids {
+		ids[i] = must.NotFail(batch.Get(i)).(*types.Document).Map()["_id"]
+	}
+
+	return ids, cursor.Map()["id"].(int64)
+}
+
+func TestCursors(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db := testutil.Schema(ctx, t, pool)
+	collection := testutil.CreateTable(ctx, t, pool, db)
+
+	actual := handle(ctx, t, handler, types.MustNewDocument(
+		"insert", collection,
+		"documents", types.MustNewArray(
+			types.MustNewDocument("_id", int32(1)),
+			types.MustNewDocument("_id", int32(2)),
+			types.MustNewDocument("_id", int32(3)),
+			types.MustNewDocument("_id", int32(4)),
+			types.MustNewDocument("_id", int32(5)),
+		),
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(5), "ok", float64(1)), actual)
+
+	getMore := func(t *testing.T, id int64, batchSize int32) *types.Document {
+		t.Helper()
+		return handle(ctx, t, handler, types.MustNewDocument(
+			"getMore", id,
+			"collection", collection,
+			"batchSize", batchSize,
+			"$db", db,
+		))
+	}
+
+	t.Run("GetMore", func(t *testing.T) {
+		t.Parallel()
+
+		ids, id := cursorBatch(t, handle(ctx, t, handler, types.MustNewDocument(
+			"find", collection,
+			"sort", types.MustNewDocument("_id", int32(1)),
+			"batchSize", int32(2),
+			"$db", db,
+		)))
+		assert.Equal(t, []any{int32(1), int32(2)}, ids)
+		require.NotZero(t, id)
+
+		ids, nextID := cursorBatch(t, getMore(t, id, 2))
+		assert.Equal(t, []any{int32(3), int32(4)}, ids)
+		assert.Equal(t, id, nextID)
+
+		ids, nextID = cursorBatch(t, getMore(t, id, 0))
+		assert.Equal(t, []any{int32(5)}, ids)
+		assert.Zero(t, nextID)
+
+		actual := getMore(t, id, 0)
+		assert.Equal(t, int32(common.ErrCursorNotFound), actual.Map()["code"])
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		t.Parallel()
+
+		// without sort, documents are read from the open rows stream
+
+		var all []any
+		ids, id := cursorBatch(t, handle(ctx, t, handler, types.MustNewDocument(
+			"find", collection,
+			"filter", types.MustNewDocument("_id", types.MustNewDocument("$gt", int32(1))),
+			"batchSize", int32(1),
+			"$db", db,
+		)))
+		all = append(all, ids...)
+
+		for id != 0 {
+			ids, id = cursorBatch(t, getMore(t, id, 1))
+			all = append(all, ids...)
+		}
+		assert.ElementsMatch(t, []any{int32(2), int32(3), int32(4), int32(5)}, all)
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		t.Parallel()
+
+		ids, id := cursorBatch(t, handle(ctx, t, handler, types.MustNewDocument(
+			"find", collection,
+			"sort", types.MustNewDocument("_id", int32(-1)),
+			"limit", int32(3),
+			"batchSize", int32(2),
+			"$db", db,
+		)))
+		assert.Equal(t, []any{int32(5), int32(4)}, ids)
+		require.NotZero(t, id)
+
+		ids, id = cursorBatch(t, getMore(t, id, 0))
+		assert.Equal(t, []any{int32(3)}, ids)
+		assert.Zero(t, id)
+	})
+
+	t.Run("EmptyFirstBatch", func(t *testing.T) {
+		t.Parallel()
+
+		ids, id := cursorBatch(t, handle(ctx, t, handler, types.MustNewDocument(
+			"find", collection,
+			"sort", types.MustNewDocument("_id", int32(1)),
+			"batchSize", int32(0),
+			"$db", db,
+		)))
+		assert.Empty(t, ids)
+		require.NotZero(t, id)
+
+		ids, id = cursorBatch(t, getMore(t, id, 0))
+		assert.Equal(t, []any{int32(1), int32(2), int32(3), int32(4), int32(5)}, ids)
+		assert.Zero(t, id)
+	})
+
+	t.Run("SingleBatch", func(t *testing.T) {
+		t.Parallel()
+
+		ids, id := cursorBatch(t, handle(ctx, t, handler, types.MustNewDocument(
+			"find", collection,
+			"sort", types.MustNewDocument("_id", int32(1)),
+			"batchSize", int32(2),
+			"singleBatch", true,
+			"$db", db,
+		)))
+		assert.Equal(t, []any{int32(1), int32(2)}, ids)
+		assert.Zero(t, id)
+	})
+
+	t.Run("KillCursors", func(t *testing.T) {
+		t.Parallel()
+
+		_, id := cursorBatch(t, handle(ctx, t, handler, types.MustNewDocument(
+			"find", collection,
+			"sort", types.MustNewDocument("_id", int32(1)),
+			"batchSize", int32(1),
+			"$db", db,
+		)))
+		require.NotZero(t, id)
+
+		actual := getMore(t, id, 1)
+		_, nextID := cursorBatch(t, actual)
+		assert.Equal(t, id, nextID)
+
+		actual = handle(ctx, t, handler, types.MustNewDocument(
+			"killCursors", collection,
+			"cursors", types.MustNewArray(id, int64(42)),
+			"$db", db,
+		))
+		expected := types.MustNewDocument(
+			"cursorsKilled", types.MustNewArray(id),
+			"cursorsNotFound", types.MustNewArray(int64(42)),
+			"cursorsAlive", types.MustNewArray(),
+			"cursorsUnknown", types.MustNewArray(),
+			"ok", float64(1),
+		)
+		assert.Equal(t, expected, actual)
+
+		actual = getMore(t, id, 1)
+		assert.Equal(t, int32(common.ErrCursorNotFound), actual.Map()["code"])
+	})
+
+	t.Run("OtherCollection", func(t *testing.T) {
+		t.Parallel()
+
+		_, id := cursorBatch(t, handle(ctx, t, handler, types.MustNewDocument(
+			"find", collection,
+			"sort", types.MustNewDocument("_id", int32(1)),
+			"batchSize", int32(1),
+			"$db", db,
+		)))
+		require.NotZero(t, id)
+
+		actual := handle(ctx, t, handler, types.MustNewDocument(
+			"getMore", id,
+			"collection", collection+"_other",
+			"$db", db,
+		))
+		assert.Equal(t, int32(common.ErrUnauthorized), actual.Map()["code"])
+	})
+
+	t.Run("NegativeBatchSize", func(t *testing.T) {
+		t.Parallel()
+
+		actual := handle(ctx, t, handler, types.MustNewDocument(
+			"find", collection,
+			"batchSize", int32(-1),
+			"$db", db,
+		))
+		assert.Equal(t, int32(common.ErrBadValue), actual.Map()["code"])
+	})
+
+	t.Run("KillCursorsTypeMismatch", func(t *testing.T) {
+		t.Parallel()
+
+		actual := handle(ctx, t, handler, types.MustNewDocument(
+			"killCursors", int32(1),
+			"cursors", types.MustNewArray(int64(42)),
+			"$db", db,
+		))
+		assert.Equal(t, int32(common.ErrTypeMismatch), actual.Map()["code"])
+	})
+
+	t.Run("SQLTable", func(t *testing.T) {
+		t.Parallel()
+
+		sqlCollection := createSQLTable(ctx, t, pool, db, "_id int4")
+		sql := fmt.Sprintf(`INSERT INTO %s VALUES (1), (2), (3)`, pgx.Identifier{db, sqlCollection}.Sanitize())
+		_, err := pool.Exec(ctx, sql)
+		require.NoError(t, err)
+
+		ids, id := cursorBatch(t, handle(ctx, t, handler, types.MustNewDocument(
+			"find", sqlCollection,
+			"sort", types.MustNewDocument("_id", int32(1)),
+			"batchSize", int32(2),
+			"$db", db,
+		)))
+		assert.Equal(t, []any{int32(1), int32(2)}, ids)
+		require.NotZero(t, id)
+
+		ids, id = cursorBatch(t, handle(ctx, t, handler, types.MustNewDocument(
+			"getMore", id,
+			"collection", sqlCollection,
+			"$db", db,
+		)))
+		assert.Equal(t, []any{int32(3)}, ids)
+		assert.Zero(t, id)
+
+		ids, id = cursorBatch(t, handle(ctx, t, handler, types.MustNewDocument(
+			"find", sqlCollection,
+			"sort", types.MustNewDocument("_id", int32(1)),
+			"batchSize", int32(1),
+			"singleBatch", true,
+			"$db", db,
+		)))
+		assert.Equal(t, []any{int32(1)}, ids)
+		assert.Zero(t, id)
+	})
+}
+
+func TestCursorTimeout(t *testing.T) {
+	t.Parallel()
+	ctx, _, pool := setup(t, nil)
+	db := testutil.Schema(ctx, t, pool)
+	collection := testutil.CreateTable(ctx, t, pool, db)
+
+	handler := newHandler(ctx, t, pool, 100*time.Millisecond)
+
+	actual := handle(ctx, t, handler, types.MustNewDocument(
+		"insert", collection,
+		"documents", types.MustNewArray(
+			types.MustNewDocument("_id", int32(1)),
+			types.MustNewDocument("_id", int32(2)),
+		),
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(2), "ok", float64(1)), actual)
+
+	find := types.MustNewDocument(
+		"find", collection,
+		"batchSize", int32(1),
+		"$db", db,
+	)
+	_, idleID := cursorBatch(t, handle(ctx, t, handler, find))
+	require.NotZero(t, idleID)
+
+	must.NoError(find.Set("noCursorTimeout", true))
+	_, noTimeoutID := cursorBatch(t, handle(ctx, t, handler, find))
+	require.NotZero(t, noTimeoutID)
+
+	time.Sleep(500 * time.Millisecond)
+
+	actual = handle(ctx, t, handler, types.MustNewDocument(
+		"getMore", idleID,
+		"collection", collection,
+		"$db", db,
+	))
+	assert.Equal(t, int32(common.ErrCursorNotFound), actual.Map()["code"])
+
+	ids, id := cursorBatch(t, handle(ctx, t, handler, types.MustNewDocument(
+		"getMore", noTimeoutID,
+		"collection", collection,
+		"$db", db,
+	)))
+	assert.Len(t, ids, 1)
+	assert.Zero(t, id)
+}
+
+func TestCursorsMoreThanPool(t *testing.T) {
+	t.Parallel()
+	ctx, handler, pool := setup(t, nil)
+	db := testutil.Schema(ctx, t, pool)
+	collection := testutil.CreateTable(ctx, t, pool, db)
+
+	actual := handle(ctx, t, handler, types.MustNewDocument(
+		"insert", collection,
+		"documents", types.MustNewArray(
+			types.MustNewDocument("_id", int32(1)),
+			types.MustNewDocument("_id", int32(2)),
+		),
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(2), "ok", float64(1)), actual)
+
+	// open more not exhausted cursors than there are pool connections
+	n := int(pool.Config().MaxConns) + 2
+	cursorIDs := make([]int64, n)
+	for i := range cursorIDs {
+		var ids []any
+		ids, cursorIDs[i] = cursorBatch(t, handle(ctx, t, handler, types.MustNewDocument(
+			"find", collection,
+			"batchSize", int32(1),
+			"$db", db,
+		)))
+		assert.Len(t, ids, 1)
+		require.NotZero(t, cursorIDs[i])
+	}
+
+	// other requests should not wait for a connection forever
+	countCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	actual = handle(countCtx, t, handler, types.MustNewDocument(
+		"count", collection,
+		"$db", db,
+	))
+	assert.Equal(t, types.MustNewDocument("n", int32(2), "ok", float64(1)), actual)
+
+	for _, id := range cursorIDs {
+		ids, nextID := cursorBatch(t, handle(ctx, t, handler, types.MustNewDocument(
+			"getMore", id,
+			"collection", collection,
+			"$db", db,
+		)))
+		assert.Len(t, ids, 1)
+		assert.Zero(t, nextID)
+	}
+}
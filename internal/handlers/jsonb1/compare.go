@@ -0,0 +1,178 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonb1
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/FerretDB/FerretDB/internal/fjson"
+	"github.com/FerretDB/FerretDB/internal/handlers/common"
+	"github.com/FerretDB/FerretDB/internal/pg"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+)
+
+// numericArg returns the number as a string accepted by PostgreSQL numeric type,
+// and true if it is NaN.
+func numericArg(value any) (string, bool) {
+	switch value := value.(type) {
+	case int32:
+		return strconv.FormatInt(int64(value), 10), false
+	case int64:
+		return strconv.FormatInt(value, 10), false
+	case float64:
+		switch {
+		case math.IsNaN(value):
+			return "NaN", true
+		case math.IsInf(value, 1):
+			return "Infinity", false
+		case math.IsInf(value, -1):
+			return "-Infinity", false
+		default:
+			return strconv.FormatFloat(value, 'g', -1, 64), false
+		}
+	case types.Decimal128:
+		return value.String(), value.IsNaN()
+	default:
+		panic(fmt.Sprintf("unexpected type %T", value))
+	}
+}
+
+// compareExpr returns SQL condition that compares the given jsonb value with the given value
+// using SQL comparison operator op ("=", "<", "<=", ">" or ">=") and MongoDB semantics:
+//   - values of different types never match, except that numbers of all types are compared numerically;
+//   - NaN equals NaN, but is neither less nor greater than anything;
+//   - dates are compared as instants, strings and ObjectIDs are compared bytewise;
+//   - null matches null, undefined and missing values.
+//
+// Documents, arrays and other composite or rarely used types can only be compared for equality.
+func compareExpr(v, op string, value any, p *pg.Placeholder) (sql string, args []any, err error) {
+	switch value := value.(type) {
+	case int32, int64, float64, types.Decimal128:
+		n, isNaN := numericArg(value)
+		if isNaN {
+			if op == "<" || op == ">" {
+				sql = "FALSE"
+				return
+			}
+
+			sql = numericExpr(v) + " = 'NaN'"
+			return
+		}
+
+		sql = "NULLIF(" + numericExpr(v) + ", 'NaN') " + op + " " + p.Next() + "::numeric"
+		args = []any{n}
+
+	case string:
+		sql = "(CASE WHEN jsonb_typeof(" + v + ") = 'string' THEN " + v + " #>> '{}' END) COLLATE \"C\" " +
+			op + " " + p.Next() + "::text"
+		args = []any{value}
+
+	case bool:
+		sql = "(CASE WHEN jsonb_typeof(" + v + ") = 'boolean' THEN (" + v + ")::boolean END) " + op + " " + p.Next() + "::boolean"
+		args = []any{value}
+
+	case time.Time:
+		sql = "(" + v + "->>'$d')::bigint " + op + " " + p.Next() + "::bigint"
+		args = []any{value.UnixMilli()}
+
+	case types.ObjectID:
+		sql = "(" + v + "->>'$o') COLLATE \"C\" " + op + " " + p.Next() + "::text"
+		args = []any{hex.EncodeToString(value[:])}
+
+	case types.Timestamp:
+		sql = "(" + v + "->>'$t')::numeric " + op + " " + p.Next() + "::numeric"
+		args = []any{strconv.FormatUint(uint64(value), 10)}
+
+	case types.Binary:
+		// compare length, then subtype, then bytes
+		b := "decode(" + v + "->>'$b', 'base64')"
+		sql = "(length(" + b + "), (" + v + "->>'s')::int4, " + b + ") " + op +
+			" (" + p.Next() + "::int4, " + p.Next() + "::int4, " + p.Next() + "::bytea)"
+		args = []any{int32(len(value.B)), int32(value.Subtype), value.B}
+
+	case types.NullType:
+		if op == "<" || op == ">" {
+			sql = "FALSE"
+			return
+		}
+
+		sql = "(" + v + " IS NULL OR jsonb_typeof(" + v + ") = 'null' OR " + typeExpr("undefined", v) + ")"
+
+	default:
+		if op != "=" {
+			err = common.NewError(common.ErrNotImplemented, fmt.Errorf("comparison of %T values is not implemented", value))
+			return
+		}
+
+		var b []byte
+		if b, err = fjson.Marshal(value); err != nil {
+			err = lazyerrors.Errorf("compareExpr: %w", err)
+			return
+		}
+
+		sql = v + " = " + p.Next() + "::jsonb"
+		args = []any{string(b)}
+	}
+
+	return
+}
+
+// inExpr returns SQL condition that checks that the given jsonb value equals any of array values.
+//
+// Regular expressions in the array match strings.
+func inExpr(v string, arr *types.Array, p *pg.Placeholder) (sql string, args []any, err error) {
+	if arr.Len() == 0 {
+		sql = "FALSE"
+		return
+	}
+
+	for i := 0; i < arr.Len(); i++ {
+		var el any
+		if el, err = arr.Get(i); err != nil {
+			err = lazyerrors.Errorf("inExpr: %w", err)
+			return
+		}
+
+		var elSQL string
+		var elArgs []any
+		if re, ok := el.(types.Regex); ok {
+			elSQL = "jsonb_typeof(" + v + ") = 'string' AND " + v + " #>> '{}' ~"
+			var reSQL string
+			reSQL, elArgs, err = scalar(re, p)
+			elSQL += " " + reSQL
+		} else {
+			elSQL, elArgs, err = compareExpr(v, "=", el, p)
+		}
+
+		if err != nil {
+			err = lazyerrors.Errorf("inExpr: %w", err)
+			return
+		}
+
+		if i != 0 {
+			sql += " OR "
+		}
+		sql += "(" + elSQL + ")"
+		args = append(args, elArgs...)
+	}
+
+	sql = "(" + sql + ")"
+	return
+}
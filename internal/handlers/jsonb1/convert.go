@@ -22,7 +22,10 @@ import (
 	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
 )
 
-func nextRow(rows pgx.Rows) (*types.Document, error) {
+// nextRow returns the document from the first column of the next row, or nil if there are no more rows.
+//
+// Other columns are scanned into dest.
+func nextRow(rows pgx.Rows, dest ...any) (*types.Document, error) {
 	if !rows.Next() {
 		err := rows.Err()
 		if err != nil {
@@ -32,7 +35,7 @@ func nextRow(rows pgx.Rows) (*types.Document, error) {
 	}
 
 	var b []byte
-	if err := rows.Scan(&b); err != nil {
+	if err := rows.Scan(append([]any{&b}, dest...)...); err != nil {
 		return nil, lazyerrors.Error(err)
 	}
 
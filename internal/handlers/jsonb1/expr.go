@@ -0,0 +1,287 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonb1
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/FerretDB/FerretDB/internal/fjson"
+	"github.com/FerretDB/FerretDB/internal/pg"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+)
+
+// exprKind represents the SQL type of translated aggregation expression.
+type exprKind int
+
+const (
+	exprJSONB   exprKind = iota // fjson-encoded jsonb value; NULL for missing fields
+	exprNumeric                 // numeric value
+	exprBoolean                 // boolean value
+)
+
+// exprValue represents aggregation expression translated to SQL.
+type exprValue struct {
+	sql  string
+	kind exprKind
+}
+
+// unknownBoolean is used for expressions that can't be translated to SQL.
+var unknownBoolean = exprValue{sql: "NULL::boolean", kind: exprBoolean}
+
+// exprTranslator translates aggregation expressions to SQL.
+type exprTranslator struct {
+	p       *pg.Placeholder
+	aliases int
+}
+
+// exprCondition returns SQL condition for {$expr: expr} that is false only for documents
+// that don't match it, or empty string if nothing could be translated.
+//
+// The condition is a pre-filter: it may be true or NULL for documents that don't match,
+// so fetched documents should be checked with common.EvalExpr.
+// Translated expressions produce NULL instead of values they can't compute exactly,
+// and SQL three-valued logic keeps that property for $and, $or and $not.
+func exprCondition(expr any, p *pg.Placeholder) (sql string, args []any) {
+	t := exprTranslator{p: p}
+	v, args := t.boolean(expr)
+	if v == unknownBoolean {
+		return "", nil
+	}
+
+	return "(" + v.sql + ") IS NOT FALSE", args
+}
+
+// alias returns a new unique SQL alias.
+func (t *exprTranslator) alias() string {
+	t.aliases++
+	return fmt.Sprintf("ex%d", t.aliases)
+}
+
+// boolean translates aggregation expression that is used as a condition.
+func (t *exprTranslator) boolean(expr any) (exprValue, []any) {
+	saved := *t.p
+	v, args, ok := t.translate(expr)
+	if !ok || v.kind != exprBoolean {
+		// drop placeholders of the partially translated expression
+		*t.p = saved
+		return unknownBoolean, nil
+	}
+
+	return v, args
+}
+
+// translate translates aggregation expression to SQL.
+// It returns false if the expression can't be translated; placeholders may be consumed in that case.
+func (t *exprTranslator) translate(expr any) (v exprValue, args []any, ok bool) {
+	switch expr := expr.(type) {
+	case string:
+		if strings.HasPrefix(expr, "$") {
+			return t.fieldPath(expr)
+		}
+		return t.literal(expr)
+
+	case *types.Document:
+		if expr.Len() != 1 || !strings.HasPrefix(expr.Keys()[0], "$") {
+			return
+		}
+
+		op := expr.Keys()[0]
+		arg := must.NotFail(expr.Get(op))
+
+		switch op {
+		case "$literal":
+			return t.literal(arg)
+		case "$eq", "$ne", "$gt", "$gte", "$lt", "$lte":
+			return t.comparison(op, arg)
+		case "$and", "$or", "$not":
+			return t.logic(op, arg)
+		default:
+			return
+		}
+
+	default:
+		return t.literal(expr)
+	}
+}
+
+// literal translates literal value.
+func (t *exprTranslator) literal(value any) (v exprValue, args []any, ok bool) {
+	switch value := value.(type) {
+	case int32, int64, float64, types.Decimal128:
+		n, isNaN := numericArg(value)
+		if isNaN {
+			return
+		}
+		return exprValue{sql: t.p.Next() + "::numeric", kind: exprNumeric}, []any{n}, true
+	case string:
+		b := must.NotFail(fjson.Marshal(value))
+		return exprValue{sql: t.p.Next() + "::jsonb", kind: exprJSONB}, []any{string(b)}, true
+	case bool:
+		return exprValue{sql: t.p.Next() + "::boolean", kind: exprBoolean}, []any{value}, true
+	default:
+		return
+	}
+}
+
+// fieldPath translates field path like "$a.b".
+//
+// Only paths through embedded documents are translated; arrays and other values in the middle
+// of the path produce NULL, as well as missing fields.
+func (t *exprTranslator) fieldPath(path string) (v exprValue, args []any, ok bool) {
+	if strings.HasPrefix(path, "$$") {
+		return
+	}
+
+	keys := strings.Split(path[1:], ".")
+	for _, key := range keys {
+		if key == "" || strings.HasPrefix(key, "$") {
+			return
+		}
+	}
+
+	v = exprValue{sql: "_jsonb", kind: exprJSONB}
+	for _, key := range keys {
+		v.sql = "(CASE WHEN " + v.sql + " ? '$k' THEN " + v.sql + " -> " + t.p.Next() + "::text END)"
+		args = append(args, key)
+	}
+
+	ok = true
+	return
+}
+
+// numericSide returns SQL numeric expression for the value, or NULL.
+func numericSide(v exprValue) string {
+	switch v.kind {
+	case exprNumeric:
+		return "NULLIF(" + v.sql + ", 'NaN')"
+	case exprJSONB:
+		return "NULLIF(" + numericExpr(v.sql) + ", 'NaN')"
+	default:
+		return "NULL"
+	}
+}
+
+// stringSide returns SQL text expression for the value, or NULL.
+func stringSide(v exprValue) string {
+	if v.kind != exprJSONB {
+		return "NULL"
+	}
+	return "(CASE WHEN jsonb_typeof(" + v.sql + ") = 'string' THEN " + v.sql + " #>> '{}' END) COLLATE \"C\""
+}
+
+// booleanSide returns SQL boolean expression for the value, or NULL.
+func booleanSide(v exprValue) string {
+	switch v.kind {
+	case exprBoolean:
+		return v.sql
+	case exprJSONB:
+		return "(CASE WHEN jsonb_typeof(" + v.sql + ") = 'boolean' THEN (" + v.sql + ")::boolean END)"
+	default:
+		return "NULL"
+	}
+}
+
+// comparison translates {$op: [a, b]} comparison.
+//
+// Values are compared only if they are both numbers, strings or booleans; otherwise, the result is NULL.
+func (t *exprTranslator) comparison(op string, arg any) (v exprValue, args []any, ok bool) {
+	arr, isArray := arg.(*types.Array)
+	if !isArray || arr.Len() != 2 {
+		return
+	}
+
+	saved := *t.p
+	a, aArgs, aOK := t.translate(must.NotFail(arr.Get(0)))
+	b, bArgs, bOK := t.translate(must.NotFail(arr.Get(1)))
+	if !aOK || !bOK {
+		// drop placeholders of partially translated operands
+		*t.p = saved
+		return unknownBoolean, nil, true
+	}
+	args = append(aArgs, bArgs...)
+
+	sqlOp := comparisonOperators[op]
+	if op == "$ne" {
+		sqlOp = "<>"
+	}
+
+	// operands are evaluated once in FROM clause of the scalar subquery and then referenced by aliases
+	aAlias, bAlias := t.alias(), t.alias()
+	from := " FROM (SELECT " + a.sql + ") " + aAlias + "(v), (SELECT " + b.sql + ") " + bAlias + "(v)"
+	a.sql, b.sql = aAlias+".v", bAlias+".v"
+
+	var branches []string
+	for _, side := range []func(exprValue) string{numericSide, stringSide, booleanSide} {
+		as, bs := side(a), side(b)
+		if as == "NULL" || bs == "NULL" {
+			continue
+		}
+		branches = append(branches, " WHEN "+as+" IS NOT NULL AND "+bs+" IS NOT NULL THEN "+as+" "+sqlOp+" "+bs)
+	}
+
+	if len(branches) == 0 {
+		*t.p = saved
+		return unknownBoolean, nil, true
+	}
+
+	v = exprValue{sql: "(SELECT CASE" + strings.Join(branches, "") + " END" + from + ")", kind: exprBoolean}
+	ok = true
+	return
+}
+
+// logic translates $and, $or and $not.
+func (t *exprTranslator) logic(op string, arg any) (v exprValue, args []any, ok bool) {
+	operands := []any{arg}
+	if arr, isArray := arg.(*types.Array); isArray {
+		operands = make([]any, arr.Len())
+		for i := range operands {
+			operands[i] = must.NotFail(arr.Get(i))
+		}
+	}
+
+	if op == "$not" {
+		if len(operands) != 1 {
+			return
+		}
+		operand, operandArgs := t.boolean(operands[0])
+		return exprValue{sql: "NOT (" + operand.sql + ")", kind: exprBoolean}, operandArgs, true
+	}
+
+	if len(operands) == 0 {
+		empty := "FALSE"
+		if op == "$and" {
+			empty = "TRUE"
+		}
+		return exprValue{sql: empty, kind: exprBoolean}, nil, true
+	}
+
+	sqls := make([]string, len(operands))
+	for i, operand := range operands {
+		operandSQL, operandArgs := t.boolean(operand)
+		sqls[i] = "(" + operandSQL.sql + ")"
+		args = append(args, operandArgs...)
+	}
+
+	join := " AND "
+	if op == "$or" {
+		join = " OR "
+	}
+
+	v = exprValue{sql: "(" + strings.Join(sqls, join) + ")", kind: exprBoolean}
+	ok = true
+	return
+}
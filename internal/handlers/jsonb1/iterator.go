@@ -0,0 +1,178 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonb1
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/FerretDB/FerretDB/internal/handlers/common"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+)
+
+// findIterator returns documents selected by find command, implementing common.CursorIterator.
+//
+// Documents are read from the open rows stream and checked with residual filter (see where),
+// or taken from already fetched documents. Limit and projection are applied to them.
+type findIterator struct {
+	rows     pgx.Rows
+	cancel   context.CancelFunc // cancels rows' query
+	release  func()             // releases the connection reserved by pg.Pool.TryAcquireStream, if set
+	sortable *bool              // if set, rows have the second column that is true if they are sorted in SQL
+	peeked   *types.Document    // the document read by sortedInSQL, if any
+
+	residual   *types.Document
+	projection *types.Document
+	limit      int32 // 0 means no limit
+
+	fetched  []*types.Document // all documents if fetch was called
+	returned int32
+}
+
+// nextRow returns the document from the next row, or nil if there are no more rows.
+func (it *findIterator) nextRow() (*types.Document, error) {
+	if it.sortable == nil {
+		return nextRow(it.rows)
+	}
+
+	return nextRow(it.rows, it.sortable)
+}
+
+// sortedInSQL reads the first row and returns true if rows are sorted in SQL
+// (the second column computed for all rows with window function is true).
+//
+// The read document is returned by nextMatching later.
+func (it *findIterator) sortedInSQL() (bool, error) {
+	doc, err := it.nextRow()
+	if err != nil {
+		return false, err
+	}
+
+	it.peeked = doc
+	return doc == nil || *it.sortable, nil
+}
+
+// nextMatching returns the next row matching residual filter, or nil if there are no more rows.
+func (it *findIterator) nextMatching() (*types.Document, error) {
+	for {
+		doc := it.peeked
+		it.peeked = nil
+
+		if doc == nil {
+			var err error
+			if doc, err = it.nextRow(); err != nil || doc == nil {
+				return nil, err
+			}
+		}
+
+		matches, err := matchResidual(doc, it.residual)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			return doc, nil
+		}
+	}
+}
+
+// fetch reads all matching rows and sorts them with the given sort specification, if set.
+//
+// If limit is set, only the first limit documents are kept in memory:
+// fetched documents are sorted and truncated every time there are twice as many of them.
+func (it *findIterator) fetch(sort *types.Document) error {
+	limit := int(it.limit)
+
+	fetched := []*types.Document{}
+	for {
+		doc, err := it.nextMatching()
+		if err != nil {
+			return err
+		}
+		if doc == nil {
+			break
+		}
+
+		fetched = append(fetched, doc)
+
+		// sorting is stable and kept documents were fetched before the new ones, so ties keep fetch order
+		if limit != 0 && len(fetched) == 2*limit {
+			if err = common.SortDocuments(fetched, sort); err != nil {
+				return err
+			}
+			fetched = fetched[:limit]
+		}
+	}
+
+	if err := common.SortDocuments(fetched, sort); err != nil {
+		return err
+	}
+
+	if limit != 0 && len(fetched) > limit {
+		fetched = fetched[:limit]
+	}
+
+	it.fetched = fetched
+	return nil
+}
+
+// Next implements common.CursorIterator interface.
+func (it *findIterator) Next() (*types.Document, error) {
+	if it.limit != 0 && it.returned >= it.limit {
+		return nil, nil
+	}
+
+	var doc *types.Document
+	if it.fetched != nil {
+		if len(it.fetched) == 0 {
+			return nil, nil
+		}
+		doc, it.fetched = it.fetched[0], it.fetched[1:]
+	} else {
+		var err error
+		if doc, err = it.nextMatching(); err != nil || doc == nil {
+			return nil, err
+		}
+	}
+
+	it.returned++
+
+	doc, err := projectDocument(doc, it.projection)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	return doc, nil
+}
+
+// Close implements common.CursorIterator interface.
+//
+// Canceling the query of not exhausted rows closes their PostgreSQL connection
+// instead of reading all remaining rows.
+func (it *findIterator) Close() {
+	it.cancel()
+	it.rows.Close()
+
+	if it.release != nil {
+		it.release()
+		it.release = nil
+	}
+}
+
+// check interfaces
+var (
+	_ common.CursorIterator = (*findIterator)(nil)
+)
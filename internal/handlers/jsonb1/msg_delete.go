@@ -20,6 +20,7 @@ import (
 
 	"github.com/jackc/pgx/v4"
 
+	"github.com/FerretDB/FerretDB/internal/fjson"
 	"github.com/FerretDB/FerretDB/internal/handlers/common"
 	"github.com/FerretDB/FerretDB/internal/pg"
 	"github.com/FerretDB/FerretDB/internal/types"
@@ -60,12 +61,37 @@ func (s *storage) MsgDelete(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 		sql := fmt.Sprintf(`DELETE FROM %s`, pgx.Identifier{db, collection}.Sanitize())
 		var placeholder pg.Placeholder
 
-		elSQL, args, err := where(d["q"].(*types.Document), &placeholder)
+		elSQL, args, residual, err := where(d["q"].(*types.Document), &placeholder)
 		if err != nil {
 			return nil, lazyerrors.Error(err)
 		}
 
 		limit, _ := d["limit"].(int32)
+
+		if residual != nil {
+			table := pgx.Identifier{db, collection}.Sanitize()
+			rows, err := s.pgPool.Query(ctx, "SELECT _jsonb FROM "+table+elSQL, args...)
+			if err != nil {
+				// TODO check error code
+				return nil, common.NewError(common.ErrNamespaceNotFound, fmt.Errorf("delete: ns not found: %w", err))
+			}
+
+			ids, err := matchingIDs(rows, residual, limit)
+			if err != nil {
+				return nil, err
+			}
+
+			sql = fmt.Sprintf("DELETE FROM %s WHERE _jsonb->'_id' = $1", table)
+			for _, id := range ids {
+				tag, err := s.pgPool.Exec(ctx, sql, id)
+				if err != nil {
+					return nil, lazyerrors.Error(err)
+				}
+
+				deleted += int32(tag.RowsAffected())
+			}
+			continue
+		}
 		if limit != 0 {
 			sql += fmt.Sprintf(" WHERE _jsonb->'_id' IN (SELECT _jsonb->'_id' FROM %s", pgx.Identifier{db, collection}.Sanitize())
 			sql += elSQL
@@ -96,3 +122,42 @@ func (s *storage) MsgDelete(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 
 	return &reply, nil
 }
+
+// matchingIDs returns fjson-encoded _id values of selected rows that also match
+// residual filter returned by where, but no more than limit values if it is not zero.
+func matchingIDs(rows pgx.Rows, filter *types.Document, limit int32) ([][]byte, error) {
+	defer rows.Close()
+
+	var ids [][]byte
+	for limit == 0 || len(ids) < int(limit) {
+		doc, err := nextRow(rows)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		if doc == nil {
+			break
+		}
+
+		matches, err := matchResidual(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if !matches {
+			continue
+		}
+
+		id, err := doc.Get("_id")
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+		idb, err := fjson.Marshal(id)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+		ids = append(ids, idb)
+	}
+
+	return ids, nil
+}
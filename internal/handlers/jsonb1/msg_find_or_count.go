@@ -41,7 +41,6 @@ func (s *storage) MsgFindOrCount(ctx context.Context, msg *wire.OpMsg) (*wire.Op
 		"showRecordId",
 		"tailable",
 		"oplogReplay",
-		"noCursorTimeout",
 		"awaitData",
 		"allowPartialResults",
 		"collation",
@@ -53,8 +52,6 @@ func (s *storage) MsgFindOrCount(ctx context.Context, msg *wire.OpMsg) (*wire.Op
 	}
 	ignoredFields := []string{
 		"hint",
-		"batchSize",
-		"singleBatch",
 		"comment",
 		"maxTimeMS",
 		"readConcern",
@@ -74,120 +71,194 @@ func (s *storage) MsgFindOrCount(ctx context.Context, msg *wire.OpMsg) (*wire.Op
 	db := m["$db"].(string)
 
 	if isFindOp {
-		projectionIn, _ := m["projection"].(*types.Document)
-		projectionSQL, projectionArgs, err := projection(projectionIn, &placeholder)
-		if err != nil {
-			return nil, lazyerrors.Error(err)
-		}
-		args = append(args, projectionArgs...)
-
 		collection = m["find"].(string)
 		filter, _ = m["filter"].(*types.Document)
-		sql = fmt.Sprintf(`SELECT %s FROM %s`, projectionSQL, pgx.Identifier{db, collection}.Sanitize())
 	} else {
 		collection = m["count"].(string)
 		filter, _ = m["query"].(*types.Document)
-		sql = fmt.Sprintf(`SELECT COUNT(*) FROM %s`, pgx.Identifier{db, collection}.Sanitize())
 	}
 
 	sort, _ := m["sort"].(*types.Document)
+	projection, _ := m["projection"].(*types.Document)
 	limit, _ := m["limit"].(int32)
+	singleBatch, _ := m["singleBatch"].(bool)
+	noCursorTimeout, _ := m["noCursorTimeout"].(bool)
 
-	whereSQL, whereArgs, err := where(filter, &placeholder)
+	batchSize, err := common.GetBatchSize(document, common.DefaultBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	whereSQL, whereArgs, residual, err := where(filter, &placeholder)
 	if err != nil {
 		return nil, lazyerrors.Error(err)
 	}
 	args = append(args, whereArgs...)
 
-	sql += whereSQL
+	// residual filter is checked for fetched documents, so they are counted after that
+	countInGo := !isFindOp && residual != nil
 
-	sortMap := sort.Map()
-	if len(sortMap) != 0 {
-		sql += " ORDER BY"
-
-		for i, k := range sort.Keys() {
-			if i != 0 {
-				sql += ","
-			}
+	// a single field is sorted in SQL if all its values are scalars that SQL could sort with MongoDB comparison order;
+	// the same query returns whether they are in the second column, and documents are sorted after fetching them if not
+	sortInGo := isFindOp && sort.Len() != 0
+	var sortableSQL, orderBySQL string
+	if field, asc, ok := sqlSortField(sort); sortInGo && ok {
+		v := "_jsonb->" + placeholder.Next()
+		args = append(args, field)
 
-			sql += " _jsonb->" + placeholder.Next()
-			args = append(args, k)
+		sortableSQL = ", bool_and(" + sqlSortableExpr(v) + ") OVER ()"
+		orderBySQL = sqlOrderBy(v, asc)
+	}
 
-			order := sortMap[k].(int32)
-			if order > 0 {
-				sql += " ASC"
-			} else {
-				sql += " DESC"
-			}
-		}
+	if isFindOp || countInGo {
+		sql = fmt.Sprintf(`SELECT _jsonb%s FROM %s`, sortableSQL, pgx.Identifier{db, collection}.Sanitize())
+	} else {
+		sql = fmt.Sprintf(`SELECT COUNT(*) FROM %s`, pgx.Identifier{db, collection}.Sanitize())
 	}
 
+	sql += whereSQL + orderBySQL
+
+	// documents are checked with residual filter after fetching them,
+	// so the limit could be applied in SQL only if they are not sorted (or could be sorted) in Go
+	// and there is no residual filter
+	limitInGo := sortInGo || residual != nil
+
 	switch {
 	case limit == 0:
 		// undefined or zero - no limit
 	case limit > 0:
-		sql += " LIMIT " + placeholder.Next()
-		args = append(args, limit)
+		if !limitInGo {
+			sql += " LIMIT " + placeholder.Next()
+			args = append(args, limit)
+		}
 	default:
 		// TODO https://github.com/FerretDB/FerretDB/issues/79
 		return nil, common.NewError(common.ErrNotImplemented, fmt.Errorf("find: negative limit values are not supported"))
 	}
 
-	rows, err := s.pgPool.Query(ctx, sql, args...)
+	var reply wire.OpMsg
+	if !isFindOp && !countInGo {
+		var count int32
+		if err = s.pgPool.QueryRow(ctx, sql, args...).Scan(&count); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		// in psql, the SELECT * FROM table limit `x` ignores the value of the limit,
+		// so, we need this `if` statement to support this kind of query `db.actor.find().limit(10).count()`
+		if count > limit && limit != 0 {
+			count = limit
+		}
+		err = reply.SetSections(wire.OpMsgSection{
+			Documents: []*types.Document{types.MustNewDocument(
+				"n", count,
+				"ok", float64(1),
+			)},
+		})
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+		return &reply, nil
+	}
+
+	// find cursors outlive the request, so their query is canceled only when the cursor is closed
+	queryCtx := ctx
+	if isFindOp {
+		queryCtx = context.Background()
+	}
+	queryCtx, cancel := context.WithCancel(queryCtx)
+
+	rows, err := s.pgPool.Query(queryCtx, sql, args...)
 	if err != nil {
+		cancel()
 		return nil, lazyerrors.Error(err)
 	}
-	defer rows.Close()
 
-	var reply wire.OpMsg
-	if isFindOp { //nolint:nestif // TODO simplify
-		var docs types.Array
+	iter := &findIterator{
+		rows:       rows,
+		cancel:     cancel,
+		residual:   residual,
+		projection: projection,
+		limit:      limit,
+	}
+
+	if orderBySQL != "" {
+		iter.sortable = new(bool)
+
+		var sorted bool
+		if sorted, err = iter.sortedInSQL(); err != nil {
+			iter.Close()
+			return nil, err
+		}
+		sortInGo = !sorted
+	}
+
+	switch {
+	case sortInGo:
+		if err = iter.fetch(sort); err != nil {
+			iter.Close()
+			return nil, err
+		}
+
+	case isFindOp:
+		// the rows stream is kept open by the cursor only if that leaves enough connections for other requests;
+		// otherwise, all documents are fetched now
+		var ok bool
+		if iter.release, ok = s.pgPool.TryAcquireStream(); !ok {
+			if err = iter.fetch(nil); err != nil {
+				iter.Close()
+				return nil, err
+			}
+		}
+	}
+
+	if countInGo {
+		defer iter.Close()
+
+		var count int32
 		for {
-			doc, err := nextRow(rows)
+			doc, err := iter.Next()
 			if err != nil {
-				return nil, lazyerrors.Error(err)
+				return nil, err
 			}
 			if doc == nil {
 				break
 			}
-
-			if err = docs.Append(doc); err != nil {
-				return nil, lazyerrors.Error(err)
-			}
+			count++
 		}
+
 		err = reply.SetSections(wire.OpMsgSection{
 			Documents: []*types.Document{types.MustNewDocument(
-				"cursor", types.MustNewDocument(
-					"firstBatch", &docs,
-					"id", int64(0), // TODO
-					"ns", db+"."+collection,
-				),
+				"n", count,
 				"ok", float64(1),
 			)},
 		})
-	} else {
-		var count int32
-		for rows.Next() {
-			err := rows.Scan(&count)
-			if err != nil {
-				return nil, lazyerrors.Error(err)
-			}
-		}
-		// in psql, the SELECT * FROM table limit `x` ignores the value of the limit,
-		// so, we need this `if` statement to support this kind of query `db.actor.find().limit(10).count()`
-		if count > limit && limit != 0 {
-			count = limit
-		}
 		if err != nil {
 			return nil, lazyerrors.Error(err)
 		}
-		err = reply.SetSections(wire.OpMsgSection{
-			Documents: []*types.Document{types.MustNewDocument(
-				"n", count,
-				"ok", float64(1),
-			)},
-		})
+
+		return &reply, nil
 	}
+
+	firstBatch, id, err := s.cursors.FirstBatch(iter, &common.CursorOpts{
+		NS:          db + "." + collection,
+		BatchSize:   batchSize,
+		SingleBatch: singleBatch,
+		NoTimeout:   noCursorTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = reply.SetSections(wire.OpMsgSection{
+		Documents: []*types.Document{types.MustNewDocument(
+			"cursor", types.MustNewDocument(
+				"firstBatch", firstBatch,
+				"id", id,
+				"ns", db+"."+collection,
+			),
+			"ok", float64(1),
+		)},
+	})
 	if err != nil {
 		return nil, lazyerrors.Error(err)
 	}
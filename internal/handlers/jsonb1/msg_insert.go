@@ -48,7 +48,11 @@ func (s *storage) MsgInsert(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 			return nil, lazyerrors.Error(err)
 		}
 
-		d := doc.(*types.Document)
+		d, err := common.PrepareDocumentForInsert(doc.(*types.Document))
+		if err != nil {
+			return nil, err
+		}
+
 		sql := fmt.Sprintf("INSERT INTO %s (_jsonb) VALUES ($1)", pgx.Identifier{db, collection}.Sanitize())
 		b, err := fjson.Marshal(d)
 		if err != nil {
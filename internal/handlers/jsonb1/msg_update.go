@@ -17,6 +17,7 @@ package jsonb1
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v4"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/FerretDB/FerretDB/internal/pg"
 	"github.com/FerretDB/FerretDB/internal/types"
 	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+	"github.com/FerretDB/FerretDB/internal/util/must"
 	"github.com/FerretDB/FerretDB/internal/wire"
 )
 
@@ -46,6 +48,7 @@ func (s *storage) MsgUpdate(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 	db := m["$db"].(string)
 
 	var selected, updated int32
+	var upserted types.Array
 	for i := 0; i < docs.Len(); i++ {
 		doc, err := docs.Get(i)
 		if err != nil {
@@ -54,8 +57,6 @@ func (s *storage) MsgUpdate(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 
 		unimplementedFields := []string{
 			"c",
-			"upsert",
-			"multi",
 			"collation",
 			"arrayFilters",
 			"hint",
@@ -65,11 +66,13 @@ func (s *storage) MsgUpdate(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 		}
 
 		docM := doc.(*types.Document).Map()
+		multi, _ := docM["multi"].(bool)
+		upsert, _ := docM["upsert"].(bool)
 
 		sql := fmt.Sprintf(`SELECT _jsonb FROM %s`, pgx.Identifier{db, collection}.Sanitize())
 		var placeholder pg.Placeholder
 
-		whereSQL, args, err := where(docM["q"].(*types.Document), &placeholder)
+		whereSQL, args, residual, err := where(docM["q"].(*types.Document), &placeholder)
 		if err != nil {
 			return nil, lazyerrors.Error(err)
 		}
@@ -93,13 +96,41 @@ func (s *storage) MsgUpdate(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 				break
 			}
 
+			matches, err := matchResidual(updateDoc, residual)
+			if err != nil {
+				return nil, err
+			}
+			if !matches {
+				continue
+			}
+
 			if err = updateDocs.Append(updateDoc); err != nil {
 				return nil, lazyerrors.Error(err)
 			}
+
+			// without multi flag only the first matching document is updated
+			if !multi {
+				break
+			}
 		}
+		rows.Close()
 
 		selected += int32(updateDocs.Len())
 
+		if updateDocs.Len() == 0 && upsert {
+			id, err := s.upsert(ctx, db, collection, docM["q"].(*types.Document), docM["u"].(*types.Document))
+			if err != nil {
+				return nil, err
+			}
+
+			if err = upserted.Append(types.MustNewDocument("index", int32(i), "_id", id)); err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+
+			selected++
+			continue
+		}
+
 		for i := 0; i < updateDocs.Len(); i++ {
 			updateDoc, err := updateDocs.Get(i)
 			if err != nil {
@@ -107,18 +138,8 @@ func (s *storage) MsgUpdate(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 			}
 
 			d := updateDoc.(*types.Document)
-
-			for updateOp, updateV := range docM["u"].(*types.Document).Map() {
-				switch updateOp {
-				case "$set":
-					for k, v := range updateV.(*types.Document).Map() {
-						if err := d.Set(k, v); err != nil {
-							return nil, lazyerrors.Error(err)
-						}
-					}
-				default:
-					return nil, lazyerrors.Errorf("unhandled operation %q", updateOp)
-				}
+			if err = applyUpdate(d, docM["u"].(*types.Document)); err != nil {
+				return nil, err
 			}
 
 			if err = updateDocs.Set(i, d); err != nil {
@@ -139,7 +160,12 @@ func (s *storage) MsgUpdate(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 				return nil, err
 			}
 
-			idb, err := fjson.Marshal(d.Map()["_id"].(types.ObjectID))
+			id, err := d.Get("_id")
+			if err != nil {
+				return nil, lazyerrors.Error(err)
+			}
+
+			idb, err := fjson.Marshal(id)
 			if err != nil {
 				return nil, err
 			}
@@ -152,13 +178,22 @@ func (s *storage) MsgUpdate(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 		}
 	}
 
+	res := types.MustNewDocument(
+		"n", selected,
+		"nModified", updated,
+	)
+	if upserted.Len() != 0 {
+		if err = res.Set("upserted", &upserted); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+	}
+	if err = res.Set("ok", float64(1)); err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
 	var reply wire.OpMsg
 	err = reply.SetSections(wire.OpMsgSection{
-		Documents: []*types.Document{types.MustNewDocument(
-			"n", selected,
-			"nModified", updated,
-			"ok", float64(1),
-		)},
+		Documents: []*types.Document{res},
 	})
 	if err != nil {
 		return nil, lazyerrors.Error(err)
@@ -166,3 +201,67 @@ func (s *storage) MsgUpdate(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 
 	return &reply, nil
 }
+
+// upsert inserts a new document built from equality conditions of the query and the update,
+// and returns its _id.
+func (s *storage) upsert(ctx context.Context, db, collection string, q, u *types.Document) (any, error) {
+	d := new(types.Document)
+
+	qM := q.Map()
+	for _, k := range q.Keys() {
+		// only top-level equality conditions become fields of the new document
+		if strings.HasPrefix(k, "$") {
+			continue
+		}
+		if doc, ok := qM[k].(*types.Document); ok && doc.Len() != 0 && strings.HasPrefix(doc.Keys()[0], "$") {
+			continue
+		}
+
+		if strings.Contains(k, ".") {
+			return nil, common.NewError(common.ErrNotImplemented, fmt.Errorf("upsert with dotted field %q is not implemented", k))
+		}
+
+		if err := d.Set(k, qM[k]); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+	}
+
+	if err := applyUpdate(d, u); err != nil {
+		return nil, err
+	}
+
+	d, err := common.PrepareDocumentForInsert(d)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := fjson.Marshal(d)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	sql := fmt.Sprintf("INSERT INTO %s (_jsonb) VALUES ($1)", pgx.Identifier{db, collection}.Sanitize())
+	if _, err = s.pgPool.Exec(ctx, sql, b); err != nil {
+		return nil, err
+	}
+
+	return must.NotFail(d.Get("_id")), nil
+}
+
+// applyUpdate applies update operators to the given document.
+func applyUpdate(d, u *types.Document) error {
+	for updateOp, updateV := range u.Map() {
+		switch updateOp {
+		case "$set":
+			for k, v := range updateV.(*types.Document).Map() {
+				if err := d.Set(k, v); err != nil {
+					return lazyerrors.Error(err)
+				}
+			}
+		default:
+			return lazyerrors.Errorf("unhandled operation %q", updateOp)
+		}
+	}
+
+	return nil
+}
@@ -15,38 +15,32 @@
 package jsonb1
 
 import (
-	"github.com/FerretDB/FerretDB/internal/pg"
 	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
 )
 
-func projection(projection *types.Document, p *pg.Placeholder) (sql string, args []any, err error) {
-	if projection == nil {
-		sql = "_jsonb"
-		return
-	}
-	projectionMap := projection.Map()
-	if len(projectionMap) == 0 {
-		sql = "_jsonb"
-		return
+// projectDocument returns a new document with only fields listed in the projection.
+// Fields missing in the document are set to null.
+//
+// Projection is applied to documents returned by the cursor, after they were sorted and checked with residual filter,
+// so sort and filter fields don't have to be projected.
+// It returns the document itself if projection is empty.
+func projectDocument(doc, projection *types.Document) (*types.Document, error) {
+	if projection.Len() == 0 {
+		return doc, nil
 	}
 
-	ks := ""
-	for i, k := range projection.Keys() {
-		if i != 0 {
-			ks += ", "
+	res := new(types.Document)
+	for _, k := range projection.Keys() {
+		v, err := doc.Get(k)
+		if err != nil {
+			v = types.Null
 		}
-		ks += p.Next()
-		args = append(args, k)
-	}
-	sql = "json_build_object('$k', array[" + ks + "],"
-	for i, k := range projection.Keys() {
-		if i != 0 {
-			sql += ", "
+
+		if err = res.Set(k, v); err != nil {
+			return nil, lazyerrors.Error(err)
 		}
-		sql += p.Next() + "::text, _jsonb->" + p.Next()
-		args = append(args, k, k)
 	}
-	sql += ")"
 
-	return
+	return res, nil
 }
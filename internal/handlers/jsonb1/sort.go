@@ -0,0 +1,102 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonb1
+
+import (
+	"strings"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+)
+
+// sqlSortField returns the field and order of the sort specification that could be sorted in SQL:
+// a single top-level field with order 1 or -1.
+//
+// Other specifications (including invalid ones) are sorted in Go.
+func sqlSortField(sort *types.Document) (field string, asc, ok bool) {
+	if sort.Len() != 1 {
+		return
+	}
+
+	field = sort.Keys()[0]
+	if strings.Contains(field, ".") || strings.HasPrefix(field, "$") {
+		return
+	}
+
+	var order float64
+	switch v := sort.Map()[field].(type) {
+	case int32:
+		order = float64(v)
+	case int64:
+		order = float64(v)
+	case float64:
+		order = v
+	default:
+		return
+	}
+
+	switch order {
+	case 1, -1:
+		return field, order > 0, true
+	default:
+		return
+	}
+}
+
+// sqlSortableExpr returns SQL condition that is true if the fjson-encoded value v (possibly missing)
+// is a scalar value of the type that sqlOrderBy sorts: null, number, string, ObjectID, bool, date, MinKey or MaxKey.
+func sqlSortableExpr(v string) string {
+	return "(" + v + " IS NULL OR jsonb_typeof(" + v + ") IN ('null', 'number', 'string', 'boolean') OR " +
+		"(jsonb_typeof(" + v + ") = 'object' AND NOT " + v + " ? '$k' AND " +
+		v + " ?| array['$f', '$l', '$n', '$s', '$c', '$o', '$d', '$m']))"
+}
+
+// sqlOrderBy returns ORDER BY clause that sorts documents by the fjson-encoded value v
+// using MongoDB comparison order (see types.Compare) if all values are sortable (see sqlSortableExpr).
+//
+// Values are sorted by their type's position in the comparison order first;
+// numbers are then sorted with NaN before all other values, then by infinity sign, then by value;
+// strings, symbols, ObjectIDs and booleans are sorted by their text representation, byte by byte.
+func sqlOrderBy(v string, asc bool) string {
+	object := "jsonb_typeof(" + v + ") = 'object' AND "
+
+	rank := "CASE" +
+		" WHEN " + v + " IS NULL OR jsonb_typeof(" + v + ") = 'null' THEN 5" +
+		" WHEN jsonb_typeof(" + v + ") = 'number' OR (" + object + v + " ?| array['$f', '$l', '$n']) THEN 10" +
+		" WHEN jsonb_typeof(" + v + ") = 'string' OR (" + object + v + " ?| array['$s', '$c']) THEN 15" +
+		" WHEN " + object + v + " ? '$o' THEN 35" +
+		" WHEN jsonb_typeof(" + v + ") = 'boolean' THEN 40" +
+		" WHEN " + object + v + " ? '$d' THEN 45" +
+		" WHEN " + object + "(" + v + "->>'$m')::int < 0 THEN -1" +
+		" ELSE 127" +
+		" END"
+
+	// numbers and dates as text, including NaN and infinities
+	number := "COALESCE(" + v + "->>'$n', " + v + "->>'$l', " + v + "->>'$f', " + v + "->>'$d', " +
+		"CASE WHEN jsonb_typeof(" + v + ") = 'number' THEN " + v + " #>> '{}' END)"
+	nan := "CASE WHEN " + number + " = 'NaN' THEN 0 ELSE 1 END"
+	inf := "CASE " + number + " WHEN '-Infinity' THEN -1 WHEN 'Infinity' THEN 1 ELSE 0 END"
+	finite := "CASE WHEN " + number + " NOT IN ('NaN', 'Infinity', '-Infinity') THEN (" + number + ")::numeric END"
+
+	text := "COALESCE(" + v + "->>'$s', " + v + "->>'$c', " + v + "->>'$o', " +
+		"CASE WHEN jsonb_typeof(" + v + ") IN ('string', 'boolean') THEN " + v + " #>> '{}' END) COLLATE \"C\""
+
+	order := " DESC"
+	if asc {
+		order = " ASC"
+	}
+
+	keys := []string{rank, nan, inf, finite, text}
+	return " ORDER BY " + strings.Join(keys, order+", ") + order
+}
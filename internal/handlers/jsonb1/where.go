@@ -18,55 +18,253 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/FerretDB/FerretDB/internal/fjson"
 	"github.com/FerretDB/FerretDB/internal/handlers/common"
 	"github.com/FerretDB/FerretDB/internal/pg"
 	"github.com/FerretDB/FerretDB/internal/types"
 	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+	"github.com/FerretDB/FerretDB/internal/util/must"
 )
 
+// scalar returns SQL placeholder and argument for the given regular expression.
 func scalar(v any, p *pg.Placeholder) (sql string, args []any, err error) {
-	var arg any
-	switch v := v.(type) {
-	case int32:
-		sql = "to_jsonb(" + p.Next() + "::int4)"
-		arg = v
-	case string:
-		sql = "to_jsonb(" + p.Next() + "::text)"
-		arg = v
-	case types.ObjectID:
-		sql = p.Next()
-		var b []byte
-		if b, err = fjson.Marshal(v); err != nil {
-			err = lazyerrors.Errorf("scalar: %w", err)
+	re, ok := v.(types.Regex)
+	if !ok {
+		err = lazyerrors.Errorf("scalar: unhandled field %v (%T)", v, v)
+		return
+	}
+
+	var pattern string
+	if pattern, err = common.RegexPattern(re); err != nil {
+		err = lazyerrors.Errorf("scalar: %w", err)
+		return
+	}
+
+	sql = p.Next()
+	args = []any{pattern}
+	return
+}
+
+// comparisonOperators maps comparison query operators to SQL operators.
+var comparisonOperators = map[string]string{
+	"$eq":  "=",
+	"$lt":  "<",
+	"$lte": "<=",
+	"$gt":  ">",
+	"$gte": ">=",
+}
+
+// numericExpr returns SQL expression that converts the given jsonb value to PostgreSQL numeric.
+//
+// It handles int32, int64, float64 and types.Decimal128 values; for other values, it returns NULL.
+func numericExpr(v string) string {
+	return "COALESCE(" +
+		"(" + v + "->>'$n')::numeric, " +
+		"(" + v + "->>'$l')::numeric, " +
+		"(" + v + "->>'$f')::numeric, " +
+		"CASE WHEN jsonb_typeof(" + v + ") = 'number' THEN (" + v + ")::numeric END" +
+		")"
+}
+
+// arrayElements returns SQL expression that expands the given jsonb value to a set of array elements,
+// or to an empty set if the value is not an array.
+func arrayElements(v string) string {
+	return "jsonb_array_elements(CASE jsonb_typeof(" + v + ") WHEN 'array' THEN " + v + " ELSE '[]' END)"
+}
+
+// filterRoot represents the jsonb value filters are applied to:
+// the whole document for queries, or an array element for $elemMatch.
+type filterRoot struct {
+	expr  string // SQL expression of fjson-encoded value
+	depth int    // $elemMatch nesting depth, used to make SQL aliases unique
+}
+
+// documentRoot is the filter root for the whole stored document.
+var documentRoot = filterRoot{expr: "_jsonb"}
+
+// elementsAlias returns SQL alias for array elements matched by $elemMatch at this root.
+func (r filterRoot) elementsAlias() string {
+	return fmt.Sprintf("e%d", r.depth)
+}
+
+// elements returns the filter root for array elements matched by $elemMatch at this root.
+func (r filterRoot) elements() filterRoot {
+	return filterRoot{
+		expr:  r.elementsAlias() + ".v",
+		depth: r.depth + 1,
+	}
+}
+
+// fieldValues returns SQL FROM clause that produces all values of the field with the given path as x.v,
+// following MongoDB array semantics:
+//   - arrays in the middle of the path are traversed, so "a.b" matches {a: [{b: 1}, {b: 2}]};
+//   - numeric path elements also select array elements by index, so "a.0" matches {a: [1, 2]};
+//   - if unwrap is true, arrays at the end of the path produce both the array itself and its elements,
+//     so {a: 1} and {a: [1, 2]} both match {a: [1, 2]}.
+//
+// Missing fields produce NULL values, so null matches them.
+func (r filterRoot) fieldValues(path []string, unwrap bool, p *pg.Placeholder) (sql string, args []any) {
+	sql = "(SELECT " + r.expr + ") s0(v)"
+
+	for i, key := range path {
+		prev := fmt.Sprintf("s%d.v", i)
+		next := p.Next() + "::text"
+		args = append(args, key)
+
+		// documents, scalars and missing values produce field values or NULLs;
+		// arrays produce values of that field for all their document elements, and elements themselves for indexes
+		step := "SELECT " + prev + " -> " + next + " WHERE jsonb_typeof(" + prev + ") IS DISTINCT FROM 'array'" +
+			" UNION ALL SELECT e -> " + next + " FROM " + arrayElements(prev) + " e WHERE jsonb_typeof(e) = 'object'"
+		if index, ok := types.ArrayIndex(key); ok {
+			step += fmt.Sprintf(" UNION ALL SELECT %[1]s -> %[2]d WHERE jsonb_typeof(%[1]s) = 'array'", prev, index)
+		}
+
+		sql += fmt.Sprintf(" CROSS JOIN LATERAL (%s) s%d(v)", step, i+1)
+	}
+
+	last := fmt.Sprintf("s%d.v", len(path))
+	if unwrap {
+		sql += " CROSS JOIN LATERAL (SELECT " + last + " UNION ALL SELECT " + arrayElements(last) + ") x(v)"
+	} else {
+		sql += " CROSS JOIN LATERAL (SELECT " + last + ") x(v)"
+	}
+
+	return
+}
+
+// typeKeys maps $type operator aliases to keys of fjson-encoded objects.
+var typeKeys = map[string]string{
+	"double":    "$f",
+	"object":    "$k",
+	"binData":   "$b",
+	"undefined": "$u",
+	"objectId":  "$o",
+	"date":      "$d",
+	"regex":     "$r",
+	"dbPointer": "$p",
+	"symbol":    "$s",
+	"timestamp": "$t",
+	"long":      "$l",
+	"decimal":   "$n",
+}
+
+// typeExpr returns SQL expression that checks that the given jsonb value has a type with the given $type alias.
+func typeExpr(alias, v string) string {
+	isObject := "jsonb_typeof(" + v + ") = 'object'"
+
+	switch alias {
+	case "string":
+		return "jsonb_typeof(" + v + ") = 'string'"
+	case "array":
+		return "jsonb_typeof(" + v + ") = 'array'"
+	case "bool":
+		return "jsonb_typeof(" + v + ") = 'boolean'"
+	case "null":
+		return "jsonb_typeof(" + v + ") = 'null'"
+	case "int":
+		return "jsonb_typeof(" + v + ") = 'number'"
+	case "javascript":
+		return "(" + isObject + " AND " + v + " ? '$j' AND NOT " + v + " ? 's')"
+	case "javascriptWithScope":
+		return "(" + isObject + " AND " + v + " ? '$j' AND " + v + " ? 's')"
+	case "minKey":
+		return "(" + v + "->'$m' = '-1')"
+	case "maxKey":
+		return "(" + v + "->'$m' = '1')"
+	default:
+		return "(" + isObject + " AND " + v + " ? '" + typeKeys[alias] + "')"
+	}
+}
+
+// binaryBitsExpr returns SQL condition for bitwise query operator op applied to the given bytea SQL expression.
+//
+// Bits are numbered from the least significant bit of the first byte; bits past the end are clear.
+func binaryBitsExpr(op, v string, positions []int) string {
+	bit, join, empty := "1", " AND ", "TRUE"
+	switch op {
+	case "$bitsAllSet":
+	case "$bitsAnySet":
+		join, empty = " OR ", "FALSE"
+	case "$bitsAllClear":
+		bit = "0"
+	case "$bitsAnyClear":
+		bit, join, empty = "0", " OR ", "FALSE"
+	default:
+		panic(fmt.Sprintf("unexpected operator %q", op))
+	}
+
+	if len(positions) == 0 {
+		return "(" + v + " IS NOT NULL AND " + empty + ")"
+	}
+
+	exprs := make([]string, len(positions))
+	for i, pos := range positions {
+		exprs[i] = fmt.Sprintf("CASE WHEN length(%[1]s) > %[2]d THEN get_bit(%[1]s, %[3]d) ELSE 0 END = %[4]s", v, pos/8, pos, bit)
+	}
+
+	return "(" + v + " IS NOT NULL AND (" + strings.Join(exprs, join) + "))"
+}
+
+// isOperatorExpr returns true if $elemMatch argument uses query operators form like {$gte: 80, $lt: 85}
+// instead of nested document form like {product: "xyz", qty: {$gte: 5}}.
+func isOperatorExpr(expr *types.Document) bool {
+	if expr.Len() == 0 {
+		return false
+	}
+
+	switch key := expr.Keys()[0]; key {
+	case "$and", "$or", "$nor", "$expr":
+		return false
+	default:
+		return strings.HasPrefix(key, "$")
+	}
+}
+
+// allExpr handles {field: {$all: [value1, value2, ...]}}.
+//
+// It matches if the field matches all values; values may be $elemMatch expressions.
+func (r filterRoot) allExpr(path []string, value any, p *pg.Placeholder) (sql string, args []any, err error) {
+	arr, ok := value.(*types.Array)
+	if !ok {
+		err = common.NewError(common.ErrBadValue, fmt.Errorf("$all needs an array"))
+		return
+	}
+
+	if arr.Len() == 0 {
+		sql = "FALSE"
+		return
+	}
+
+	exprs := make([]string, arr.Len())
+	for i := 0; i < arr.Len(); i++ {
+		var el any
+		if el, err = arr.Get(i); err != nil {
+			err = lazyerrors.Errorf("allExpr: %w", err)
 			return
 		}
-		arg = string(b)
-	case types.Regex:
-		var options string
-		for _, o := range v.Options {
-			switch o {
-			case 'i':
-				options += "i"
-			default:
-				err = lazyerrors.Errorf("scalar: unhandled regex option %v (%v)", o, v)
-			}
+
+		expr := types.MustNewDocument("$eq", el)
+		if d, ok := el.(*types.Document); ok && d.Len() != 0 && d.Keys()[0] == "$elemMatch" {
+			expr = d
 		}
-		sql = p.Next()
-		arg = v.Pattern
-		if options != "" {
-			arg = "(?" + options + ")" + v.Pattern
+
+		var exprArgs []any
+		if exprs[i], exprArgs, err = r.fieldExpr(path, expr, p); err != nil {
+			err = lazyerrors.Errorf("allExpr: %w", err)
+			return
 		}
-	default:
-		err = lazyerrors.Errorf("scalar: unhandled field %v (%T)", v, v)
+		args = append(args, exprArgs...)
 	}
 
-	args = []any{arg}
+	sql = "(" + strings.Join(exprs, " AND ") + ")"
 	return
 }
 
-// fieldExpr handles {field: {expr}}.
-func fieldExpr(field string, expr *types.Document, p *pg.Placeholder) (sql string, args []any, err error) {
+// fieldExpr handles {field: {expr}} for the field with the given path.
+//
+// Each operator matches if any of the field values (see fieldValues) matches,
+// while negated operators ($ne, $nin) match if none of them do.
+// Empty path means the root value itself; that is used by $elemMatch with query operators.
+func (r filterRoot) fieldExpr(path []string, expr *types.Document, p *pg.Placeholder) (sql string, args []any, err error) {
 	filterKeys := expr.Keys()
 	filterMap := expr.Map()
 
@@ -84,14 +282,21 @@ func fieldExpr(field string, expr *types.Document, p *pg.Placeholder) (sql strin
 		var arg []any
 		value := filterMap[op]
 
-		// {field: {$not: {expr}}}
-		if op == "$not" {
+		switch op {
+		case "$not":
+			// {field: {$not: {expr}}}
 			if sql != "" {
 				sql += " "
 			}
 			sql += "NOT("
 
-			argSql, arg, err = fieldExpr(field, value.(*types.Document), p)
+			var notExpr *types.Document
+			if notExpr, err = common.NotExpr(value); err != nil {
+				err = lazyerrors.Errorf("fieldExpr: %w", err)
+				return
+			}
+
+			argSql, arg, err = r.fieldExpr(path, notExpr, p)
 			if err != nil {
 				err = lazyerrors.Errorf("fieldExpr: %w", err)
 				return
@@ -100,48 +305,136 @@ func fieldExpr(field string, expr *types.Document, p *pg.Placeholder) (sql strin
 			sql += argSql + ")"
 			args = append(args, arg...)
 
+			continue
+
+		case "$all":
+			// {field: {$all: [value1, value2, ...]}}
+			if sql != "" {
+				sql += " "
+			}
+
+			argSql, arg, err = r.allExpr(path, value, p)
+			if err != nil {
+				err = lazyerrors.Errorf("fieldExpr: %w", err)
+				return
+			}
+
+			sql += argSql
+			args = append(args, arg...)
+
 			continue
 		}
 
 		if sql != "" {
 			sql += " "
 		}
-		args = append(args, field)
+
+		// $size and $elemMatch apply to arrays themselves, not to their elements
+		unwrap := len(path) != 0 && op != "$size" && op != "$elemMatch"
+		fromSQL, fromArgs := r.fieldValues(path, unwrap, p)
+		args = append(args, fromArgs...)
+
+		exists := "EXISTS"
+		var cond string
 
 		switch op {
-		case "$in":
+		case "$in", "$nin":
 			// {field: {$in: [value1, value2, ...]}}
-			sql += "_jsonb->" + p.Next() + " IN"
-			argSql, arg, err = common.InArray(value.(*types.Array), p, scalar)
-		case "$nin":
 			// {field: {$nin: [value1, value2, ...]}}
-			sql += "_jsonb->" + p.Next() + " NOT IN"
-			argSql, arg, err = common.InArray(value.(*types.Array), p, scalar)
-		case "$eq":
+			arr, ok := value.(*types.Array)
+			if !ok {
+				err = common.NewError(common.ErrBadValue, fmt.Errorf("%s needs an array", op))
+				return
+			}
+
+			if op == "$nin" {
+				exists = "NOT EXISTS"
+			}
+			cond, arg, err = inExpr("x.v", arr, p)
+		case "$eq", "$ne", "$lt", "$lte", "$gt", "$gte":
 			// {field: {$eq: value}}
-			// TODO special handling for regex
-			sql += "_jsonb->" + p.Next() + " ="
-			argSql, arg, err = scalar(value, p)
-		case "$ne":
 			// {field: {$ne: value}}
-			sql += "_jsonb->" + p.Next() + " <>"
-			argSql, arg, err = scalar(value, p)
-		case "$lt":
 			// {field: {$lt: value}}
-			sql += "_jsonb->" + p.Next() + " <"
-			argSql, arg, err = scalar(value, p)
-		case "$lte":
-			// {field: {$lte: value}}
-			sql += "_jsonb->" + p.Next() + " <="
-			argSql, arg, err = scalar(value, p)
-		case "$gt":
-			// {field: {$gt: value}}
-			sql += "_jsonb->" + p.Next() + " >"
-			argSql, arg, err = scalar(value, p)
-		case "$gte":
-			// {field: {$gte: value}}
-			sql += "_jsonb->" + p.Next() + " >="
-			argSql, arg, err = scalar(value, p)
+			// ...
+			sqlOp := comparisonOperators[op]
+			if op == "$ne" {
+				exists = "NOT EXISTS"
+				sqlOp = "="
+			}
+			cond, arg, err = compareExpr("x.v", sqlOp, value, p)
+		case "$exists":
+			// {field: {$exists: bool}}
+			if !common.Truthy(value) {
+				exists = "NOT EXISTS"
+			}
+			cond = "x.v IS NOT NULL"
+		case "$type":
+			// {field: {$type: alias}}
+			// {field: {$type: [alias1, alias2, ...]}}
+			var aliases []string
+			if aliases, err = common.ParseTypes(value); err != nil {
+				break
+			}
+
+			exprs := make([]string, len(aliases))
+			for i, alias := range aliases {
+				exprs[i] = typeExpr(alias, "x.v")
+			}
+			cond = "(" + strings.Join(exprs, " OR ") + ")"
+		case "$size":
+			// {field: {$size: value}}
+			var size int64
+			if size, err = common.ParseSize(value); err != nil {
+				break
+			}
+
+			cond = "CASE jsonb_typeof(x.v) WHEN 'array' THEN jsonb_array_length(x.v) END = " + p.Next()
+			arg = []any{size}
+		case "$elemMatch":
+			// {field: {$elemMatch: {$op: value, ...}}}
+			// {field: {$elemMatch: {field1: value1, ...}}}
+			elemMatch, ok := value.(*types.Document)
+			if !ok {
+				err = common.NewError(common.ErrBadValue, fmt.Errorf("$elemMatch needs an Object"))
+				return
+			}
+
+			elements := r.elements()
+			fromSQL += " CROSS JOIN LATERAL " + arrayElements("x.v") + " " + r.elementsAlias() + "(v)"
+
+			if isOperatorExpr(elemMatch) {
+				argSql, arg, err = elements.fieldExpr(nil, elemMatch, p)
+				break
+			}
+
+			cond = "jsonb_typeof(" + elements.expr + ") = 'object'"
+			if elemMatch.Len() != 0 {
+				cond += " AND"
+				argSql, arg, err = elements.conditions(elemMatch, p)
+			}
+		case "$mod":
+			// {field: {$mod: [divisor, remainder]}}
+			var divisor, remainder int64
+			if divisor, remainder, err = common.ParseMod(value); err != nil {
+				break
+			}
+
+			cond = "trunc(" + numericExpr("x.v") + ") % " + p.Next() + "::bigint = " + p.Next() + "::bigint"
+			arg = []any{divisor, remainder}
+		case "$bitsAllSet", "$bitsAnySet", "$bitsAllClear", "$bitsAnyClear":
+			// {field: {$bitsAllSet: mask}}
+			var positions []int
+			if positions, err = common.ParseBitmask(op, value); err != nil {
+				break
+			}
+
+			fromSQL += " CROSS JOIN LATERAL (SELECT " + numericExpr("x.v") + ") n(v)" +
+				" CROSS JOIN LATERAL (SELECT " + common.Int64Expr("n.v") + ") i(v)" +
+				" CROSS JOIN LATERAL (SELECT decode(x.v->>'$b', 'base64')) b(v)"
+
+			var intSQL string
+			intSQL, arg = common.BitsExpr(op, "i.v", positions, p)
+			cond = "(" + intSQL + " OR " + binaryBitsExpr(op, "b.v", positions) + ")"
 		case "$regex":
 			// {field: {$regex: value}}
 
@@ -154,7 +447,7 @@ func fieldExpr(field string, expr *types.Document, p *pg.Placeholder) (sql strin
 				}
 			}
 
-			sql += "_jsonb->>" + p.Next() + " ~"
+			cond = "jsonb_typeof(x.v) = 'string' AND x.v #>> '{}' ~"
 			switch value := value.(type) {
 			case string:
 				// {field: {$regex: string}}
@@ -178,7 +471,7 @@ func fieldExpr(field string, expr *types.Document, p *pg.Placeholder) (sql strin
 				return
 			}
 		default:
-			err = lazyerrors.Errorf("unhandled {%q: %v}", op, value)
+			err = common.NewError(common.ErrNotImplemented, fmt.Errorf("%s can't be translated to SQL", op))
 		}
 
 		if err != nil {
@@ -186,41 +479,52 @@ func fieldExpr(field string, expr *types.Document, p *pg.Placeholder) (sql strin
 			return
 		}
 
-		sql += " " + argSql
+		if cond != "" && argSql != "" {
+			cond += " "
+		}
+		cond += argSql
+
+		sql += exists + " (SELECT 1 FROM " + fromSQL + " WHERE " + cond + ")"
 		args = append(args, arg...)
 	}
 
 	return
 }
 
-func wherePair(key string, value any, p *pg.Placeholder) (sql string, args []any, err error) {
+func (r filterRoot) wherePair(key string, value any, p *pg.Placeholder) (sql string, args []any, err error) {
+	if key == "$expr" {
+		// top-level $expr is handled by where
+		if r == documentRoot {
+			err = common.NewError(common.ErrNotImplemented, fmt.Errorf("$expr inside $and, $or and $nor can't be translated to SQL"))
+		} else {
+			err = common.NewError(common.ErrBadValue, fmt.Errorf("$expr can only be applied to the top-level document"))
+		}
+		return
+	}
+
 	if strings.HasPrefix(key, "$") {
-		exprs := value.(*types.Array)
-		sql, args, err = common.LogicExpr(key, exprs, p, wherePair)
+		sql, args, err = common.LogicExpr(key, value, p, r.wherePair)
+		return
+	}
+
+	var path []string
+	if path, err = types.ParsePath(key); err != nil {
+		err = lazyerrors.Errorf("wherePair: %w", err)
 		return
 	}
 
 	switch value := value.(type) {
 	case *types.Document:
 		// {field: {expr}}
-		sql, args, err = fieldExpr(key, value, p)
+		sql, args, err = r.fieldExpr(path, value, p)
+
+	case types.Regex:
+		// {field: /regex/}
+		sql, args, err = r.fieldExpr(path, types.MustNewDocument("$regex", value), p)
 
 	default:
 		// {field: value}
-		switch value.(type) {
-		case types.Regex:
-			sql = "_jsonb->>" + p.Next() + " ~ "
-		default:
-			sql = "_jsonb->" + p.Next() + " = "
-		}
-
-		args = append(args, key)
-
-		var scalarSQL string
-		var scalarArgs []any
-		scalarSQL, scalarArgs, err = scalar(value, p)
-		sql += scalarSQL
-		args = append(args, scalarArgs...)
+		sql, args, err = r.fieldExpr(path, types.MustNewDocument("$eq", value), p)
 	}
 
 	if err != nil {
@@ -230,35 +534,94 @@ func wherePair(key string, value any, p *pg.Placeholder) (sql string, args []any
 	return
 }
 
-func where(filter *types.Document, p *pg.Placeholder) (sql string, args []any, err error) {
-	if filter == nil {
-		return
-	}
+// conditions returns SQL conditions for all filter fields joined with AND.
+func (r filterRoot) conditions(filter *types.Document, p *pg.Placeholder) (sql string, args []any, err error) {
 	filterMap := filter.Map()
-	if len(filterMap) == 0 {
-		return
-	}
-
-	sql = " WHERE"
 
 	for i, key := range filter.Keys() {
 		value := filterMap[key]
 
 		if i != 0 {
-			sql += " AND"
+			sql += " AND "
 		}
 
 		var argSql string
 		var arg []any
-		argSql, arg, err = wherePair(key, value, p)
+		argSql, arg, err = r.wherePair(key, value, p)
 		if err != nil {
-			err = lazyerrors.Errorf("where: %w", err)
+			err = lazyerrors.Errorf("conditions: %w", err)
 			return
 		}
 
-		sql += " (" + argSql + ")"
+		sql += "(" + argSql + ")"
 		args = append(args, arg...)
 	}
 
 	return
 }
+
+// where returns SQL WHERE clause for the filter.
+//
+// Top-level filter fields that can't be translated to SQL are returned as residual filter
+// for checking fetched documents with common.FilterDocument (see matchResidual);
+// that costs performance, but not correctness. Residual is nil if the whole filter was translated.
+// Top-level $expr is always returned as a part of residual filter, but it is also used for SQL pre-filtering.
+func where(filter *types.Document, p *pg.Placeholder) (sql string, args []any, residual *types.Document, err error) {
+	var conditions []string
+	for _, key := range filter.Keys() {
+		value := must.NotFail(filter.Get(key))
+
+		if key == "$expr" {
+			exprSQL, exprArgs := exprCondition(value, p)
+			conditions = append(conditions, exprSQL)
+			args = append(args, exprArgs...)
+			residual = residualSet(residual, key, value)
+			continue
+		}
+
+		saved := *p
+
+		var pairSQL string
+		var pairArgs []any
+		if pairSQL, pairArgs, err = documentRoot.wherePair(key, value, p); err != nil {
+			if protoErr, ok := common.ProtocolError(err); ok && protoErr.Code() == common.ErrNotImplemented {
+				// filter fetched documents in Go instead; unused placeholders are reused
+				*p = saved
+				residual = residualSet(residual, key, value)
+				err = nil
+				continue
+			}
+
+			err = lazyerrors.Errorf("where: %w", err)
+			return
+		}
+
+		conditions = append(conditions, "("+pairSQL+")")
+		args = append(args, pairArgs...)
+	}
+
+	if len(conditions) != 0 {
+		sql = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return
+}
+
+// residualSet adds the filter field to the residual filter, creating it if needed.
+func residualSet(residual *types.Document, key string, value any) *types.Document {
+	if residual == nil {
+		residual = types.MustNewDocument()
+	}
+
+	must.NoError(residual.Set(key, value))
+	return residual
+}
+
+// matchResidual returns true if the document matches residual filter returned by where, or if it is nil.
+func matchResidual(doc, residual *types.Document) (bool, error) {
+	if residual == nil {
+		return true, nil
+	}
+
+	return common.FilterDocument(doc, residual)
+}
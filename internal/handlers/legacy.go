@@ -0,0 +1,284 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/FerretDB/FerretDB/internal/handlers/common"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+	"github.com/FerretDB/FerretDB/internal/wire"
+)
+
+// splitNamespace splits full collection name of legacy messages into database and collection names.
+func splitNamespace(ns string) (db, collection string, err error) {
+	var ok bool
+	if db, collection, ok = strings.Cut(ns, "."); !ok || db == "" || collection == "" {
+		return "", "", common.NewError(common.ErrInvalidNamespace, fmt.Errorf("invalid namespace specified '%s'", ns))
+	}
+
+	return
+}
+
+// isCommandNamespace returns true if full collection name of OP_QUERY message is <db>.$cmd.
+func isCommandNamespace(ns string) bool {
+	return strings.HasSuffix(ns, ".$cmd")
+}
+
+// handleLegacyCommand handles command document built from the legacy message
+// by the existing OP_MSG command implementation.
+func (h *Handler) handleLegacyCommand(ctx context.Context, document *types.Document) (*types.Document, error) {
+	var msg wire.OpMsg
+	if err := msg.SetSections(wire.OpMsgSection{
+		Documents: []*types.Document{document},
+	}); err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	res, err := h.handleOpMsg(ctx, &msg, document.Command())
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Document()
+}
+
+// handleOpInsert handles legacy OP_INSERT message with insert command.
+func (h *Handler) handleOpInsert(ctx context.Context, insert *wire.OpInsert) error {
+	db, collection, err := splitNamespace(insert.FullCollectionName)
+	if err != nil {
+		return err
+	}
+
+	docs := types.MakeArray(len(insert.Documents))
+	for _, d := range insert.Documents {
+		if err = docs.Append(d); err != nil {
+			return lazyerrors.Error(err)
+		}
+	}
+
+	_, err = h.handleLegacyCommand(ctx, types.MustNewDocument(
+		"insert", collection,
+		"documents", docs,
+		"ordered", !insert.Flags.FlagSet(wire.OpInsertContinueOnError),
+		"$db", db,
+	))
+	return err
+}
+
+// handleOpUpdate handles legacy OP_UPDATE message with update command.
+func (h *Handler) handleOpUpdate(ctx context.Context, update *wire.OpUpdate) error {
+	db, collection, err := splitNamespace(update.FullCollectionName)
+	if err != nil {
+		return err
+	}
+
+	u := types.MustNewDocument(
+		"q", update.Selector,
+		"u", update.Update,
+	)
+
+	// set only flags that are present to match update commands sent by drivers
+	if update.Flags.FlagSet(wire.OpUpdateUpsert) {
+		if err = u.Set("upsert", true); err != nil {
+			return lazyerrors.Error(err)
+		}
+	}
+	if update.Flags.FlagSet(wire.OpUpdateMultiUpdate) {
+		if err = u.Set("multi", true); err != nil {
+			return lazyerrors.Error(err)
+		}
+	}
+
+	_, err = h.handleLegacyCommand(ctx, types.MustNewDocument(
+		"update", collection,
+		"updates", types.MustNewArray(u),
+		"$db", db,
+	))
+	return err
+}
+
+// handleOpDelete handles legacy OP_DELETE message with delete command.
+func (h *Handler) handleOpDelete(ctx context.Context, del *wire.OpDelete) error {
+	db, collection, err := splitNamespace(del.FullCollectionName)
+	if err != nil {
+		return err
+	}
+
+	var limit int32
+	if del.Flags.FlagSet(wire.OpDeleteSingleRemove) {
+		limit = 1
+	}
+
+	_, err = h.handleLegacyCommand(ctx, types.MustNewDocument(
+		"delete", collection,
+		"deletes", types.MustNewArray(types.MustNewDocument(
+			"q", del.Selector,
+			"limit", limit,
+		)),
+		"$db", db,
+	))
+	return err
+}
+
+// handleOpGetMore handles legacy OP_GET_MORE message.
+//
+// It replies with CursorNotFound flag set if the cursor does not exist.
+func (h *Handler) handleOpGetMore(ctx context.Context, getMore *wire.OpGetMore) (*wire.OpReply, error) {
+	if _, _, err := splitNamespace(getMore.FullCollectionName); err != nil {
+		return nil, err
+	}
+
+	// negative numberToReturn is used by some clients for the last batch
+	batchSize := getMore.NumberToReturn
+	if batchSize < 0 {
+		batchSize = -batchSize
+	}
+
+	batch, id, err := h.cursors.GetMore(getMore.CursorID, getMore.FullCollectionName, batchSize)
+	if err != nil {
+		if protoErr, ok := common.ProtocolError(err); ok && protoErr.Code() == common.ErrCursorNotFound {
+			return &wire.OpReply{
+				ResponseFlags: wire.OpReplyFlags(wire.OpReplyCursorNotFound),
+				Documents:     []*types.Document{},
+			}, nil
+		}
+		return nil, err
+	}
+
+	return batchReply(batch, id)
+}
+
+// handleOpKillCursors handles legacy OP_KILL_CURSORS message.
+func (h *Handler) handleOpKillCursors(ctx context.Context, kill *wire.OpKillCursors) error {
+	h.cursors.Kill("", kill.CursorIDs)
+	return nil
+}
+
+// batchReply returns OP_REPLY message with the given batch of documents and cursor id.
+func batchReply(batch *types.Array, id int64) (*wire.OpReply, error) {
+	reply := &wire.OpReply{
+		CursorID:       id,
+		NumberReturned: int32(batch.Len()),
+		Documents:      make([]*types.Document, batch.Len()),
+	}
+	for i := 0; i < batch.Len(); i++ {
+		v, err := batch.Get(i)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+		var ok bool
+		if reply.Documents[i], ok = v.(*types.Document); !ok {
+			return nil, lazyerrors.Errorf("unexpected document type %T", v)
+		}
+	}
+
+	return reply, nil
+}
+
+// handleOpQueryFind handles legacy OP_QUERY message against a real collection with find command.
+func (h *Handler) handleOpQueryFind(ctx context.Context, db, collection string, query *wire.OpQuery) (*wire.OpReply, error) {
+	filter, err := UnwrapQuery(query.Query)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	cmd := types.MustNewDocument(
+		"find", collection,
+		"filter", filter,
+	)
+
+	// $orderby modifier (or orderby) is only present if query is wrapped
+	if query.Query.Len() != 0 {
+		switch query.Query.Command() {
+		case "$query", "query":
+			m := query.Query.Map()
+			for _, k := range []string{"$orderby", "orderby"} {
+				if sort, ok := m[k].(*types.Document); ok {
+					if err = cmd.Set("sort", sort); err != nil {
+						return nil, lazyerrors.Error(err)
+					}
+				}
+			}
+		}
+	}
+
+	if query.ReturnFieldsSelector != nil {
+		if err = cmd.Set("projection", query.ReturnFieldsSelector); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+	}
+
+	if query.NumberToSkip != 0 {
+		if err = cmd.Set("skip", query.NumberToSkip); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+	}
+
+	// negative numberToReturn (and 1) means a single batch of that size;
+	// other positive values are batch sizes
+	switch n := query.NumberToReturn; {
+	case n < 0 || n == 1:
+		if n < 0 {
+			n = -n
+		}
+		if err = cmd.Set("limit", n); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		if err = cmd.Set("singleBatch", true); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+	case n > 1:
+		if err = cmd.Set("batchSize", n); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+	}
+
+	if query.Flags.FlagSet(wire.OpQueryNoCursorTimeout) {
+		if err = cmd.Set("noCursorTimeout", true); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+	}
+
+	if err = cmd.Set("$db", db); err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	res, err := h.handleLegacyCommand(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	firstBatch, err := res.GetByPath("cursor", "firstBatch")
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	batch, ok := firstBatch.(*types.Array)
+	if !ok {
+		return nil, lazyerrors.Errorf("unexpected firstBatch type %T", firstBatch)
+	}
+
+	id, err := res.GetByPath("cursor", "id")
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	return batchReply(batch, id.(int64))
+}
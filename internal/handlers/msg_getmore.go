@@ -0,0 +1,75 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/FerretDB/FerretDB/internal/handlers/common"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+	"github.com/FerretDB/FerretDB/internal/wire"
+)
+
+// MsgGetMore returns the next batch of documents of the cursor.
+func (h *Handler) MsgGetMore(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg, error) {
+	document, err := msg.Document()
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	common.Ignored(document, h.l, "maxTimeMS", "comment")
+
+	m := document.Map()
+	db := m["$db"].(string)
+
+	id, ok := m["getMore"].(int64)
+	if !ok {
+		return nil, common.NewError(common.ErrTypeMismatch, fmt.Errorf("BSON field 'getMore.getMore' should be a long"))
+	}
+
+	collection, ok := m["collection"].(string)
+	if !ok {
+		return nil, common.NewError(common.ErrTypeMismatch, fmt.Errorf("BSON field 'getMore.collection' should be a string"))
+	}
+
+	batchSize, err := common.GetBatchSize(document, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	nextBatch, id, err := h.cursors.GetMore(id, db+"."+collection, batchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply wire.OpMsg
+	err = reply.SetSections(wire.OpMsgSection{
+		Documents: []*types.Document{types.MustNewDocument(
+			"cursor", types.MustNewDocument(
+				"nextBatch", nextBatch,
+				"id", id,
+				"ns", db+"."+collection,
+			),
+			"ok", float64(1),
+		)},
+	})
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	return &reply, nil
+}
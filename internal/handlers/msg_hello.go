@@ -25,24 +25,37 @@ import (
 
 // MsgHello returns a document that describes the role of the instance.
 func (h *Handler) MsgHello(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg, error) {
+	document, err := msg.Document()
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	res := types.MustNewDocument(
+		"helloOk", true,
+		"ismaster", true,
+		// topologyVersion
+		"maxBsonObjectSize", int32(types.MaxDocumentLen),
+		"maxMessageSizeBytes", int32(wire.MaxMsgLen),
+		"maxWriteBatchSize", int32(100000),
+		"localTime", time.Now(),
+		// logicalSessionTimeoutMinutes
+		// connectionId
+		"minWireVersion", int32(13),
+		"maxWireVersion", int32(13),
+		"readOnly", false,
+	)
+
+	if err = setCompression(res, document); err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	if err = res.Set("ok", float64(1)); err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
 	var reply wire.OpMsg
-	err := reply.SetSections(wire.OpMsgSection{
-		// TODO merge with QueryCmd
-		Documents: []*types.Document{types.MustNewDocument(
-			"helloOk", true,
-			"ismaster", true,
-			// topologyVersion
-			"maxBsonObjectSize", int32(types.MaxDocumentLen),
-			"maxMessageSizeBytes", int32(wire.MaxMsgLen),
-			"maxWriteBatchSize", int32(100000),
-			"localTime", time.Now(),
-			// logicalSessionTimeoutMinutes
-			// connectionId
-			"minWireVersion", int32(13),
-			"maxWireVersion", int32(13),
-			"readOnly", false,
-			"ok", float64(1),
-		)},
+	err = reply.SetSections(wire.OpMsgSection{
+		Documents: []*types.Document{res},
 	})
 	if err != nil {
 		return nil, lazyerrors.Error(err)
@@ -50,3 +63,21 @@ func (h *Handler) MsgHello(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg, e
 
 	return &reply, nil
 }
+
+// setCompression sets "compression" field of hello or isMaster reply
+// to the list of compressors negotiated with the client, if any.
+func setCompression(reply, request *types.Document) error {
+	compressors := wire.NegotiateCompressors(request)
+	if len(compressors) == 0 {
+		return nil
+	}
+
+	names := types.MakeArray(len(compressors))
+	for _, c := range compressors {
+		if err := names.Append(c.String()); err != nil {
+			return lazyerrors.Error(err)
+		}
+	}
+
+	return reply.Set("compression", names)
+}
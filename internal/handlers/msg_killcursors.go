@@ -0,0 +1,90 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/FerretDB/FerretDB/internal/handlers/common"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+	"github.com/FerretDB/FerretDB/internal/wire"
+)
+
+// MsgKillCursors closes cursors of the collection.
+func (h *Handler) MsgKillCursors(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg, error) {
+	document, err := msg.Document()
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	common.Ignored(document, h.l, "comment")
+
+	m := document.Map()
+	db := m["$db"].(string)
+
+	collection, ok := m[document.Command()].(string)
+	if !ok {
+		return nil, common.NewError(common.ErrTypeMismatch, fmt.Errorf("BSON field 'killCursors.killCursors' should be a string"))
+	}
+
+	cursors, ok := m["cursors"].(*types.Array)
+	if !ok {
+		return nil, common.NewError(common.ErrTypeMismatch, fmt.Errorf("BSON field 'killCursors.cursors' should be an array"))
+	}
+
+	ids := make([]int64, cursors.Len())
+	for i := range ids {
+		v, err := cursors.Get(i)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+		if ids[i], ok = v.(int64); !ok {
+			err = fmt.Errorf("BSON field 'killCursors.cursors.%d' should be a long", i)
+			return nil, common.NewError(common.ErrTypeMismatch, err)
+		}
+	}
+
+	killed, notFound := h.cursors.Kill(db+"."+collection, ids)
+
+	var reply wire.OpMsg
+	err = reply.SetSections(wire.OpMsgSection{
+		Documents: []*types.Document{types.MustNewDocument(
+			"cursorsKilled", idsArray(killed),
+			"cursorsNotFound", idsArray(notFound),
+			"cursorsAlive", types.MakeArray(0),
+			"cursorsUnknown", types.MakeArray(0),
+			"ok", float64(1),
+		)},
+	})
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	return &reply, nil
+}
+
+// idsArray returns an array of cursor ids.
+func idsArray(ids []int64) *types.Array {
+	arr := types.MakeArray(len(ids))
+	for _, id := range ids {
+		must.NoError(arr.Append(id))
+	}
+
+	return arr
+}
@@ -50,20 +50,43 @@ func (h *Handler) Close() {
 	h.conn.Close()
 }
 
-// Handle "handles" the message by sending it to another wire protocol compatible service.
+// Handle "handles" the message by sending it to another wire protocol compatible service
+// and reading the response.
 //
 // Returned error is something fatal.
 func (h *Handler) Handle(ctx context.Context, header *wire.MsgHeader, body wire.MsgBody) (*wire.MsgHeader, wire.MsgBody, error) {
+	if err := h.Send(ctx, header, body); err != nil {
+		return nil, nil, err
+	}
+
+	return h.Receive(ctx)
+}
+
+// Send sends the message to another wire protocol compatible service without waiting for the response.
+//
+// It should be used directly for messages that do not expect a response (with moreToCome flag).
+// Returned error is something fatal.
+func (h *Handler) Send(ctx context.Context, header *wire.MsgHeader, body wire.MsgBody) error {
 	deadline, _ := ctx.Deadline()
 	h.conn.SetDeadline(deadline)
 
 	if err := wire.WriteMessage(h.bufw, header, body); err != nil {
-		return nil, nil, err
+		return err
 	}
 
 	if err := h.bufw.Flush(); err != nil {
-		return nil, nil, lazyerrors.Error(err)
+		return lazyerrors.Error(err)
 	}
 
+	return nil
+}
+
+// Receive reads the next message from another wire protocol compatible service.
+//
+// Returned error is something fatal.
+func (h *Handler) Receive(ctx context.Context) (*wire.MsgHeader, wire.MsgBody, error) {
+	deadline, _ := ctx.Deadline()
+	h.conn.SetDeadline(deadline)
+
 	return wire.ReadMessage(h.bufr)
 }
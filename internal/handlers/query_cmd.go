@@ -16,41 +16,60 @@ package handlers
 
 import (
 	"context"
-	"fmt"
-	"time"
 
-	"github.com/FerretDB/FerretDB/internal/handlers/common"
 	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
 	"github.com/FerretDB/FerretDB/internal/wire"
 )
 
-func (h *Handler) QueryCmd(ctx context.Context, query *wire.OpQuery) (*wire.OpReply, error) {
-	switch cmd := query.Query.Command(); cmd {
-	case "ismaster":
-		// TODO merge with MsgHello
-		reply := &wire.OpReply{
-			NumberReturned: 1,
-			Documents: []*types.Document{
-				types.MustNewDocument(
-					"helloOk", true,
-					"ismaster", true,
-					// topologyVersion
-					"maxBsonObjectSize", int32(types.MaxDocumentLen),
-					"maxMessageSizeBytes", int32(wire.MaxMsgLen),
-					"maxWriteBatchSize", int32(100000),
-					"localTime", time.Now(),
-					// logicalSessionTimeoutMinutes
-					// connectionId
-					"minWireVersion", int32(13),
-					"maxWireVersion", int32(13),
-					"readOnly", false,
-					"ok", float64(1),
-				),
-			},
+// QueryCmd handles command sent as OP_QUERY against <db>.$cmd collection
+// by the same implementation as OP_MSG command.
+//
+// Command errors are returned as is; see Handle for how they are sent to the client.
+func (h *Handler) QueryCmd(ctx context.Context, db string, query *wire.OpQuery) (*wire.OpReply, error) {
+	document, err := UnwrapQuery(query.Query)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	if err = document.Set("$db", db); err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	res, err := h.handleLegacyCommand(ctx, document)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &wire.OpReply{
+		NumberReturned: 1,
+		Documents:      []*types.Document{res},
+	}
+	return reply, nil
+}
+
+// UnwrapQuery returns a copy of the OP_QUERY query document
+// without query modifiers like $readPreference.
+//
+// The query may be wrapped into $query or query field together with modifiers;
+// otherwise, the query document itself is returned.
+func UnwrapQuery(query *types.Document) (*types.Document, error) {
+	if query.Len() != 0 {
+		switch query.Command() {
+		case "$query", "query":
+			inner, ok := query.Map()[query.Keys()[0]].(*types.Document)
+			if ok {
+				query = inner
+			}
 		}
-		return reply, nil
+	}
 
-	default:
-		return nil, common.NewError(common.ErrNotImplemented, fmt.Errorf("QueryCmd: unhandled command %q", cmd))
+	res := new(types.Document)
+	for _, k := range query.Keys() {
+		if err := res.Set(k, query.Map()[k]); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
 	}
+
+	return res, nil
 }
@@ -15,9 +15,22 @@
 package sql
 
 import (
+	"math/big"
+	"strconv"
+
+	"github.com/jackc/pgtype"
 	"github.com/jackc/pgx/v4"
 
 	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+)
+
+const (
+	// decimal128Digits is the number of significant decimal digits in Decimal128 values.
+	decimal128Digits = 34
+
+	// decimal128MinExponent is the minimal exponent of Decimal128 values.
+	decimal128MinExponent = -6176
 )
 
 type rowInfo struct {
@@ -52,9 +65,92 @@ func nextRow(rows pgx.Rows, rowInfo *rowInfo) (*types.Document, error) {
 	pairs := make([]any, len(values)*2)
 	for i, v := range values {
 		pairs[i*2] = rowInfo.names[i]
-		pairs[i*2+1] = v
+		if pairs[i*2+1], err = convertValue(v); err != nil {
+			return nil, lazyerrors.Errorf("column %q: %w", rowInfo.names[i], err)
+		}
 	}
 
 	doc := types.MustNewDocument(pairs...)
 	return doc, nil
 }
+
+// convertValue converts value returned by pgx to the value of the type listed in columnTypes.
+func convertValue(v any) (any, error) {
+	switch v := v.(type) {
+	case nil:
+		return types.Null, nil
+	case int16:
+		return int32(v), nil
+	case float32:
+		return float64(v), nil
+	case []byte:
+		return types.Binary{Subtype: types.BinaryGeneric, B: v}, nil
+	case pgtype.InfinityModifier:
+		if v == pgtype.NegativeInfinity {
+			return types.ParseDecimal128("-Infinity")
+		}
+		return types.ParseDecimal128("Infinity")
+	case pgtype.Numeric:
+		if v.NaN {
+			return types.ParseDecimal128("NaN")
+		}
+		return numericToDecimal128(v)
+	default:
+		return v, nil
+	}
+}
+
+// numericToDecimal128 converts PostgreSQL numeric value to Decimal128.
+//
+// Values that can't be represented exactly are rounded to 34 significant digits (half to even);
+// too large values are converted to infinities, and too small values are rounded to zero.
+func numericToDecimal128(v pgtype.Numeric) (types.Decimal128, error) {
+	neg := v.Int.Sign() < 0
+	coef := new(big.Int).Abs(v.Int)
+	exp := int(v.Exp)
+
+	// pgx removes trailing zeros of integers, restore them to keep the scale
+	if exp > 0 {
+		coef.Mul(coef, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+		exp = 0
+	}
+
+	// number of the least significant digits to round off
+	round := len(coef.String()) - decimal128Digits
+	if r := decimal128MinExponent - exp; r > round {
+		round = r
+	}
+
+	if round > 0 {
+		div := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(round)), nil)
+		rem := new(big.Int)
+		coef.QuoRem(coef, div, rem)
+
+		switch rem.Lsh(rem, 1).Cmp(div) {
+		case 1:
+			coef.Add(coef, big.NewInt(1))
+		case 0:
+			if coef.Bit(0) == 1 {
+				coef.Add(coef, big.NewInt(1))
+			}
+		}
+
+		exp += round
+	}
+
+	s := coef.String() + "E" + strconv.Itoa(exp)
+	if neg {
+		s = "-" + s
+	}
+
+	d, err := types.ParseDecimal128(s)
+	if err != nil {
+		// the rounded value fits into 34 digits, so it can only overflow
+		if neg {
+			return types.ParseDecimal128("-Infinity")
+		}
+		return types.ParseDecimal128("Infinity")
+	}
+
+	return d, nil
+}
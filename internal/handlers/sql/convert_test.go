@@ -15,13 +15,16 @@
 package sql
 
 import (
+	"math/big"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgtype"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/must"
 )
 
 func TestConvert(t *testing.T) {
@@ -47,3 +50,89 @@ func TestConvert(t *testing.T) {
 	)
 	assert.Equal(t, expected, doc)
 }
+
+func TestConvertValue(t *testing.T) {
+	t.Parallel()
+
+	bigInt := func(s string) *big.Int {
+		i, ok := new(big.Int).SetString(s, 10)
+		require.True(t, ok)
+		return i
+	}
+
+	for name, tc := range map[string]struct {
+		v        any
+		expected any
+	}{
+		"Null": {
+			v:        nil,
+			expected: types.Null,
+		},
+		"Int2": {
+			v:        int16(-42),
+			expected: int32(-42),
+		},
+		"Float4": {
+			v:        float32(1.5),
+			expected: float64(1.5),
+		},
+		"Bytea": {
+			v:        []byte{0x01},
+			expected: types.Binary{Subtype: types.BinaryGeneric, B: []byte{0x01}},
+		},
+		"Numeric": {
+			v:        pgtype.Numeric{Int: big.NewInt(-25), Exp: -1, Status: pgtype.Present},
+			expected: must.NotFail(types.ParseDecimal128("-2.5")),
+		},
+		"NumericInteger": {
+			v:        pgtype.Numeric{Int: big.NewInt(1), Exp: 2, Status: pgtype.Present},
+			expected: must.NotFail(types.ParseDecimal128("100")),
+		},
+		"NumericLeadingZeros": {
+			v:        pgtype.Numeric{Int: big.NewInt(1), Exp: -3, Status: pgtype.Present},
+			expected: must.NotFail(types.ParseDecimal128("0.001")),
+		},
+		"NumericRounded": {
+			// 35 digits, the last one is rounded half to even
+			v:        pgtype.Numeric{Int: bigInt("-12345678901234567890123456789012345"), Exp: -5, Status: pgtype.Present},
+			expected: must.NotFail(types.ParseDecimal128("-123456789012345678901234567890.1234")),
+		},
+		"NumericRoundedUp": {
+			v:        pgtype.Numeric{Int: bigInt("99999999999999999999999999999999999"), Status: pgtype.Present},
+			expected: must.NotFail(types.ParseDecimal128("1000000000000000000000000000000000E2")),
+		},
+		"NumericLargeInteger": {
+			v:        pgtype.Numeric{Int: big.NewInt(1), Exp: 40, Status: pgtype.Present},
+			expected: must.NotFail(types.ParseDecimal128("1000000000000000000000000000000000E7")),
+		},
+		"NumericOverflow": {
+			v:        pgtype.Numeric{Int: big.NewInt(-1), Exp: 7000, Status: pgtype.Present},
+			expected: must.NotFail(types.ParseDecimal128("-Infinity")),
+		},
+		"NumericUnderflow": {
+			v:        pgtype.Numeric{Int: big.NewInt(1), Exp: -7000, Status: pgtype.Present},
+			expected: must.NotFail(types.ParseDecimal128("0E-6176")),
+		},
+		"NumericNaN": {
+			v:        pgtype.Numeric{NaN: true, Status: pgtype.Present},
+			expected: must.NotFail(types.ParseDecimal128("NaN")),
+		},
+		"NumericInfinity": {
+			v:        pgtype.Infinity,
+			expected: must.NotFail(types.ParseDecimal128("Infinity")),
+		},
+		"Text": {
+			v:        "foo",
+			expected: "foo",
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := convertValue(tc.v)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
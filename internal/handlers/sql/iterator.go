@@ -0,0 +1,38 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import "github.com/FerretDB/FerretDB/internal/types"
+
+// docsIterator returns already fetched documents, implementing common.CursorIterator.
+type docsIterator struct {
+	docs []*types.Document
+}
+
+// Next implements common.CursorIterator interface.
+func (it *docsIterator) Next() (*types.Document, error) {
+	if len(it.docs) == 0 {
+		return nil, nil
+	}
+
+	doc := it.docs[0]
+	it.docs = it.docs[1:]
+	return doc, nil
+}
+
+// Close implements common.CursorIterator interface.
+func (it *docsIterator) Close() {
+	it.docs = nil
+}
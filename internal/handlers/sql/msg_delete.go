@@ -44,6 +44,11 @@ func (s *storage) MsgDelete(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 	db := m["$db"].(string)
 	docs, _ := m["deletes"].(*types.Array)
 
+	cols, err := s.columns(ctx, db, collection)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
 	var deleted int32
 	for i := 0; i < docs.Len(); i++ {
 		doc, err := docs.Get(i)
@@ -60,7 +65,7 @@ func (s *storage) MsgDelete(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 		sql := fmt.Sprintf(`DELETE FROM %s`, pgx.Identifier{db, collection}.Sanitize())
 		var placeholder pg.Placeholder
 
-		elSQL, args, err := where(d["q"].(*types.Document), &placeholder)
+		elSQL, args, err := where(d["q"].(*types.Document), cols, &placeholder)
 		if err != nil {
 			return nil, lazyerrors.Error(err)
 		}
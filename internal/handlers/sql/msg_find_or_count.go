@@ -30,8 +30,6 @@ import (
 // MsgFindOrCount finds documents in a collection or view and returns a cursor to the selected documents
 // or count the number of documents that matches the query filter.
 func (s *storage) MsgFindOrCount(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg, error) {
-	// TODO cursor / getMore support via https://www.postgresql.org/docs/current/sql-declare.html
-
 	document, err := msg.Document()
 	if err != nil {
 		return nil, lazyerrors.Error(err)
@@ -43,7 +41,6 @@ func (s *storage) MsgFindOrCount(ctx context.Context, msg *wire.OpMsg) (*wire.Op
 		"showRecordId",
 		"tailable",
 		"oplogReplay",
-		"noCursorTimeout",
 		"awaitData",
 		"allowPartialResults",
 		"collation",
@@ -55,8 +52,6 @@ func (s *storage) MsgFindOrCount(ctx context.Context, msg *wire.OpMsg) (*wire.Op
 	}
 	ignoredFields := []string{
 		"hint",
-		"batchSize",
-		"singleBatch",
 		"comment",
 		"maxTimeMS",
 		"readConcern",
@@ -94,10 +89,22 @@ func (s *storage) MsgFindOrCount(ctx context.Context, msg *wire.OpMsg) (*wire.Op
 	}
 	sort, _ := m["sort"].(*types.Document)
 	limit, _ := m["limit"].(int32)
+	singleBatch, _ := m["singleBatch"].(bool)
+	noCursorTimeout, _ := m["noCursorTimeout"].(bool)
+
+	batchSize, err := common.GetBatchSize(document, common.DefaultBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	cols, err := s.columns(ctx, db, collection)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
 
 	var placeholder pg.Placeholder
 
-	whereSQL, args, err := where(filter, &placeholder)
+	whereSQL, args, err := where(filter, cols, &placeholder)
 	if err != nil {
 		return nil, lazyerrors.Error(err)
 	}
@@ -144,7 +151,7 @@ func (s *storage) MsgFindOrCount(ctx context.Context, msg *wire.OpMsg) (*wire.Op
 	if isFindOp { //nolint:nestif // TODO simplify
 		rowInfo := extractRowInfo(rows)
 
-		var docs types.Array
+		var docs []*types.Document
 
 		for {
 			doc, err := nextRow(rows, rowInfo)
@@ -155,16 +162,27 @@ func (s *storage) MsgFindOrCount(ctx context.Context, msg *wire.OpMsg) (*wire.Op
 				break
 			}
 
-			if err = docs.Append(doc); err != nil {
-				return nil, lazyerrors.Error(err)
-			}
+			docs = append(docs, doc)
+		}
+
+		// all rows are already read, so the cursor does not hold the connection
+		var firstBatch *types.Array
+		var id int64
+		firstBatch, id, err = s.cursors.FirstBatch(&docsIterator{docs: docs}, &common.CursorOpts{
+			NS:          db + "." + collection,
+			BatchSize:   batchSize,
+			SingleBatch: singleBatch,
+			NoTimeout:   noCursorTimeout,
+		})
+		if err != nil {
+			return nil, err
 		}
 
 		err = res.SetSections(wire.OpMsgSection{
 			Documents: []*types.Document{types.MustNewDocument(
 				"cursor", types.MustNewDocument(
-					"firstBatch", &docs,
-					"id", int64(0),
+					"firstBatch", firstBatch,
+					"id", id,
 					"ns", db+"."+collection,
 				),
 				"ok", float64(1),
@@ -49,6 +49,12 @@ func (s *storage) MsgInsert(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 	// TODO https://github.com/FerretDB/FerretDB/issues/200
 	_ = ordered
 
+	cols, err := s.columns(ctx, db, collection)
+	if err != nil {
+		return nil, err
+	}
+	_, idColumn := cols["_id"]
+
 	var inserted int32
 	for i := 0; i < docs.Len(); i++ {
 		doc, err := docs.Get(i)
@@ -56,16 +62,34 @@ func (s *storage) MsgInsert(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 			return nil, lazyerrors.Error(err)
 		}
 
-		d := doc.(*types.Document)
+		_, hasID := doc.(*types.Document).Map()["_id"]
+		d, err := common.PrepareDocumentForInsert(doc.(*types.Document))
+		if err != nil {
+			return nil, err
+		}
+
 		m := d.Map()
 
 		sql := fmt.Sprintf("INSERT INTO %s (", pgx.Identifier{db, collection}.Sanitize())
 		var args []any
 
 		for _, k := range d.Keys() {
-			// TODO
+			v := m[k]
+
+			// _id is stored in the _id column; tables without it can't store explicit _id,
+			// and generated _id is not stored
 			if k == "_id" {
-				continue
+				if !idColumn {
+					if hasID {
+						return nil, common.NewError(common.ErrNotImplemented, fmt.Errorf("insert: table %q has no _id column", collection))
+					}
+					continue
+				}
+
+				// ObjectID is stored as bytea
+				if id, ok := v.(types.ObjectID); ok {
+					v = id[:]
+				}
 			}
 
 			if len(args) != 0 {
@@ -73,7 +97,7 @@ func (s *storage) MsgInsert(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg,
 			}
 
 			sql += pgx.Identifier{k}.Sanitize()
-			args = append(args, m[k])
+			args = append(args, v)
 		}
 
 		sql += ") VALUES ("
@@ -15,20 +15,50 @@
 package sql
 
 import (
+	"context"
+
 	"go.uber.org/zap"
 
 	"github.com/FerretDB/FerretDB/internal/handlers/common"
 	"github.com/FerretDB/FerretDB/internal/pg"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
 )
 
 type storage struct {
-	pgPool *pg.Pool
-	l      *zap.SugaredLogger
+	pgPool  *pg.Pool
+	l       *zap.SugaredLogger
+	cursors *common.Cursors
 }
 
-func NewStorage(pgPool *pg.Pool, l *zap.SugaredLogger) common.Storage {
+func NewStorage(pgPool *pg.Pool, l *zap.SugaredLogger, cursors *common.Cursors) common.Storage {
 	return &storage{
-		pgPool: pgPool,
-		l:      l,
+		pgPool:  pgPool,
+		l:       l,
+		cursors: cursors,
 	}
 }
+
+// columns returns columns of the given table.
+func (s *storage) columns(ctx context.Context, db, collection string) (columns, error) {
+	sql := `SELECT column_name, udt_name FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2`
+	rows, err := s.pgPool.Query(ctx, sql, db, collection)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+	defer rows.Close()
+
+	res := make(columns)
+	var name, pgType string
+	for rows.Next() {
+		if err = rows.Scan(&name, &pgType); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		res[name] = pgType
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	return res, nil
+}
@@ -31,28 +31,85 @@ func scalar(v any, p *pg.Placeholder) (sql string, args []any, err error) {
 
 	switch v := v.(type) {
 	case types.Regex:
-		var options string
-		for _, o := range v.Options {
-			switch o {
-			case 'i':
-				options += "i"
-			default:
-				err = lazyerrors.Errorf("scalar: unhandled regex option %v (%v)", o, v)
-			}
-		}
-		s := v.Pattern
-		if options != "" {
-			s = "(?" + options + ")" + v.Pattern
+		var pattern string
+		if pattern, err = common.RegexPattern(v); err != nil {
+			err = lazyerrors.Errorf("scalar: %w", err)
+			return
 		}
-		args = []any{s}
+		args = []any{pattern}
 	default:
 		args = []any{v}
 	}
 	return
 }
 
+// columns maps column names of the queried table to PostgreSQL type names.
+type columns map[string]string
+
+// columnTypes maps PostgreSQL type names to $type operator aliases of values returned for them.
+var columnTypes = map[string]string{
+	"int2":        "int",
+	"int4":        "int",
+	"int8":        "long",
+	"float4":      "double",
+	"float8":      "double",
+	"numeric":     "decimal",
+	"text":        "string",
+	"varchar":     "string",
+	"bpchar":      "string",
+	"bool":        "bool",
+	"bytea":       "binData",
+	"date":        "date",
+	"timestamp":   "date",
+	"timestamptz": "date",
+}
+
+// isNumeric returns true if the given field is a column with numeric values.
+func (c columns) isNumeric(field string) bool {
+	switch columnTypes[c[field]] {
+	case "int", "long", "double", "decimal":
+		return true
+	default:
+		return false
+	}
+}
+
+// typeExpr handles {field: {$type: value}}.
+//
+// Non-NULL values match the type of the column, NULL values match "null".
+func (c columns) typeExpr(field string, value any) (sql string, err error) {
+	var aliases []string
+	if aliases, err = common.ParseTypes(value); err != nil {
+		return
+	}
+
+	pgType, ok := c[field]
+	if !ok {
+		sql = "FALSE"
+		return
+	}
+
+	var exprs []string
+	for _, alias := range aliases {
+		switch alias {
+		case columnTypes[pgType]:
+			exprs = append(exprs, pgx.Identifier{field}.Sanitize()+" IS NOT NULL")
+		case "null":
+			exprs = append(exprs, pgx.Identifier{field}.Sanitize()+" IS NULL")
+		}
+	}
+
+	if len(exprs) == 0 {
+		sql = "FALSE"
+		return
+	}
+
+	sql = "(" + strings.Join(exprs, " OR ") + ")"
+	return
+}
+
 // fieldExpr handles {field: {expr}}.
-func fieldExpr(field string, expr *types.Document, p *pg.Placeholder) (sql string, args []any, err error) {
+func (c columns) fieldExpr(field string, expr *types.Document, p *pg.Placeholder) (sql string, args []any, err error) {
 	filterKeys := expr.Keys()
 	filterMap := expr.Map()
 
@@ -77,7 +134,13 @@ func fieldExpr(field string, expr *types.Document, p *pg.Placeholder) (sql strin
 			}
 			sql += "NOT("
 
-			argSql, arg, err = fieldExpr(field, value.(*types.Document), p)
+			var notExpr *types.Document
+			if notExpr, err = common.NotExpr(value); err != nil {
+				err = lazyerrors.Errorf("fieldExpr: %w", err)
+				return
+			}
+
+			argSql, arg, err = c.fieldExpr(field, notExpr, p)
 			if err != nil {
 				err = lazyerrors.Errorf("fieldExpr: %w", err)
 				return
@@ -92,6 +155,63 @@ func fieldExpr(field string, expr *types.Document, p *pg.Placeholder) (sql strin
 		if sql != "" {
 			sql += " "
 		}
+
+		switch op {
+		case "$exists":
+			// {field: {$exists: bool}}
+			if _, ok := c[field]; ok == common.Truthy(value) {
+				sql += "TRUE"
+			} else {
+				sql += "FALSE"
+			}
+			continue
+
+		case "$type":
+			// {field: {$type: alias}}
+			// {field: {$type: [alias1, alias2, ...]}}
+			if argSql, err = c.typeExpr(field, value); err != nil {
+				err = lazyerrors.Errorf("fieldExpr: %w", err)
+				return
+			}
+			sql += argSql
+			continue
+
+		case "$mod":
+			// {field: {$mod: [divisor, remainder]}}
+			var divisor, remainder int64
+			if divisor, remainder, err = common.ParseMod(value); err != nil {
+				err = lazyerrors.Errorf("fieldExpr: %w", err)
+				return
+			}
+
+			if !c.isNumeric(field) {
+				sql += "FALSE"
+				continue
+			}
+
+			sql += "trunc(" + pgx.Identifier{field}.Sanitize() + "::numeric) % " + p.Next() + "::bigint = " + p.Next() + "::bigint"
+			args = append(args, divisor, remainder)
+			continue
+
+		case "$bitsAllSet", "$bitsAnySet", "$bitsAllClear", "$bitsAnyClear":
+			// {field: {$bitsAllSet: mask}}
+			var positions []int
+			if positions, err = common.ParseBitmask(op, value); err != nil {
+				err = lazyerrors.Errorf("fieldExpr: %w", err)
+				return
+			}
+
+			if !c.isNumeric(field) {
+				sql += "FALSE"
+				continue
+			}
+
+			argSql, arg = common.BitsExpr(op, common.Int64Expr(pgx.Identifier{field}.Sanitize()+"::numeric"), positions, p)
+			sql += argSql
+			args = append(args, arg...)
+			continue
+		}
+
 		sql += pgx.Identifier{field}.Sanitize()
 
 		switch op {
@@ -179,17 +299,21 @@ func fieldExpr(field string, expr *types.Document, p *pg.Placeholder) (sql strin
 	return
 }
 
-func wherePair(key string, value any, p *pg.Placeholder) (sql string, args []any, err error) {
+func (c columns) wherePair(key string, value any, p *pg.Placeholder) (sql string, args []any, err error) {
+	if key == "$expr" {
+		err = common.NewError(common.ErrNotImplemented, fmt.Errorf("$expr is not implemented for SQL tables yet"))
+		return
+	}
+
 	if strings.HasPrefix(key, "$") {
-		exprs := value.(*types.Array)
-		sql, args, err = common.LogicExpr(key, exprs, p, wherePair)
+		sql, args, err = common.LogicExpr(key, value, p, c.wherePair)
 		return
 	}
 
 	switch value := value.(type) {
 	case *types.Document:
 		// {field: {expr}}
-		sql, args, err = fieldExpr(key, value, p)
+		sql, args, err = c.fieldExpr(key, value, p)
 
 	default:
 		// {field: value}
@@ -209,7 +333,7 @@ func wherePair(key string, value any, p *pg.Placeholder) (sql string, args []any
 	return
 }
 
-func where(filter *types.Document, p *pg.Placeholder) (sql string, args []any, err error) {
+func where(filter *types.Document, c columns, p *pg.Placeholder) (sql string, args []any, err error) {
 	if filter == nil {
 		return
 	}
@@ -229,7 +353,7 @@ func where(filter *types.Document, p *pg.Placeholder) (sql string, args []any, e
 
 		var argSql string
 		var arg []any
-		argSql, arg, err = wherePair(key, value, p)
+		argSql, arg, err = c.wherePair(key, value, p)
 		if err != nil {
 			err = lazyerrors.Errorf("where: %w", err)
 			return
@@ -52,6 +52,9 @@ var (
 // Pool data struct for *pgxpool.Pool.
 type Pool struct {
 	*pgxpool.Pool
+
+	// streams limits the number of connections held by long-lived rows streams (see TryAcquireStream)
+	streams chan struct{}
 }
 
 // TableStats describes some statistics for a table.
@@ -107,8 +110,10 @@ func NewPool(connString string, logger *zap.Logger, lazy bool) (*Pool, error) {
 		return nil, fmt.Errorf("pg.NewPool: %w", err)
 	}
 
+	// reserve at least half of the connections for normal requests
 	res := &Pool{
-		Pool: p,
+		Pool:    p,
+		streams: make(chan struct{}, config.MaxConns/2),
 	}
 
 	if !lazy {
@@ -118,6 +123,20 @@ func NewPool(connString string, logger *zap.Logger, lazy bool) (*Pool, error) {
 	return res, err
 }
 
+// TryAcquireStream reserves a connection for a long-lived rows stream, like a server-side cursor
+// that is read by several requests.
+//
+// It returns false if too many connections are already used that way, so a query result should not be kept open.
+// Otherwise, the returned function should be called after the stream is closed.
+func (pgPool *Pool) TryAcquireStream() (release func(), ok bool) {
+	select {
+	case pgPool.streams <- struct{}{}:
+		return func() { <-pgPool.streams }, true
+	default:
+		return nil, false
+	}
+}
+
 // IsValidUTF8Locale Currently supported locale variants, compromised between https://www.postgresql.org/docs/9.3/multibyte.html
 // and https://www.gnu.org/software/libc/manual/html_node/Locale-Names.html.
 //
@@ -0,0 +1,235 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package recorder provides wire protocol traffic recording and reading.
+//
+// A recording is a sequence of records; each record is a request and all responses sent to it.
+// Each record is encoded as:
+//
+//	int64   connection ID
+//	int64   request time (Unix nanoseconds)
+//	bytes   request message (header and body, as sent by the client)
+//	int32   number of responses
+//
+// followed by responses, each encoded as:
+//
+//	int64   response time (Unix nanoseconds)
+//	bytes   response message (header and body, as sent to the client)
+//
+// All integers are little-endian.
+package recorder
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+	"github.com/FerretDB/FerretDB/internal/wire"
+)
+
+// maxResponses is the maximum number of responses in a single record.
+const maxResponses = 1000000
+
+// Message represents a single recorded wire message.
+//
+// Raw message bytes are recorded as-is; Header and Body are set when the record is read.
+// Body is nil if the message failed validation.
+type Message struct {
+	Time   time.Time
+	Header *wire.MsgHeader
+	Body   wire.MsgBody
+	Raw    []byte
+}
+
+// Record represents a request and responses sent to it.
+//
+// Requests without reply have no responses; exhaust cursors may have many.
+type Record struct {
+	ConnID    int64
+	Request   Message
+	Responses []Message
+}
+
+// Recorder writes records to a file.
+//
+// It is safe for concurrent use.
+type Recorder struct {
+	m    sync.Mutex
+	f    *os.File
+	bufw *bufio.Writer
+}
+
+// New creates a new recorder that writes to a new file in the given directory.
+//
+// Recordings contain all client traffic including credentials,
+// so the directory and the file are accessible only by the current user.
+func New(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	name := fmt.Sprintf("%s.rec", time.Now().UTC().Format("20060102-150405.000000000"))
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	return &Recorder{
+		f:    f,
+		bufw: bufio.NewWriter(f),
+	}, nil
+}
+
+// Path returns the path of the recording file.
+func (r *Recorder) Path() string {
+	return r.f.Name()
+}
+
+// Write writes a record.
+func (r *Recorder) Write(rec *Record) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if err := WriteRecord(r.bufw, rec); err != nil {
+		return lazyerrors.Error(err)
+	}
+
+	// flush every record to keep the recording usable if the process is killed
+	if err := r.bufw.Flush(); err != nil {
+		return lazyerrors.Error(err)
+	}
+
+	return nil
+}
+
+// Close flushes and closes the recording file.
+func (r *Recorder) Close() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	err := r.bufw.Flush()
+	if e := r.f.Close(); err == nil {
+		err = e
+	}
+
+	if err != nil {
+		return lazyerrors.Error(err)
+	}
+
+	return nil
+}
+
+// WriteRecord writes a single record to the writer.
+func WriteRecord(w *bufio.Writer, rec *Record) error {
+	if err := binary.Write(w, binary.LittleEndian, rec.ConnID); err != nil {
+		return lazyerrors.Error(err)
+	}
+
+	if err := writeMessage(w, &rec.Request); err != nil {
+		return lazyerrors.Error(err)
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, int32(len(rec.Responses))); err != nil {
+		return lazyerrors.Error(err)
+	}
+
+	for i := range rec.Responses {
+		if err := writeMessage(w, &rec.Responses[i]); err != nil {
+			return lazyerrors.Error(err)
+		}
+	}
+
+	return nil
+}
+
+// ReadRecord reads a single record from the reader.
+//
+// It returns io.EOF if there are no more records.
+func ReadRecord(r *bufio.Reader) (*Record, error) {
+	var rec Record
+	if err := binary.Read(r, binary.LittleEndian, &rec.ConnID); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, lazyerrors.Error(err)
+	}
+
+	if err := readMessage(r, &rec.Request); err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	var n int32
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+	if n < 0 || n > maxResponses {
+		return nil, lazyerrors.Errorf("invalid number of responses %d", n)
+	}
+
+	rec.Responses = make([]Message, n)
+	for i := range rec.Responses {
+		if err := readMessage(r, &rec.Responses[i]); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+	}
+
+	return &rec, nil
+}
+
+// writeMessage writes message time, header and body.
+func writeMessage(w *bufio.Writer, msg *Message) error {
+	if err := binary.Write(w, binary.LittleEndian, msg.Time.UnixNano()); err != nil {
+		return lazyerrors.Error(err)
+	}
+
+	if len(msg.Raw) < wire.MsgHeaderLen {
+		return lazyerrors.Errorf("raw message is too short (%d bytes)", len(msg.Raw))
+	}
+
+	if _, err := w.Write(msg.Raw); err != nil {
+		return lazyerrors.Error(err)
+	}
+
+	return nil
+}
+
+// readMessage reads message time, header and body.
+func readMessage(r *bufio.Reader, msg *Message) error {
+	var t int64
+	if err := binary.Read(r, binary.LittleEndian, &t); err != nil {
+		return lazyerrors.Error(err)
+	}
+	msg.Time = time.Unix(0, t)
+
+	header, body, raw, err := wire.ReadMessageRaw(r)
+	if err != nil && !errors.As(err, new(*wire.ValidationError)) {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return lazyerrors.Error(err)
+	}
+
+	msg.Header = header
+	msg.Body = body
+	msg.Raw = raw
+
+	return nil
+}
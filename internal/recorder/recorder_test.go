@@ -0,0 +1,127 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recorder
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+	"github.com/FerretDB/FerretDB/internal/wire"
+)
+
+// makeMsg returns OP_MSG header and body with the given document.
+func makeMsg(t *testing.T, requestID, responseTo int32, doc *types.Document) Message {
+	t.Helper()
+
+	var msg wire.OpMsg
+	require.NoError(t, msg.SetSections(wire.OpMsgSection{Documents: []*types.Document{doc}}))
+
+	b, err := msg.MarshalBinary()
+	require.NoError(t, err)
+
+	header := &wire.MsgHeader{
+		MessageLength: int32(wire.MsgHeaderLen + len(b)),
+		RequestID:     requestID,
+		ResponseTo:    responseTo,
+		OpCode:        wire.OP_MSG,
+	}
+
+	raw, err := wire.MarshalMessage(header, &msg)
+	require.NoError(t, err)
+
+	return Message{Header: header, Body: &msg, Raw: raw}
+}
+
+// withTime returns a copy of the message with the given time.
+func withTime(msg Message, t time.Time) Message {
+	msg.Time = t
+	return msg
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(0, time.Now().UnixNano())
+
+	req := makeMsg(t, 1, 0, must.NotFail(types.NewDocument("ping", int32(1), "$db", "admin")))
+	res := makeMsg(t, 2, 1, must.NotFail(types.NewDocument("ok", float64(1))))
+	ins := makeMsg(t, 3, 0, must.NotFail(types.NewDocument("insert", "test", "$db", "test")))
+
+	// message with checksumPresent flag and wrong checksum fails validation, but is recorded as-is
+	invalid := makeMsg(t, 4, 0, must.NotFail(types.NewDocument("ping", int32(1), "$db", "admin")))
+	invalid.Raw = append(append([]byte{}, invalid.Raw...), 0x01, 0x02, 0x03, 0x04)
+	invalid.Raw[0] += 4
+	invalid.Raw[wire.MsgHeaderLen] |= byte(wire.OpMsgChecksumPresent)
+	invalid.Header = &wire.MsgHeader{
+		MessageLength: invalid.Header.MessageLength + 4,
+		RequestID:     4,
+		OpCode:        wire.OP_MSG,
+	}
+	invalid.Body = nil
+
+	records := []*Record{{
+		ConnID:    1,
+		Request:   withTime(req, now),
+		Responses: []Message{withTime(res, now.Add(time.Millisecond))},
+	}, {
+		ConnID:    2,
+		Request:   withTime(ins, now.Add(time.Second)),
+		Responses: []Message{},
+	}, {
+		ConnID:    2,
+		Request:   withTime(invalid, now.Add(2*time.Second)),
+		Responses: []Message{},
+	}}
+
+	dir := filepath.Join(t.TempDir(), "records")
+	r, err := New(dir)
+	require.NoError(t, err)
+
+	// recordings contain credentials
+	fi, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), fi.Mode().Perm())
+	fi, err = os.Stat(r.Path())
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+
+	for _, rec := range records {
+		require.NoError(t, r.Write(rec))
+	}
+	require.NoError(t, r.Close())
+
+	f, err := os.Open(r.Path())
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+
+	bufr := bufio.NewReader(f)
+	for _, expected := range records {
+		actual, err := ReadRecord(bufr)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	_, err = ReadRecord(bufr)
+	assert.Equal(t, io.EOF, err)
+}
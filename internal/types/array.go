@@ -79,6 +79,18 @@ func (a *Array) GetByPath(path ...string) (any, error) {
 	return getByPath(a, path...)
 }
 
+// SetByPath sets the value by path - a sequence of indexes and keys, creating intermediate documents.
+// Missing array elements are filled with nulls.
+func (a *Array) SetByPath(value any, path ...string) error {
+	return setByPath(a, value, path...)
+}
+
+// RemoveByPath removes the value by path - a sequence of indexes and keys.
+// Array elements are set to null. It does nothing if the path does not exist.
+func (a *Array) RemoveByPath(path ...string) {
+	removeByPath(a, path...)
+}
+
 // Subslice returns a slice of the array, sharing the same underlying space and elements.
 func (a *Array) Subslice(low, high int) (*Array, error) {
 	l := a.Len()
@@ -0,0 +1,319 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// canonicalOrder returns the position of the value's type in the MongoDB comparison order.
+//
+// Values of different types with the same position (for example, all numbers) are compared by value.
+// See https://docs.mongodb.com/manual/reference/bson-type-comparison-order/.
+func canonicalOrder(v any) int {
+	switch v := v.(type) {
+	case MinKeyType:
+		return -1
+	case UndefinedType:
+		return 0
+	case NullType:
+		return 5
+	case float64, int32, int64, Decimal128:
+		return 10
+	case string, Symbol, CString:
+		return 15
+	case *Document:
+		return 20
+	case *Array:
+		return 25
+	case Binary:
+		return 30
+	case ObjectID:
+		return 35
+	case bool:
+		return 40
+	case time.Time:
+		return 45
+	case Timestamp:
+		return 47
+	case Regex:
+		return 50
+	case DBPointer:
+		return 55
+	case JavaScript:
+		return 60
+	case JavaScriptScope:
+		return 65
+	case MaxKeyType:
+		return 127
+	default:
+		panic(fmt.Sprintf("types.canonicalOrder: unhandled type %T", v))
+	}
+}
+
+// Comparable returns true if a and b have the same position in the MongoDB comparison order,
+// so query operators like $gt and $lt could compare them.
+// For example, int32 and float64 values are comparable, while int32 and string values are not.
+func Comparable(a, b any) bool {
+	return canonicalOrder(a) == canonicalOrder(b)
+}
+
+// Compare compares two values using MongoDB comparison order.
+// The result will be 0 if a == b, -1 if a < b, and +1 if a > b.
+//
+// Values of different types are compared by their position in the comparison order
+// (MinKey, Undefined, Null, numbers, strings, documents, arrays, binary data, ObjectIDs, booleans,
+// dates, timestamps, regular expressions, DBPointers, JavaScript code, JavaScript code with scope, MaxKey).
+// Numbers of different types are compared by their exact values; NaN is equal to NaN and less than any other number.
+// Documents and arrays are compared element by element.
+//
+// Compare panics if a or b is not a value of one of the types package's supported types.
+func Compare(a, b any) int {
+	if oa, ob := canonicalOrder(a), canonicalOrder(b); oa != ob {
+		return compareOrdered(oa, ob)
+	}
+
+	switch a := a.(type) {
+	case MinKeyType, UndefinedType, NullType, MaxKeyType:
+		return 0
+
+	case float64, int32, int64, Decimal128:
+		return compareNumbers(a, b)
+
+	case string, Symbol, CString:
+		return strings.Compare(stringValue(a), stringValue(b))
+
+	case *Document:
+		return compareDocuments(a, b.(*Document))
+
+	case *Array:
+		return compareArrays(a, b.(*Array))
+
+	case Binary:
+		b := b.(Binary)
+		if c := compareOrdered(len(a.B), len(b.B)); c != 0 {
+			return c
+		}
+		if c := compareOrdered(a.Subtype, b.Subtype); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.B, b.B)
+
+	case ObjectID:
+		b := b.(ObjectID)
+		return bytes.Compare(a[:], b[:])
+
+	case bool:
+		b := b.(bool)
+		switch {
+		case a == b:
+			return 0
+		case b:
+			return -1
+		default:
+			return 1
+		}
+
+	case time.Time:
+		b := b.(time.Time)
+		switch {
+		case a.Before(b):
+			return -1
+		case a.After(b):
+			return 1
+		default:
+			return 0
+		}
+
+	case Timestamp:
+		return compareOrdered(a, b.(Timestamp))
+
+	case Regex:
+		b := b.(Regex)
+		if c := strings.Compare(a.Pattern, b.Pattern); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Options, b.Options)
+
+	case DBPointer:
+		b := b.(DBPointer)
+		if c := strings.Compare(a.Namespace, b.Namespace); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+
+	case JavaScript:
+		return strings.Compare(string(a), string(b.(JavaScript)))
+
+	case JavaScriptScope:
+		b := b.(JavaScriptScope)
+		if c := strings.Compare(a.Code, b.Code); c != 0 {
+			return c
+		}
+		return compareDocuments(a.Scope, b.Scope)
+	}
+
+	panic(fmt.Sprintf("not reached: %T", a))
+}
+
+// compareOrdered compares two ordered values.
+func compareOrdered[T ~int | ~int64 | ~uint8 | ~uint64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// stringValue returns string, Symbol or CString value as a string.
+func stringValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case Symbol:
+		return string(v)
+	case CString:
+		return string(v)
+	default:
+		panic(fmt.Sprintf("types.stringValue: unexpected type %T", v))
+	}
+}
+
+// compareDocuments compares documents field by field:
+// first by the type of values, then by field names, then by values.
+// If all fields are equal, the shorter document is less.
+func compareDocuments(a, b *Document) int {
+	ak, bk := a.Keys(), b.Keys()
+	am, bm := a.Map(), b.Map()
+
+	for i := 0; i < len(ak) && i < len(bk); i++ {
+		av, bv := am[ak[i]], bm[bk[i]]
+		if c := compareOrdered(canonicalOrder(av), canonicalOrder(bv)); c != 0 {
+			return c
+		}
+		if c := strings.Compare(ak[i], bk[i]); c != 0 {
+			return c
+		}
+		if c := Compare(av, bv); c != 0 {
+			return c
+		}
+	}
+
+	return compareOrdered(len(ak), len(bk))
+}
+
+// compareArrays compares arrays element by element.
+// If all elements are equal, the shorter array is less.
+func compareArrays(a, b *Array) int {
+	for i := 0; i < a.Len() && i < b.Len(); i++ {
+		if c := Compare(a.s[i], b.s[i]); c != 0 {
+			return c
+		}
+	}
+
+	return compareOrdered(a.Len(), b.Len())
+}
+
+// numberKind represents a kind of number for comparison.
+type numberKind int
+
+const (
+	numberNaN numberKind = iota
+	numberNegInf
+	numberFinite
+	numberPosInf
+)
+
+// compareNumbers compares float64, int32, int64 and Decimal128 values by their exact values.
+func compareNumbers(a, b any) int {
+	// fast paths for the most common cases
+	switch a := a.(type) {
+	case int32:
+		switch b := b.(type) {
+		case int32:
+			return compareOrdered(int64(a), int64(b))
+		case int64:
+			return compareOrdered(int64(a), b)
+		}
+	case int64:
+		switch b := b.(type) {
+		case int32:
+			return compareOrdered(a, int64(b))
+		case int64:
+			return compareOrdered(a, b)
+		}
+	case float64:
+		if b, ok := b.(float64); ok && !math.IsNaN(a) && !math.IsNaN(b) {
+			switch {
+			case a < b:
+				return -1
+			case a > b:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+
+	ak, ar := numberValue(a)
+	bk, br := numberValue(b)
+	if ak != bk || ak != numberFinite {
+		return compareOrdered(ak, bk)
+	}
+
+	return ar.Cmp(br)
+}
+
+// numberValue returns the kind of the given number and, for finite numbers, its exact value.
+func numberValue(v any) (numberKind, *big.Rat) {
+	switch v := v.(type) {
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return numberNaN, nil
+		case math.IsInf(v, -1):
+			return numberNegInf, nil
+		case math.IsInf(v, 1):
+			return numberPosInf, nil
+		default:
+			return numberFinite, new(big.Rat).SetFloat64(v)
+		}
+	case int32:
+		return numberFinite, new(big.Rat).SetInt64(int64(v))
+	case int64:
+		return numberFinite, new(big.Rat).SetInt64(v)
+	case Decimal128:
+		switch {
+		case v.IsNaN():
+			return numberNaN, nil
+		case v.IsInf(-1):
+			return numberNegInf, nil
+		case v.IsInf(1):
+			return numberPosInf, nil
+		default:
+			return numberFinite, v.Rat()
+		}
+	default:
+		panic(fmt.Sprintf("types.numberValue: unexpected type %T", v))
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FerretDB/FerretDB/internal/util/must"
+)
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	// values in ascending order; values in the same inner slice are equal
+	ordered := [][]any{
+		{MinKey},
+		{Undefined},
+		{Null},
+		{math.Inf(-1), must.NotFail(ParseDecimal128("-Infinity"))},
+		{int64(math.MinInt64), float64(math.MinInt64)},
+		{float64(-1.5), must.NotFail(ParseDecimal128("-1.50"))},
+		{int32(-1), int64(-1), float64(-1), must.NotFail(ParseDecimal128("-1.0"))},
+		{int32(0), int64(0), float64(0), math.Copysign(0, -1), must.NotFail(ParseDecimal128("-0"))},
+		{must.NotFail(ParseDecimal128("0.1"))},
+		{float64(0.1)}, // 0.1000000000000000055511151231257827...
+		{int32(1), int64(1), float64(1), must.NotFail(ParseDecimal128("1.000"))},
+		{int64(math.MaxInt64)},
+		{float64(math.MaxInt64)}, // 2^63
+		{math.Inf(1), must.NotFail(ParseDecimal128("Infinity"))},
+		{"", Symbol(""), CString("")},
+		{"a", Symbol("a")},
+		{"ab"},
+		{"b"},
+		{MustNewDocument()},
+		{MustNewDocument("a", Null)},
+		{MustNewDocument("a", int32(1))},
+		{MustNewDocument("a", int32(1), "b", int32(1))},
+		{MustNewDocument("a", int32(2))},
+		{MustNewDocument("b", int32(1))},
+		{MustNewDocument("a", "a")}, // types of values are compared before keys
+		{must.NotFail(NewArray())},
+		{must.NotFail(NewArray(int32(1)))},
+		{must.NotFail(NewArray(int32(1), int32(1)))},
+		{must.NotFail(NewArray(float64(2)))},
+		{must.NotFail(NewArray("a"))},
+		{Binary{Subtype: BinaryUser, B: []byte{0x02}}},
+		{Binary{Subtype: BinaryGeneric, B: []byte{0x01, 0x02}}},
+		{Binary{Subtype: BinaryUser, B: []byte{0x01, 0x02}}},
+		{ObjectID{}},
+		{ObjectID{0x01}},
+		{false},
+		{true},
+		{time.Unix(-1, 0)},
+		{time.Unix(0, 0), time.Unix(0, 0).UTC()},
+		{Timestamp(0)},
+		{Timestamp(math.MaxUint64)},
+		{Regex{Pattern: "a"}},
+		{Regex{Pattern: "a", Options: "i"}},
+		{DBPointer{Namespace: "db.c"}},
+		{JavaScript("x")},
+		{JavaScriptScope{Code: "x", Scope: MustNewDocument()}},
+		{JavaScriptScope{Code: "x", Scope: MustNewDocument("a", int32(1))}},
+		{MaxKey},
+	}
+
+	for i, ai := range ordered {
+		for j, bj := range ordered {
+			expected := compareOrdered(i, j)
+			for _, a := range ai {
+				for _, b := range bj {
+					assert.Equal(t, expected, Compare(a, b), "Compare(%#v, %#v)", a, b)
+				}
+			}
+		}
+	}
+}
+
+func TestCompareNaN(t *testing.T) {
+	t.Parallel()
+
+	nans := []any{math.NaN(), must.NotFail(ParseDecimal128("NaN"))}
+	for _, a := range nans {
+		for _, b := range nans {
+			assert.Equal(t, 0, Compare(a, b))
+		}
+
+		for _, b := range []any{math.Inf(-1), int32(0), int64(math.MinInt64), must.NotFail(ParseDecimal128("-Infinity"))} {
+			assert.Equal(t, -1, Compare(a, b), "Compare(%#v, %#v)", a, b)
+			assert.Equal(t, 1, Compare(b, a), "Compare(%#v, %#v)", b, a)
+		}
+
+		assert.Equal(t, 1, Compare(a, Null))
+		assert.Equal(t, -1, Compare(a, ""))
+	}
+}
+
+func TestComparable(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, Comparable(int32(1), float64(2)))
+	assert.True(t, Comparable("a", Symbol("b")))
+	assert.False(t, Comparable(int32(1), "1"))
+	assert.False(t, Comparable(Null, Undefined))
+}
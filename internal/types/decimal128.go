@@ -0,0 +1,304 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal128 represents BSON type Decimal128 - IEEE 754-2008 128-bit decimal floating point number
+// in the binary integer decimal (BID) encoding.
+//
+// H and L are high and low 64 bits of the encoded value.
+type Decimal128 struct {
+	H uint64
+	L uint64
+}
+
+const (
+	decimal128ExponentBias = 6176
+	decimal128MinExponent  = -6176
+	decimal128MaxExponent  = 6111
+	decimal128MaxDigits    = 34
+)
+
+var (
+	// decimal128MaxCoefficient is the maximum valid coefficient: 10^34 - 1.
+	decimal128MaxCoefficient = new(big.Int).Sub(new(big.Int).Exp(big.NewInt(10), big.NewInt(34), nil), big.NewInt(1))
+
+	bigTen = big.NewInt(10)
+
+	decimal128NaN    = Decimal128{H: 0x7c00000000000000}
+	decimal128Inf    = Decimal128{H: 0x7800000000000000}
+	decimal128NegInf = Decimal128{H: 0xf800000000000000}
+)
+
+// decimal128Kind represents a kind of Decimal128 value.
+type decimal128Kind int
+
+const (
+	decimal128Finite decimal128Kind = iota
+	decimal128Infinity
+	decimal128NotANumber
+)
+
+// parts returns the sign, kind, coefficient and exponent of the value.
+//
+// Non-canonical coefficients (larger than 10^34 - 1) are treated as zero.
+func (d Decimal128) parts() (neg bool, kind decimal128Kind, coef *big.Int, exp int) {
+	neg = d.H>>63 == 1
+
+	var high uint64
+	switch {
+	case d.H>>58&0x1f == 0x1f:
+		return neg, decimal128NotANumber, nil, 0
+
+	case d.H>>58&0x1f == 0x1e:
+		return neg, decimal128Infinity, nil, 0
+
+	case d.H>>61&0x3 == 0x3:
+		// 2 bits after the sign bit are "11": the coefficient is always larger than 10^34 - 1
+		exp = int(d.H>>47&0x3fff) - decimal128ExponentBias
+		return neg, decimal128Finite, new(big.Int), exp
+
+	default:
+		exp = int(d.H>>49&0x3fff) - decimal128ExponentBias
+		high = d.H & 0x1ffffffffffff
+	}
+
+	coef = new(big.Int).SetUint64(high)
+	coef.Lsh(coef, 64)
+	coef.Or(coef, new(big.Int).SetUint64(d.L))
+
+	if coef.Cmp(decimal128MaxCoefficient) > 0 {
+		coef.SetInt64(0)
+	}
+
+	return neg, decimal128Finite, coef, exp
+}
+
+// newDecimal128 encodes the finite value.
+//
+// The coefficient must not be larger than 10^34 - 1, and the exponent must be in the valid range.
+func newDecimal128(neg bool, coef *big.Int, exp int) Decimal128 {
+	var low, high big.Int
+	low.And(coef, new(big.Int).SetUint64(^uint64(0)))
+	high.Rsh(coef, 64)
+
+	d := Decimal128{
+		H: high.Uint64() | uint64(exp+decimal128ExponentBias)<<49,
+		L: low.Uint64(),
+	}
+	if neg {
+		d.H |= 1 << 63
+	}
+
+	return d
+}
+
+// IsNaN returns true if the value is NaN.
+func (d Decimal128) IsNaN() bool {
+	_, kind, _, _ := d.parts()
+	return kind == decimal128NotANumber
+}
+
+// IsInf returns true if the value is an infinity, according to sign.
+// If sign > 0, IsInf reports whether the value is positive infinity.
+// If sign < 0, IsInf reports whether the value is negative infinity.
+// If sign == 0, IsInf reports whether the value is either infinity.
+func (d Decimal128) IsInf(sign int) bool {
+	neg, kind, _, _ := d.parts()
+	if kind != decimal128Infinity {
+		return false
+	}
+
+	return sign == 0 || (sign > 0 && !neg) || (sign < 0 && neg)
+}
+
+// Rat returns the finite value as a rational number.
+// It returns nil for NaN and infinities.
+func (d Decimal128) Rat() *big.Rat {
+	neg, kind, coef, exp := d.parts()
+	if kind != decimal128Finite {
+		return nil
+	}
+
+	res := new(big.Rat).SetInt(coef)
+	scale := new(big.Int).Exp(bigTen, big.NewInt(int64(abs(exp))), nil)
+	if exp >= 0 {
+		res.Mul(res, new(big.Rat).SetInt(scale))
+	} else {
+		res.Quo(res, new(big.Rat).SetInt(scale))
+	}
+
+	if neg {
+		res.Neg(res)
+	}
+
+	return res
+}
+
+// String returns the canonical string representation of the value,
+// as defined by the BSON Decimal128 specification.
+func (d Decimal128) String() string {
+	neg, kind, coef, exp := d.parts()
+
+	var sign string
+	if neg {
+		sign = "-"
+	}
+
+	switch kind {
+	case decimal128NotANumber:
+		return "NaN"
+	case decimal128Infinity:
+		return sign + "Infinity"
+	}
+
+	digits := coef.String()
+	adjusted := exp + len(digits) - 1
+
+	// scientific notation
+	if exp > 0 || adjusted < -6 {
+		res := sign + digits[:1]
+		if len(digits) > 1 {
+			res += "." + digits[1:]
+		}
+
+		res += "E"
+		if adjusted >= 0 {
+			res += "+"
+		}
+		return res + strconv.Itoa(adjusted)
+	}
+
+	if exp == 0 {
+		return sign + digits
+	}
+
+	// regular notation with decimal point
+	if point := len(digits) + exp; point > 0 {
+		return sign + digits[:point] + "." + digits[point:]
+	}
+
+	return sign + "0." + strings.Repeat("0", -exp-len(digits)) + digits
+}
+
+// ParseDecimal128 parses a string representation of decimal number.
+//
+// It accepts numbers in regular and scientific notations, NaN, Inf and Infinity (case-insensitive).
+// Values that can't be represented exactly are rejected.
+func ParseDecimal128(s string) (Decimal128, error) {
+	str := s
+
+	var neg bool
+	if str != "" && (str[0] == '-' || str[0] == '+') {
+		neg = str[0] == '-'
+		str = str[1:]
+	}
+
+	switch strings.ToLower(str) {
+	case "nan":
+		if neg {
+			return Decimal128{H: decimal128NaN.H | 1<<63}, nil
+		}
+		return decimal128NaN, nil
+	case "inf", "infinity":
+		if neg {
+			return decimal128NegInf, nil
+		}
+		return decimal128Inf, nil
+	}
+
+	mantissa, exponent, hasExp := strings.Cut(str, "e")
+	if !hasExp {
+		mantissa, exponent, hasExp = strings.Cut(str, "E")
+	}
+
+	var exp int
+	if hasExp {
+		var err error
+		if exp, err = strconv.Atoi(exponent); err != nil {
+			return Decimal128{}, fmt.Errorf("types.ParseDecimal128: invalid exponent in %q", s)
+		}
+	}
+
+	intPart, fracPart, _ := strings.Cut(mantissa, ".")
+	digits := intPart + fracPart
+	if digits == "" {
+		return Decimal128{}, fmt.Errorf("types.ParseDecimal128: no digits in %q", s)
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return Decimal128{}, fmt.Errorf("types.ParseDecimal128: invalid character %q in %q", c, s)
+		}
+	}
+
+	exp -= len(fracPart)
+
+	coef, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Decimal128{}, fmt.Errorf("types.ParseDecimal128: invalid number %q", s)
+	}
+
+	// drop trailing zeros while there are too many digits or the exponent is too small
+	mod := new(big.Int)
+	for coef.Sign() != 0 && (coef.Cmp(decimal128MaxCoefficient) > 0 || exp < decimal128MinExponent) {
+		q, m := new(big.Int).QuoRem(coef, bigTen, mod)
+		if m.Sign() != 0 {
+			return Decimal128{}, fmt.Errorf("types.ParseDecimal128: %q can't be represented exactly", s)
+		}
+		coef = q
+		exp++
+	}
+
+	// add trailing zeros while the exponent is too large
+	for coef.Sign() != 0 && exp > decimal128MaxExponent {
+		coef.Mul(coef, bigTen)
+		exp--
+
+		if coef.Cmp(decimal128MaxCoefficient) > 0 {
+			return Decimal128{}, fmt.Errorf("types.ParseDecimal128: %q overflows", s)
+		}
+	}
+
+	// zero can have any exponent, clamp it
+	if coef.Sign() == 0 {
+		switch {
+		case exp < decimal128MinExponent:
+			exp = decimal128MinExponent
+		case exp > decimal128MaxExponent:
+			exp = decimal128MaxExponent
+		}
+	}
+
+	return newDecimal128(neg, coef, exp), nil
+}
+
+// abs returns the absolute value of x.
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// check interfaces
+var (
+	_ fmt.Stringer = Decimal128{}
+)
@@ -0,0 +1,125 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FerretDB/FerretDB/internal/util/must"
+)
+
+func TestDecimal128(t *testing.T) {
+	t.Parallel()
+
+	// test vectors from BSON specification corpus
+	for name, tc := range map[string]struct {
+		d Decimal128
+		s string
+	}{
+		"Zero":          {d: Decimal128{H: 0x3040000000000000}, s: "0"},
+		"NegativeZero":  {d: Decimal128{H: 0xb040000000000000}, s: "-0"},
+		"One":           {d: Decimal128{H: 0x3040000000000000, L: 1}, s: "1"},
+		"NegativeOne":   {d: Decimal128{H: 0xb040000000000000, L: 1}, s: "-1"},
+		"Fraction":      {d: Decimal128{H: 0x303c000000000000, L: 0x3e8}, s: "10.00"},
+		"Small":         {d: Decimal128{H: 0x3034000000000000, L: 0x4d2}, s: "0.001234"},
+		"Tiny":          {d: Decimal128{H: 0x302c000000000000, L: 0x4d2}, s: "1.234E-7"},
+		"Exponent":      {d: Decimal128{H: 0x3042000000000000, L: 1}, s: "1E+1"},
+		"Scientific":    {d: Decimal128{H: 0x3052000000000000, L: 0x3039}, s: "1.2345E+13"},
+		"MinPositive":   {d: Decimal128{H: 0x0000000000000000, L: 1}, s: "1E-6176"},
+		"Max":           {d: Decimal128{H: 0x5fffed09bead87c0, L: 0x378d8e63ffffffff}, s: "9.999999999999999999999999999999999E+6144"},
+		"NaN":           {d: Decimal128{H: 0x7c00000000000000}, s: "NaN"},
+		"Infinity":      {d: Decimal128{H: 0x7800000000000000}, s: "Infinity"},
+		"NegInfinity":   {d: Decimal128{H: 0xf800000000000000}, s: "-Infinity"},
+		"ZeroExponent":  {d: Decimal128{H: 0x3046000000000000}, s: "0E+3"},
+		"ZeroFraction":  {d: Decimal128{H: 0x303a000000000000}, s: "0.000"},
+		"Significand34": {d: Decimal128{H: 0x3040ffffffffffff, L: 0xffffffffffffffff}, s: "5192296858534827628530496329220095"},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.s, tc.d.String())
+
+			actual, err := ParseDecimal128(tc.s)
+			require.NoError(t, err)
+			assert.Equal(t, tc.d, actual)
+		})
+	}
+
+	t.Run("Parse", func(t *testing.T) {
+		t.Parallel()
+
+		for s, expected := range map[string]string{
+			"+1":                                  "1",
+			"1.":                                  "1",
+			".5":                                  "0.5",
+			"1e3":                                 "1E+3",
+			"inf":                                 "Infinity",
+			"-INFINITY":                           "-Infinity",
+			"-nan":                                "NaN",
+			"1E-6177":                             "", // inexact
+			"10E-6177":                            "1E-6176",
+			"0E-8000":                             "0E-6176",
+			"0E+8000":                             "0E+6111",
+			"1E+6111":                             "1E+6111",
+			"1E+6112":                             "1.0E+6112", // clamped
+			"1E+6145":                             "",          // overflow
+			"":                                    "",
+			".":                                   "",
+			"1..2":                                "",
+			"1e":                                  "",
+			"1e+-2":                               "",
+			"0x1":                                 "",
+			"Infinityy":                           "",
+			"12345678901234567890123456789012345": "", // inexact
+			"12345678901234567890123456789012340": "1.234567890123456789012345678901234E+34",
+		} {
+			d, err := ParseDecimal128(s)
+			if expected == "" {
+				assert.Error(t, err, "%q", s)
+				continue
+			}
+
+			require.NoError(t, err, "%q", s)
+			assert.Equal(t, expected, d.String(), "%q", s)
+		}
+	})
+
+	t.Run("NonCanonical", func(t *testing.T) {
+		t.Parallel()
+
+		// coefficients larger than 10^34 - 1 are treated as zero
+		assert.Equal(t, "0", Decimal128{H: 0x6c10000000000000}.String())
+		assert.Equal(t, "0", Decimal128{H: 0x3041ed09bead87c0, L: 0x378d8e6400000000}.String())
+	})
+
+	t.Run("Rat", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, big.NewRat(1234, 1000000), must.NotFail(ParseDecimal128("0.001234")).Rat())
+		assert.Equal(t, big.NewRat(-12000, 1), must.NotFail(ParseDecimal128("-1.2E+4")).Rat())
+		assert.Nil(t, must.NotFail(ParseDecimal128("NaN")).Rat())
+
+		assert.True(t, must.NotFail(ParseDecimal128("NaN")).IsNaN())
+		assert.True(t, must.NotFail(ParseDecimal128("-Infinity")).IsInf(-1))
+		assert.False(t, must.NotFail(ParseDecimal128("-Infinity")).IsInf(1))
+		assert.True(t, must.NotFail(ParseDecimal128("Infinity")).IsInf(0))
+		assert.False(t, must.NotFail(ParseDecimal128("1")).IsInf(0))
+	})
+}
@@ -229,6 +229,18 @@ func (d *Document) GetByPath(path ...string) (any, error) {
 	return getByPath(d, path...)
 }
 
+// SetByPath sets the value by path - a sequence of indexes and keys, creating intermediate documents.
+// Missing array elements are filled with nulls.
+func (d *Document) SetByPath(value any, path ...string) error {
+	return setByPath(d, value, path...)
+}
+
+// RemoveByPath removes the value by path - a sequence of indexes and keys.
+// Array elements are set to null. It does nothing if the path does not exist.
+func (d *Document) RemoveByPath(path ...string) {
+	removeByPath(d, path...)
+}
+
 // Set the value of the given key, replacing any existing value.
 func (d *Document) Set(key string, value any) error {
 	if !isValidKey(key) {
@@ -0,0 +1,61 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync/atomic"
+	"time"
+
+	"github.com/FerretDB/FerretDB/internal/util/must"
+)
+
+// objectIDProcess is a process-unique random value used in ObjectIDs generated by NewObjectID.
+var objectIDProcess [5]byte
+
+// objectIDCounter is the last counter value used in ObjectIDs generated by NewObjectID.
+var objectIDCounter uint32
+
+func init() {
+	must.NotFail(rand.Read(objectIDProcess[:]))
+
+	var b [4]byte
+	must.NotFail(rand.Read(b[:]))
+	objectIDCounter = binary.BigEndian.Uint32(b[:])
+}
+
+// NewObjectID returns a new unique ObjectID.
+//
+// It consists of a 4-byte timestamp in seconds since the Unix epoch, a 5-byte process-unique random value,
+// and a 3-byte counter starting with a random value.
+func NewObjectID() ObjectID {
+	return newObjectIDTime(time.Now())
+}
+
+// newObjectIDTime returns a new unique ObjectID for the given time.
+func newObjectIDTime(t time.Time) ObjectID {
+	var res ObjectID
+
+	binary.BigEndian.PutUint32(res[0:4], uint32(t.Unix()))
+	copy(res[4:9], objectIDProcess[:])
+
+	c := atomic.AddUint32(&objectIDCounter, 1)
+	res[9] = byte(c >> 16)
+	res[10] = byte(c >> 8)
+	res[11] = byte(c)
+
+	return res
+}
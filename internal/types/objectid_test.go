@@ -0,0 +1,47 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewObjectID(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Date(2022, time.February, 1, 12, 0, 0, 0, time.UTC)
+	id1 := newObjectIDTime(ts)
+	id2 := newObjectIDTime(ts)
+
+	assert.NotEqual(t, id1, id2)
+	assert.Equal(t, uint32(ts.Unix()), binary.BigEndian.Uint32(id1[0:4]))
+	assert.Equal(t, id1[4:9], id2[4:9])
+
+	c1 := uint32(id1[9])<<16 | uint32(id1[10])<<8 | uint32(id1[11])
+	c2 := uint32(id2[9])<<16 | uint32(id2[10])<<8 | uint32(id2[11])
+	assert.Equal(t, (c1+1)&0xffffff, c2)
+
+	seen := make(map[ObjectID]struct{})
+	for i := 0; i < 1000; i++ {
+		id := NewObjectID()
+		_, ok := seen[id]
+		assert.False(t, ok)
+		seen[id] = struct{}{}
+	}
+}
@@ -17,8 +17,90 @@ package types
 import (
 	"fmt"
 	"strconv"
+	"strings"
 )
 
+//go:generate ../../bin/stringer -linecomment -type PathErrorCode
+
+// PathErrorCode represents PathError code. Values match MongoDB error codes.
+type PathErrorCode int32
+
+const (
+	PathErrorBadValue       = PathErrorCode(2)  // BadValue
+	PathErrorNotViable      = PathErrorCode(28) // PathNotViable
+	PathErrorEmptyFieldName = PathErrorCode(56) // EmptyFieldName
+)
+
+// maxArrayPadding is the maximal number of nulls that could be added to the array by SetByPath.
+const maxArrayPadding = 1500000
+
+// PathError represents an error returned by path operations.
+type PathError struct {
+	code PathErrorCode
+	err  error
+}
+
+// newPathError creates a new PathError.
+func newPathError(code PathErrorCode, err error) error {
+	return &PathError{
+		code: code,
+		err:  err,
+	}
+}
+
+// Code returns the error code.
+func (e *PathError) Code() PathErrorCode {
+	return e.code
+}
+
+// Error implements error interface.
+func (e *PathError) Error() string {
+	return fmt.Sprintf("%[1]s (%[1]d): %[2]v", e.code, e.err)
+}
+
+// Unwrap implements standard error unwrapping interface.
+func (e *PathError) Unwrap() error {
+	return e.err
+}
+
+// ParsePath splits dot notation path like "a.b.0" into a sequence of keys and indexes.
+func ParsePath(path string) ([]string, error) {
+	if path == "" {
+		return nil, newPathError(PathErrorEmptyFieldName, fmt.Errorf("empty path is not valid"))
+	}
+
+	res := strings.Split(path, ".")
+	for _, p := range res {
+		if p == "" {
+			err := fmt.Errorf("path %q contains an empty field name, which is not allowed", path)
+			return nil, newPathError(PathErrorEmptyFieldName, err)
+		}
+	}
+
+	return res, nil
+}
+
+// ArrayIndex returns the array index for the given path element
+// if it consists only of digits without leading zeros.
+func ArrayIndex(p string) (int, bool) {
+	if p == "" || (len(p) > 1 && p[0] == '0') {
+		return 0, false
+	}
+
+	for _, c := range p {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+	}
+
+	index, err := strconv.Atoi(p)
+	if err != nil {
+		return 0, false
+	}
+
+	return index, true
+}
+
 // getByPath returns a value by path - a sequence of indexes and keys.
 func getByPath[T CompositeTypeInterface](comp T, path ...string) (any, error) {
 	var next any = comp
@@ -48,3 +130,134 @@ func getByPath[T CompositeTypeInterface](comp T, path ...string) (any, error) {
 
 	return next, nil
 }
+
+// setByPath sets the value by path - a sequence of indexes and keys, creating intermediate documents.
+//
+// Missing array elements before the given index are set to null.
+// PathError is returned if the path traverses a scalar value or uses a non-numeric key for an array.
+func setByPath[T CompositeTypeInterface](comp T, value any, path ...string) error {
+	if len(path) == 0 {
+		return newPathError(PathErrorEmptyFieldName, fmt.Errorf("empty path is not valid"))
+	}
+
+	var next any = comp
+	var prev string
+	for i, p := range path {
+		last := i == len(path)-1
+
+		switch c := next.(type) {
+		case *Document:
+			if last {
+				if err := c.Set(p, value); err != nil {
+					return fmt.Errorf("types.setByPath: %w", err)
+				}
+				return nil
+			}
+
+			v, err := c.Get(p)
+			if err != nil {
+				v = MustNewDocument()
+				if err = c.Set(p, v); err != nil {
+					return fmt.Errorf("types.setByPath: %w", err)
+				}
+			}
+			next = v
+
+		case *Array:
+			index, ok := ArrayIndex(p)
+			if !ok {
+				err := fmt.Errorf("cannot create field %q in array %q: not a valid index", p, prev)
+				return newPathError(PathErrorNotViable, err)
+			}
+
+			if index < c.Len() {
+				if last {
+					if err := c.Set(index, value); err != nil {
+						return fmt.Errorf("types.setByPath: %w", err)
+					}
+					return nil
+				}
+
+				next = c.s[index]
+				break
+			}
+
+			if pad := index - c.Len(); pad > maxArrayPadding {
+				err := fmt.Errorf("can't backfill more than %d elements", maxArrayPadding)
+				return newPathError(PathErrorBadValue, err)
+			}
+
+			for c.Len() < index {
+				if err := c.Append(Null); err != nil {
+					return fmt.Errorf("types.setByPath: %w", err)
+				}
+			}
+
+			if last {
+				if err := c.Append(value); err != nil {
+					return fmt.Errorf("types.setByPath: %w", err)
+				}
+				return nil
+			}
+
+			v := MustNewDocument()
+			if err := c.Append(v); err != nil {
+				return fmt.Errorf("types.setByPath: %w", err)
+			}
+			next = v
+
+		default:
+			err := fmt.Errorf("cannot create field %q in element {%s: %v}", p, prev, next)
+			return newPathError(PathErrorNotViable, err)
+		}
+
+		prev = p
+	}
+
+	panic("not reached")
+}
+
+// removeByPath removes the value by path - a sequence of indexes and keys.
+//
+// Array elements are set to null instead of being removed, like MongoDB's $unset does.
+// It does nothing if the path does not exist.
+func removeByPath[T CompositeTypeInterface](comp T, path ...string) {
+	var next any = comp
+	for i, p := range path {
+		last := i == len(path)-1
+
+		switch c := next.(type) {
+		case *Document:
+			if last {
+				c.Remove(p)
+				return
+			}
+
+			v, err := c.Get(p)
+			if err != nil {
+				return
+			}
+			next = v
+
+		case *Array:
+			index, ok := ArrayIndex(p)
+			if !ok || index >= c.Len() {
+				return
+			}
+
+			if last {
+				c.s[index] = Null
+				return
+			}
+			next = c.s[index]
+
+		default:
+			return
+		}
+	}
+}
+
+// check interfaces
+var (
+	_ error = (*PathError)(nil)
+)
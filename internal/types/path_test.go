@@ -98,3 +98,186 @@ func TestGetByPath(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePath(t *testing.T) {
+	t.Parallel()
+
+	path, err := ParsePath("a.0.b")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "0", "b"}, path)
+
+	for _, p := range []string{"", "a.", ".a", "a..b"} {
+		_, err = ParsePath(p)
+		var pe *PathError
+		require.ErrorAs(t, err, &pe, "%q", p)
+		assert.Equal(t, PathErrorEmptyFieldName, pe.Code())
+	}
+}
+
+func TestSetByPath(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		name     string
+		doc      *Document
+		path     []string
+		value    any
+		expected *Document
+		code     PathErrorCode
+	}
+
+	for _, tc := range []testCase{{ //nolint:paralleltest // false positive
+		name:     "Replace",
+		doc:      MustNewDocument("a", int32(1), "b", int32(2)),
+		path:     []string{"a"},
+		value:    "foo",
+		expected: MustNewDocument("a", "foo", "b", int32(2)),
+	}, {
+		name:     "CreateIntermediate",
+		doc:      MustNewDocument("a", MustNewDocument()),
+		path:     []string{"a", "b", "c"},
+		value:    int32(1),
+		expected: MustNewDocument("a", MustNewDocument("b", MustNewDocument("c", int32(1)))),
+	}, {
+		name:     "NumericKeyInDocument",
+		doc:      MustNewDocument(),
+		path:     []string{"a", "0"},
+		value:    int32(1),
+		expected: MustNewDocument("a", MustNewDocument("0", int32(1))),
+	}, {
+		name:     "ArrayElement",
+		doc:      MustNewDocument("a", must.NotFail(NewArray(int32(1), int32(2)))),
+		path:     []string{"a", "1"},
+		value:    int32(3),
+		expected: MustNewDocument("a", must.NotFail(NewArray(int32(1), int32(3)))),
+	}, {
+		name:     "ArrayPadding",
+		doc:      MustNewDocument("a", must.NotFail(NewArray(int32(1)))),
+		path:     []string{"a", "3"},
+		value:    int32(4),
+		expected: MustNewDocument("a", must.NotFail(NewArray(int32(1), Null, Null, int32(4)))),
+	}, {
+		name:     "ArrayPaddingIntermediate",
+		doc:      MustNewDocument("a", must.NotFail(NewArray())),
+		path:     []string{"a", "1", "b"},
+		value:    int32(1),
+		expected: MustNewDocument("a", must.NotFail(NewArray(Null, MustNewDocument("b", int32(1))))),
+	}, {
+		name:     "ArrayNestedDocument",
+		doc:      MustNewDocument("a", must.NotFail(NewArray(MustNewDocument("b", int32(1))))),
+		path:     []string{"a", "0", "c"},
+		value:    int32(2),
+		expected: MustNewDocument("a", must.NotFail(NewArray(MustNewDocument("b", int32(1), "c", int32(2))))),
+	}, {
+		name:  "ArrayNonNumeric",
+		doc:   MustNewDocument("a", must.NotFail(NewArray(int32(1)))),
+		path:  []string{"a", "b"},
+		value: int32(1),
+		code:  PathErrorNotViable,
+	}, {
+		name:  "ArrayLeadingZero",
+		doc:   MustNewDocument("a", must.NotFail(NewArray(int32(1)))),
+		path:  []string{"a", "00"},
+		value: int32(1),
+		code:  PathErrorNotViable,
+	}, {
+		name:  "ArrayTooLarge",
+		doc:   MustNewDocument("a", must.NotFail(NewArray())),
+		path:  []string{"a", "1500001"},
+		value: int32(1),
+		code:  PathErrorBadValue,
+	}, {
+		name:  "Scalar",
+		doc:   MustNewDocument("a", int32(1)),
+		path:  []string{"a", "b"},
+		value: int32(1),
+		code:  PathErrorNotViable,
+	}, {
+		name:  "ArrayNull",
+		doc:   MustNewDocument("a", must.NotFail(NewArray(Null))),
+		path:  []string{"a", "0", "b"},
+		value: int32(1),
+		code:  PathErrorNotViable,
+	}, {
+		name:  "Empty",
+		doc:   MustNewDocument(),
+		value: int32(1),
+		code:  PathErrorEmptyFieldName,
+	}} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.doc.SetByPath(tc.value, tc.path...)
+			if tc.code == 0 {
+				require.NoError(t, err)
+				assert.Equal(t, tc.expected, tc.doc)
+				return
+			}
+
+			var pe *PathError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, tc.code, pe.Code())
+		})
+	}
+}
+
+func TestRemoveByPath(t *testing.T) {
+	t.Parallel()
+
+	newDoc := func() *Document {
+		return MustNewDocument(
+			"a", MustNewDocument("b", int32(1), "c", int32(2)),
+			"d", must.NotFail(NewArray(int32(1), MustNewDocument("e", int32(3)))),
+			"f", int32(4),
+		)
+	}
+
+	type testCase struct {
+		path     []string
+		expected *Document
+	}
+
+	for _, tc := range []testCase{{ //nolint:paralleltest // false positive
+		path: []string{"a", "b"},
+		expected: MustNewDocument(
+			"a", MustNewDocument("c", int32(2)),
+			"d", must.NotFail(NewArray(int32(1), MustNewDocument("e", int32(3)))),
+			"f", int32(4),
+		),
+	}, {
+		path: []string{"d", "0"},
+		expected: MustNewDocument(
+			"a", MustNewDocument("b", int32(1), "c", int32(2)),
+			"d", must.NotFail(NewArray(Null, MustNewDocument("e", int32(3)))),
+			"f", int32(4),
+		),
+	}, {
+		path: []string{"d", "1", "e"},
+		expected: MustNewDocument(
+			"a", MustNewDocument("b", int32(1), "c", int32(2)),
+			"d", must.NotFail(NewArray(int32(1), MustNewDocument())),
+			"f", int32(4),
+		),
+	}, {
+		path:     []string{"d", "2"},
+		expected: newDoc(),
+	}, {
+		path:     []string{"f", "g"},
+		expected: newDoc(),
+	}, {
+		path:     []string{"missing", "g"},
+		expected: newDoc(),
+	}} {
+		tc := tc
+		t.Run(fmt.Sprint(tc.path), func(t *testing.T) {
+			t.Parallel()
+
+			doc := newDoc()
+			doc.RemoveByPath(tc.path...)
+
+			// compare values, not internal representations of empty documents
+			assert.Zero(t, Compare(tc.expected, doc), "expected %v, got %v", tc.expected, doc)
+		})
+	}
+}
@@ -41,7 +41,17 @@
 //  int32            *bson.int32Type      *fjson.int32Type      32-bit integer
 //  types.Timestamp  *bson.timestampType  *fjson.timestampType  Timestamp
 //  int64            *bson.int64Type      *fjson.int64Type      64-bit integer
+//  types.Decimal128 *bson.decimal128Type *fjson.decimal128Type 128-bit decimal floating point
 //  types.CString    *bson.CString        *fjson.cstringType    Zero-terminated UTF-8 string
+//  types.MinKeyType *bson.minKeyType     *fjson.minKeyType     Min key
+//  types.MaxKeyType *bson.maxKeyType     *fjson.maxKeyType     Max key
+//
+// Deprecated scalar types (passed by values)
+//  types.UndefinedType       *bson.undefinedType       *fjson.undefinedType       Undefined
+//  types.DBPointer           *bson.dbPointerType       *fjson.dbPointerType       DBPointer
+//  types.JavaScript          *bson.javaScriptType      *fjson.javaScriptType      JavaScript code
+//  types.Symbol              *bson.symbolType          *fjson.symbolType          Symbol
+//  types.JavaScriptScope     *bson.javaScriptScopeType *fjson.javaScriptScopeType JavaScript code with scope
 package types
 
 import (
@@ -53,7 +63,9 @@ const MaxDocumentLen = 16777216
 
 // ScalarType represents scalar type.
 type ScalarType interface {
-	float64 | string | Binary | ObjectID | bool | time.Time | NullType | Regex | int32 | Timestamp | int64 | CString
+	float64 | string | Binary | ObjectID | bool | time.Time | NullType | Regex | int32 | Timestamp | int64 | Decimal128 |
+		CString | MinKeyType | MaxKeyType |
+		UndefinedType | DBPointer | JavaScript | Symbol | JavaScriptScope
 }
 
 // CompositeType represents composite type - *Document or *Array.
@@ -70,6 +82,8 @@ type Type interface {
 type CompositeTypeInterface interface {
 	CompositeType
 	GetByPath(path ...string) (any, error)
+	SetByPath(value any, path ...string) error
+	RemoveByPath(path ...string)
 
 	compositeType() // seal for go-sumtype
 }
@@ -96,10 +110,54 @@ type (
 	//
 	// Most callers should use types.Null value instead.
 	NullType struct{}
+
+	// MinKeyType represents BSON type MinKey that compares lower than all other values.
+	//
+	// Most callers should use types.MinKey value instead.
+	MinKeyType struct{}
+
+	// MaxKeyType represents BSON type MaxKey that compares higher than all other values.
+	//
+	// Most callers should use types.MaxKey value instead.
+	MaxKeyType struct{}
+
+	// UndefinedType represents deprecated BSON type Undefined.
+	//
+	// Most callers should use types.Undefined value instead.
+	UndefinedType struct{}
+
+	// DBPointer represents deprecated BSON type DBPointer.
+	DBPointer struct {
+		Namespace string
+		ID        ObjectID
+	}
+
+	// JavaScript represents deprecated BSON type JavaScript code.
+	JavaScript string
+
+	// Symbol represents deprecated BSON type Symbol.
+	Symbol string
+
+	// JavaScriptScope represents deprecated BSON type JavaScript code with scope.
+	JavaScriptScope struct {
+		Code  string
+		Scope *Document
+	}
 )
 
-// Null represents BSON value Null.
-var Null = NullType{}
+var (
+	// Null represents BSON value Null.
+	Null = NullType{}
+
+	// MinKey represents BSON value MinKey.
+	MinKey = MinKeyType{}
+
+	// MaxKey represents BSON value MaxKey.
+	MaxKey = MaxKeyType{}
+
+	// Undefined represents deprecated BSON value Undefined.
+	Undefined = UndefinedType{}
+)
 
 // validateValue validates value.
 func validateValue(value any) error {
@@ -132,8 +190,27 @@ func validateValue(value any) error {
 		return nil
 	case int64:
 		return nil
+	case Decimal128:
+		return nil
 	case CString:
 		return nil
+	case MinKeyType:
+		return nil
+	case MaxKeyType:
+		return nil
+	case UndefinedType:
+		return nil
+	case DBPointer:
+		return nil
+	case JavaScript:
+		return nil
+	case Symbol:
+		return nil
+	case JavaScriptScope:
+		if value.Scope == nil {
+			return fmt.Errorf("types.validateValue: JavaScriptScope without scope")
+		}
+		return value.Scope.validate()
 	default:
 		return fmt.Errorf("types.validateValue: unsupported type: %[1]T (%[1]v)", value)
 	}
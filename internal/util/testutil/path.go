@@ -15,7 +15,6 @@
 package testutil
 
 import (
-	"strconv"
 	"testing"
 	"time"
 
@@ -40,39 +39,11 @@ func GetByPath[T types.CompositeTypeInterface](tb testing.TB, comp T, path ...st
 func SetByPath[T types.CompositeTypeInterface](tb testing.TB, comp T, value any, path ...string) {
 	tb.Helper()
 
-	l := len(path)
-	require.NotZero(tb, l, "path is empty")
-
-	var next any = comp
-	for i, p := range path {
-		last := i == l-1
-		switch c := next.(type) {
-		case *types.Document:
-			var err error
-			next, err = c.Get(p)
-			require.NoError(tb, err)
-
-			if last {
-				err = c.Set(p, value)
-				require.NoError(tb, err)
-			}
-
-		case *types.Array:
-			index, err := strconv.Atoi(p)
-			require.NoError(tb, err)
-
-			next, err = c.Get(index)
-			require.NoError(tb, err)
-
-			if last {
-				err = c.Set(index, value)
-				require.NoError(tb, err)
-			}
+	_, err := comp.GetByPath(path...)
+	require.NoError(tb, err)
 
-		default:
-			tb.Fatalf("can't access %T by path %q", next, p)
-		}
-	}
+	err = comp.SetByPath(value, path...)
+	require.NoError(tb, err)
 }
 
 // CompareAndSetByPathNum asserts that two values with the same path in two objects (documents or arrays)
@@ -14,7 +14,13 @@
 
 package wire
 
-import "strings"
+import (
+	"bufio"
+	"encoding/binary"
+	"strings"
+
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+)
 
 type flagBit uint32
 
@@ -35,3 +41,26 @@ func (flags flags) string(bitStringer func(flagBit) string) string {
 	res := flags.strings(bitStringer)
 	return "[" + strings.Join(res, "|") + "]"
 }
+
+// readReserved reads reserved int32 field that must be zero.
+func readReserved(bufr *bufio.Reader) error {
+	var zero int32
+	if err := binary.Read(bufr, binary.LittleEndian, &zero); err != nil {
+		return lazyerrors.Errorf("binary.Read: %w", err)
+	}
+
+	if zero != 0 {
+		return lazyerrors.Errorf("reserved field is %d, expected 0", zero)
+	}
+
+	return nil
+}
+
+// writeReserved writes reserved int32 field.
+func writeReserved(bufw *bufio.Writer) error {
+	if err := binary.Write(bufw, binary.LittleEndian, int32(0)); err != nil {
+		return lazyerrors.Errorf("binary.Write: %w", err)
+	}
+
+	return nil
+}
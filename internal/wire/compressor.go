@@ -0,0 +1,159 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+)
+
+//go:generate ../../bin/stringer -linecomment -type Compressor
+
+// Compressor represents compressor ID used by OP_COMPRESSED messages.
+type Compressor uint8
+
+const (
+	CompressorNoop   = Compressor(0) // noop
+	CompressorSnappy = Compressor(1) // snappy
+	CompressorZlib   = Compressor(2) // zlib
+	CompressorZstd   = Compressor(3) // zstd
+)
+
+// SupportedCompressors contains compressors that could be negotiated with the client.
+//
+// Noop compressor is not negotiated, but messages compressed with it are still accepted.
+var SupportedCompressors = []Compressor{CompressorSnappy, CompressorZlib, CompressorZstd}
+
+var (
+	// Both are safe for concurrent use with EncodeAll / DecodeAll.
+	zstdEncoder = must.NotFail(zstd.NewWriter(nil))
+	zstdDecoder = must.NotFail(zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxMsgLen)))
+)
+
+// NegotiateCompressors returns compressors requested by the client
+// in the "compression" field of hello or isMaster command document
+// that are also supported by FerretDB.
+//
+// Compressors are returned in the client's order of preference; unknown names are ignored.
+func NegotiateCompressors(doc *types.Document) []Compressor {
+	names, _ := doc.Map()["compression"].(*types.Array)
+
+	res := make([]Compressor, 0, len(SupportedCompressors))
+	for i := 0; i < names.Len(); i++ {
+		name, _ := must.NotFail(names.Get(i)).(string)
+		for _, c := range SupportedCompressors {
+			if c.String() == name && !compressorIn(c, res) {
+				res = append(res, c)
+			}
+		}
+	}
+
+	return res
+}
+
+// compressorIn returns true if c is present in compressors.
+func compressorIn(c Compressor, compressors []Compressor) bool {
+	for _, compressor := range compressors {
+		if c == compressor {
+			return true
+		}
+	}
+	return false
+}
+
+// compress compresses b with the given compressor.
+func compress(c Compressor, b []byte) ([]byte, error) {
+	switch c {
+	case CompressorNoop:
+		return b, nil
+
+	case CompressorSnappy:
+		return snappy.Encode(nil, b), nil
+
+	case CompressorZlib:
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		if _, err := w.Write(b); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		return buf.Bytes(), nil
+
+	case CompressorZstd:
+		return zstdEncoder.EncodeAll(b, nil), nil
+
+	default:
+		return nil, lazyerrors.Errorf("unsupported compressor %s", c)
+	}
+}
+
+// decompress decompresses b with the given compressor, checking that the result has an expected size.
+func decompress(c Compressor, b []byte, size int32) ([]byte, error) {
+	var res []byte
+
+	switch c {
+	case CompressorNoop:
+		res = b
+
+	case CompressorSnappy:
+		l, err := snappy.DecodedLen(b)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+		if l != int(size) {
+			return nil, lazyerrors.Errorf("expected uncompressed size %d, got %d", size, l)
+		}
+
+		if res, err = snappy.Decode(nil, b); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+	case CompressorZlib:
+		r, err := zlib.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+		// read one more byte to detect too long data without reading all of it
+		if res, err = io.ReadAll(io.LimitReader(r, int64(size)+1)); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+	case CompressorZstd:
+		var err error
+		if res, err = zstdDecoder.DecodeAll(b, make([]byte, 0, size)); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+	default:
+		return nil, lazyerrors.Errorf("unsupported compressor %s", c)
+	}
+
+	if l := len(res); l != int(size) {
+		return nil, lazyerrors.Errorf("expected uncompressed size %d, got %d", size, l)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrChecksumMismatch is returned when OP_MSG checksum does not match message contents.
+var ErrChecksumMismatch = errors.New("OP_MSG checksum does not match contents")
+
+// ValidationError is returned for well-framed messages that failed validation.
+//
+// The whole message was read from the connection, so it is possible to reply with an error
+// and continue using the connection.
+type ValidationError struct {
+	err error
+}
+
+// newValidationError creates a new ValidationError.
+func newValidationError(err error) error {
+	return &ValidationError{err: err}
+}
+
+// Error implements error interface.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("wire validation error: %v", e.err)
+}
+
+// Unwrap implements standard error unwrapping interface.
+func (e *ValidationError) Unwrap() error {
+	return e.err
+}
+
+// check interfaces
+var (
+	_ error = (*ValidationError)(nil)
+)
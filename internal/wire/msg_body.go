@@ -17,10 +17,14 @@ package wire
 import (
 	"bufio"
 	"encoding"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 
+	"github.com/FerretDB/FerretDB/internal/bson"
 	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+	"github.com/FerretDB/FerretDB/internal/util/must"
 )
 
 type MsgBody interface {
@@ -34,71 +38,180 @@ type MsgBody interface {
 
 //go-sumtype:decl MsgBody
 
+// ReadMessage reads the next message from the reader.
+//
+// If the message was read completely, but failed validation, *ValidationError is returned together
+// with the message header; the caller may reply with an error and continue reading messages.
 func ReadMessage(r *bufio.Reader) (*MsgHeader, MsgBody, error) {
+	header, body, _, err := ReadMessageRaw(r)
+	return header, body, err
+}
+
+// ReadMessageRaw is like ReadMessage, but also returns the whole message (header and body) exactly as it was read.
+//
+// Raw message is returned together with the header if message failed validation.
+func ReadMessageRaw(r *bufio.Reader) (*MsgHeader, MsgBody, []byte, error) {
 	var header MsgHeader
 	if err := header.readFrom(r); err != nil {
 		if err == io.EOF {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
-		return nil, nil, lazyerrors.Error(err)
+		return nil, nil, nil, lazyerrors.Error(err)
 	}
 
-	b := make([]byte, header.MessageLength-MsgHeaderLen)
+	raw := make([]byte, header.MessageLength)
+	copy(raw, must.NotFail(header.MarshalBinary()))
+
+	b := raw[MsgHeaderLen:]
 	if n, err := io.ReadFull(r, b); err != nil {
-		return nil, nil, lazyerrors.Errorf("expected %d, read %d: %w", len(b), n, err)
+		return nil, nil, nil, lazyerrors.Errorf("expected %d, read %d: %w", len(b), n, err)
+	}
+
+	body, err := readBody(&header, b)
+	if err != nil {
+		var ve *ValidationError
+		if errors.As(err, &ve) {
+			return &header, nil, raw, lazyerrors.Error(err)
+		}
+
+		return nil, nil, nil, lazyerrors.Error(err)
+	}
+
+	return &header, body, raw, nil
+}
+
+// readBody unmarshals message body of the given header's opcode.
+//
+// Too large documents are reported as *ValidationError.
+func readBody(header *MsgHeader, b []byte) (MsgBody, error) {
+	body, err := unmarshalBody(header, b)
+	if err != nil {
+		var ve *ValidationError
+		if !errors.As(err, &ve) && errors.Is(err, bson.ErrDocumentTooLarge) {
+			err = newValidationError(err)
+		}
+
+		return nil, lazyerrors.Error(err)
 	}
 
+	return body, nil
+}
+
+// unmarshalBody unmarshals message body of the given header's opcode.
+func unmarshalBody(header *MsgHeader, b []byte) (MsgBody, error) {
 	switch header.OpCode {
 	case OP_REPLY:
 		var reply OpReply
 		if err := reply.UnmarshalBinary(b); err != nil {
-			return nil, nil, lazyerrors.Error(err)
+			return nil, lazyerrors.Error(err)
 		}
 
-		return &header, &reply, nil
+		return &reply, nil
 
 	case OP_MSG:
+		// verify checksum before parsing sections, so corrupted messages are reported as such
+		if len(b) >= 8 && OpMsgFlags(binary.LittleEndian.Uint32(b)).FlagSet(OpMsgChecksumPresent) {
+			if checksum := msgChecksum(header, b); checksum != binary.LittleEndian.Uint32(b[len(b)-4:]) {
+				return nil, lazyerrors.Error(newValidationError(ErrChecksumMismatch))
+			}
+		}
+
 		var msg OpMsg
 		if err := msg.UnmarshalBinary(b); err != nil {
-			return nil, nil, lazyerrors.Error(err)
+			return nil, lazyerrors.Error(err)
 		}
 
-		return &header, &msg, nil
+		return &msg, nil
 
 	case OP_QUERY:
 		var query OpQuery
 		if err := query.UnmarshalBinary(b); err != nil {
-			return nil, nil, lazyerrors.Error(err)
+			return nil, lazyerrors.Error(err)
 		}
 
-		return &header, &query, nil
+		return &query, nil
+
+	case OP_COMPRESSED:
+		var compressed OpCompressed
+		if err := compressed.UnmarshalBinary(b); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+		return &compressed, nil
 
 	case OP_UPDATE:
-		fallthrough
+		var update OpUpdate
+		if err := update.UnmarshalBinary(b); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+		return &update, nil
+
 	case OP_INSERT:
-		fallthrough
-	case OP_GET_BY_OID:
-		fallthrough
+		var insert OpInsert
+		if err := insert.UnmarshalBinary(b); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+		return &insert, nil
+
 	case OP_GET_MORE:
-		fallthrough
+		var getMore OpGetMore
+		if err := getMore.UnmarshalBinary(b); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+		return &getMore, nil
+
 	case OP_DELETE:
-		fallthrough
+		var del OpDelete
+		if err := del.UnmarshalBinary(b); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+		return &del, nil
+
 	case OP_KILL_CURSORS:
-		fallthrough
-	case OP_COMPRESSED:
+		var kill OpKillCursors
+		if err := kill.UnmarshalBinary(b); err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+		return &kill, nil
+
+	case OP_GET_BY_OID:
 		fallthrough
 
 	default:
-		return nil, nil, lazyerrors.Errorf("unhandled opcode %s", header.OpCode)
+		return nil, lazyerrors.Errorf("unhandled opcode %s", header.OpCode)
 	}
 }
 
+// WriteMessage writes the given message to the writer.
+//
+// OP_MSG checksum is generated if checksumPresent flag is set.
 func WriteMessage(w *bufio.Writer, header *MsgHeader, msg MsgBody) error {
-	b, err := msg.MarshalBinary()
+	b, err := MarshalMessage(header, msg)
 	if err != nil {
 		return lazyerrors.Error(err)
 	}
 
+	if _, err := w.Write(b); err != nil {
+		return lazyerrors.Error(err)
+	}
+
+	return nil
+}
+
+// MarshalMessage returns the whole message (header and body) as WriteMessage writes it.
+//
+// OP_MSG checksum is generated if checksumPresent flag is set.
+func MarshalMessage(header *MsgHeader, msg MsgBody) ([]byte, error) {
+	b, err := msg.MarshalBinary()
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
 	if expected := len(b) + MsgHeaderLen; int32(expected) != header.MessageLength {
 		panic(fmt.Sprintf(
 			"expected length %d (marshaled body size) + %d (fixed marshaled header size) = %d, got %d",
@@ -106,13 +219,7 @@ func WriteMessage(w *bufio.Writer, header *MsgHeader, msg MsgBody) error {
 		))
 	}
 
-	if err := header.writeTo(w); err != nil {
-		return lazyerrors.Error(err)
-	}
-
-	if _, err := w.Write(b); err != nil {
-		return lazyerrors.Error(err)
-	}
+	setChecksum(header, msg, b)
 
-	return nil
+	return append(must.NotFail(header.MarshalBinary()), b...), nil
 }
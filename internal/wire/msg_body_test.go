@@ -0,0 +1,114 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FerretDB/FerretDB/internal/bson"
+)
+
+// makeMessage returns message bytes with the given opcode, header's MessageLength and body.
+func makeMessage(opCode OpCode, length int32, body []byte) []byte {
+	b := make([]byte, MsgHeaderLen, MsgHeaderLen+len(body))
+	binary.LittleEndian.PutUint32(b[0:4], uint32(length))
+	binary.LittleEndian.PutUint32(b[4:8], 1)
+	binary.LittleEndian.PutUint32(b[12:16], uint32(opCode))
+	return append(b, body...)
+}
+
+func TestReadMessageInvalidLength(t *testing.T) {
+	t.Parallel()
+
+	for name, length := range map[string]int32{
+		"Negative": -1,
+		"Short":    MsgHeaderLen - 1,
+		"Huge":     MaxMsgLen + 1,
+		"Max":      0x7fffffff,
+	} {
+		name, length := name, length
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			b := makeMessage(OP_MSG, length, nil)
+			header, body, err := ReadMessage(bufio.NewReader(bytes.NewReader(b)))
+			require.Error(t, err)
+			assert.Nil(t, header)
+			assert.Nil(t, body)
+
+			var ve *ValidationError
+			assert.False(t, errors.As(err, &ve))
+		})
+	}
+}
+
+func TestReadMessageTooLarge(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Document", func(t *testing.T) {
+		t.Parallel()
+
+		// flags, kind 0 section with a document that claims to be larger than the limit
+		body := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x01, 0x00}
+		b := makeMessage(OP_MSG, int32(MsgHeaderLen+len(body)), body)
+
+		header, msg, err := ReadMessage(bufio.NewReader(bytes.NewReader(b)))
+		var ve *ValidationError
+		require.ErrorAs(t, err, &ve)
+		assert.ErrorIs(t, err, bson.ErrDocumentTooLarge)
+		assert.Equal(t, &MsgHeader{MessageLength: int32(len(b)), RequestID: 1, OpCode: OP_MSG}, header)
+		assert.Nil(t, msg)
+	})
+
+	t.Run("Section", func(t *testing.T) {
+		t.Parallel()
+
+		// flags, kind 1 section that claims to be larger than the whole message
+		body := []byte{0x00, 0x00, 0x00, 0x00, 0x01, 0xff, 0xff, 0xff, 0x7f}
+		b := makeMessage(OP_MSG, int32(MsgHeaderLen+len(body)), body)
+
+		header, msg, err := ReadMessage(bufio.NewReader(bytes.NewReader(b)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid kind 1 section length 2147483647")
+		assert.Nil(t, header)
+		assert.Nil(t, msg)
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		t.Parallel()
+
+		// flags, "a.$cmd", numberToSkip, numberToReturn, document that claims to be larger than the limit
+		body := []byte{
+			0x00, 0x00, 0x00, 0x00, 0x61, 0x2e, 0x24, 0x63, 0x6d, 0x64, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff,
+			0x00, 0x00, 0x00, 0x7f, 0x00,
+		}
+		b := makeMessage(OP_QUERY, int32(MsgHeaderLen+len(body)), body)
+
+		header, msg, err := ReadMessage(bufio.NewReader(bytes.NewReader(b)))
+		var ve *ValidationError
+		require.ErrorAs(t, err, &ve)
+		assert.ErrorIs(t, err, bson.ErrDocumentTooLarge)
+		assert.Equal(t, OP_QUERY, header.OpCode)
+		assert.Nil(t, msg)
+	})
+}
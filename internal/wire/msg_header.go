@@ -75,19 +75,6 @@ func (msg *MsgHeader) readFrom(r *bufio.Reader) error {
 	return nil
 }
 
-func (msg *MsgHeader) writeTo(w *bufio.Writer) error {
-	v, err := msg.MarshalBinary()
-	if err != nil {
-		return lazyerrors.Error(err)
-	}
-
-	if _, err := w.Write(v); err != nil {
-		return lazyerrors.Error(err)
-	}
-
-	return nil
-}
-
 // MarshalBinary writes a MsgHeader to a byte array.
 func (msg *MsgHeader) MarshalBinary() ([]byte, error) {
 	var buf bytes.Buffer
@@ -0,0 +1,186 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+)
+
+// opCompressedHeaderLen is the size of OpCompressed fields before compressed message.
+const opCompressedHeaderLen = 9
+
+// OpCompressed is a message that wraps any other message compressed with one of compressors.
+//
+// It keeps compressed data as-is so it could be passed through without changes; use Decompress to get
+// the original message.
+type OpCompressed struct {
+	OriginalOpCode    OpCode
+	UncompressedSize  int32
+	Compressor        Compressor
+	CompressedMessage []byte
+}
+
+func (msg *OpCompressed) msgbody() {}
+
+func (msg *OpCompressed) readFrom(bufr *bufio.Reader) error {
+	if err := binary.Read(bufr, binary.LittleEndian, &msg.OriginalOpCode); err != nil {
+		return lazyerrors.Errorf("wire.OpCompressed.ReadFrom (binary.Read): %w", err)
+	}
+	if err := binary.Read(bufr, binary.LittleEndian, &msg.UncompressedSize); err != nil {
+		return lazyerrors.Errorf("wire.OpCompressed.ReadFrom (binary.Read): %w", err)
+	}
+	if err := binary.Read(bufr, binary.LittleEndian, &msg.Compressor); err != nil {
+		return lazyerrors.Errorf("wire.OpCompressed.ReadFrom (binary.Read): %w", err)
+	}
+
+	if msg.OriginalOpCode == OP_COMPRESSED {
+		return lazyerrors.Errorf("wire.OpCompressed.ReadFrom: nested %s", msg.OriginalOpCode)
+	}
+
+	if s := msg.UncompressedSize; s < 0 || s > MaxMsgLen-MsgHeaderLen {
+		return lazyerrors.Errorf("wire.OpCompressed.ReadFrom: invalid uncompressed size %d", s)
+	}
+
+	if msg.Compressor != CompressorNoop && !compressorIn(msg.Compressor, SupportedCompressors) {
+		return lazyerrors.Errorf("wire.OpCompressed.ReadFrom: unsupported compressor %s", msg.Compressor)
+	}
+
+	var err error
+	if msg.CompressedMessage, err = io.ReadAll(bufr); err != nil {
+		return lazyerrors.Errorf("wire.OpCompressed.ReadFrom (io.ReadAll): %w", err)
+	}
+
+	return nil
+}
+
+// UnmarshalBinary reads an OpCompressed from a byte array.
+func (msg *OpCompressed) UnmarshalBinary(b []byte) error {
+	br := bytes.NewReader(b)
+	bufr := bufio.NewReader(br)
+
+	if err := msg.readFrom(bufr); err != nil {
+		return lazyerrors.Errorf("wire.OpCompressed.UnmarshalBinary: %w", err)
+	}
+
+	return nil
+}
+
+// MarshalBinary writes an OpCompressed to a byte array.
+func (msg *OpCompressed) MarshalBinary() ([]byte, error) {
+	b := make([]byte, opCompressedHeaderLen, opCompressedHeaderLen+len(msg.CompressedMessage))
+
+	binary.LittleEndian.PutUint32(b[0:4], uint32(msg.OriginalOpCode))
+	binary.LittleEndian.PutUint32(b[4:8], uint32(msg.UncompressedSize))
+	b[8] = byte(msg.Compressor)
+
+	return append(b, msg.CompressedMessage...), nil
+}
+
+// String returns a string representation for logging.
+func (msg *OpCompressed) String() string {
+	if msg == nil {
+		return "<nil>"
+	}
+
+	m := map[string]any{
+		"OriginalOpCode":   msg.OriginalOpCode.String(),
+		"UncompressedSize": msg.UncompressedSize,
+		"Compressor":       msg.Compressor.String(),
+		"CompressedSize":   len(msg.CompressedMessage),
+	}
+
+	return string(must.NotFail(json.MarshalIndent(m, "", "  ")))
+}
+
+// Compress wraps the given message into OP_COMPRESSED message using the given compressor.
+//
+// Returned header keeps RequestID and ResponseTo of the original header.
+func Compress(compressor Compressor, header *MsgHeader, body MsgBody) (*MsgHeader, *OpCompressed, error) {
+	b, err := body.MarshalBinary()
+	if err != nil {
+		return nil, nil, lazyerrors.Error(err)
+	}
+
+	setChecksum(&MsgHeader{
+		MessageLength: int32(MsgHeaderLen + len(b)),
+		RequestID:     header.RequestID,
+		ResponseTo:    header.ResponseTo,
+		OpCode:        header.OpCode,
+	}, body, b)
+
+	compressed, err := compress(compressor, b)
+	if err != nil {
+		return nil, nil, lazyerrors.Error(err)
+	}
+
+	msg := &OpCompressed{
+		OriginalOpCode:    header.OpCode,
+		UncompressedSize:  int32(len(b)),
+		Compressor:        compressor,
+		CompressedMessage: compressed,
+	}
+
+	resHeader := &MsgHeader{
+		MessageLength: int32(MsgHeaderLen + opCompressedHeaderLen + len(compressed)),
+		RequestID:     header.RequestID,
+		ResponseTo:    header.ResponseTo,
+		OpCode:        OP_COMPRESSED,
+	}
+
+	return resHeader, msg, nil
+}
+
+// Decompress returns the message wrapped into the given OP_COMPRESSED message and its header.
+//
+// Returned header keeps RequestID and ResponseTo of the OP_COMPRESSED message header.
+// Like ReadMessage, it returns *ValidationError together with the header if the message can't be used.
+func Decompress(header *MsgHeader, msg *OpCompressed) (*MsgHeader, MsgBody, error) {
+	resHeader := &MsgHeader{
+		MessageLength: MsgHeaderLen + msg.UncompressedSize,
+		RequestID:     header.RequestID,
+		ResponseTo:    header.ResponseTo,
+		OpCode:        msg.OriginalOpCode,
+	}
+
+	b, err := decompress(msg.Compressor, msg.CompressedMessage, msg.UncompressedSize)
+	if err != nil {
+		return resHeader, nil, lazyerrors.Error(newValidationError(err))
+	}
+
+	body, err := readBody(resHeader, b)
+	if err != nil {
+		var ve *ValidationError
+		if errors.As(err, &ve) {
+			return resHeader, nil, lazyerrors.Error(err)
+		}
+
+		return nil, nil, lazyerrors.Error(err)
+	}
+
+	return resHeader, body, nil
+}
+
+// check interfaces
+var (
+	_ MsgBody = (*OpCompressed)(nil)
+)
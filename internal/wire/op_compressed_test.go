@@ -0,0 +1,186 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+	"github.com/FerretDB/FerretDB/internal/util/testutil"
+)
+
+// compressedBody returns OP_COMPRESSED body fields followed by data.
+func compressedBody(originalOpCode OpCode, size int32, compressor Compressor, data []byte) []byte {
+	return must.NotFail((&OpCompressed{
+		OriginalOpCode:    originalOpCode,
+		UncompressedSize:  size,
+		Compressor:        compressor,
+		CompressedMessage: data,
+	}).MarshalBinary())
+}
+
+var compressedTestCases = []testCase{{
+	name: "noop",
+	headerB: []byte{
+		0x65, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0xdc, 0x07, 0x00, 0x00,
+	},
+	bodyB: append(
+		[]byte{0xdd, 0x07, 0x00, 0x00, 0x4c, 0x00, 0x00, 0x00, 0x00},
+		testutil.MustParseDumpFile("testdata", "handshake5_body.hex")...,
+	),
+	msgHeader: &MsgHeader{
+		MessageLength: 101,
+		RequestID:     3,
+		OpCode:        OP_COMPRESSED,
+	},
+	msgBody: &OpCompressed{
+		OriginalOpCode:    OP_MSG,
+		UncompressedSize:  76,
+		Compressor:        CompressorNoop,
+		CompressedMessage: testutil.MustParseDumpFile("testdata", "handshake5_body.hex"),
+	},
+}, {
+	name: "Nested",
+	headerB: []byte{
+		0x1a, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0xdc, 0x07, 0x00, 0x00,
+	},
+	bodyB: compressedBody(OP_COMPRESSED, 1, CompressorNoop, []byte{0x00}),
+	err:   `wire.OpCompressed.ReadFrom: nested OP_COMPRESSED`,
+}, {
+	name: "InvalidSize",
+	headerB: []byte{
+		0x1a, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0xdc, 0x07, 0x00, 0x00,
+	},
+	bodyB: compressedBody(OP_MSG, -1, CompressorNoop, []byte{0x00}),
+	err:   `wire.OpCompressed.ReadFrom: invalid uncompressed size -1`,
+}, {
+	name: "UnsupportedCompressor",
+	headerB: []byte{
+		0x1a, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0xdc, 0x07, 0x00, 0x00,
+	},
+	bodyB: compressedBody(OP_MSG, 1, Compressor(42), []byte{0x00}),
+	err:   `wire.OpCompressed.ReadFrom: unsupported compressor Compressor(42)`,
+}}
+
+func TestCompressed(t *testing.T) {
+	t.Parallel()
+	testMessages(t, compressedTestCases)
+}
+
+func FuzzCompressed(f *testing.F) {
+	fuzzMessages(f, compressedTestCases)
+}
+
+func TestCompressDecompress(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range msgTestCases {
+		if tc.msgHeader == nil {
+			continue
+		}
+
+		for _, c := range []Compressor{CompressorNoop, CompressorSnappy, CompressorZlib, CompressorZstd} {
+			tc, c := tc, c
+			t.Run(tc.name+"/"+c.String(), func(t *testing.T) {
+				t.Parallel()
+
+				header, msg, err := Compress(c, tc.msgHeader, tc.msgBody)
+				require.NoError(t, err)
+				assert.Equal(t, OP_COMPRESSED, header.OpCode)
+				assert.Equal(t, tc.msgHeader.RequestID, header.RequestID)
+				assert.Equal(t, tc.msgHeader.ResponseTo, header.ResponseTo)
+
+				b, err := msg.MarshalBinary()
+				require.NoError(t, err)
+				assert.Equal(t, int(header.MessageLength), MsgHeaderLen+len(b))
+
+				actualHeader, actualBody, err := Decompress(header, msg)
+				require.NoError(t, err)
+				assert.Equal(t, tc.msgHeader, actualHeader)
+				assert.Equal(t, tc.msgBody, actualBody)
+			})
+		}
+	}
+}
+
+func TestDecompressInvalid(t *testing.T) {
+	t.Parallel()
+
+	header := &MsgHeader{OpCode: OP_COMPRESSED}
+	body := must.NotFail(msgTestCases[0].msgBody.MarshalBinary())
+
+	for _, c := range []Compressor{CompressorNoop, CompressorSnappy, CompressorZlib, CompressorZstd} {
+		c := c
+		t.Run(c.String(), func(t *testing.T) {
+			t.Parallel()
+
+			compressed, err := compress(c, body)
+			require.NoError(t, err)
+
+			for _, size := range []int32{int32(len(body)) - 1, int32(len(body)) + 1} {
+				msg := &OpCompressed{
+					OriginalOpCode:    OP_MSG,
+					UncompressedSize:  size,
+					Compressor:        c,
+					CompressedMessage: compressed,
+				}
+				_, _, err = Decompress(header, msg)
+				assert.Error(t, err, "size %d", size)
+			}
+		})
+	}
+}
+
+func TestNegotiateCompressors(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		doc      *types.Document
+		expected []Compressor
+	}{
+		"Missing": {
+			doc:      types.MustNewDocument("hello", int32(1)),
+			expected: []Compressor{},
+		},
+		"Order": {
+			doc: types.MustNewDocument(
+				"hello", int32(1),
+				"compression", must.NotFail(types.NewArray("zstd", "unknown", "noop", "snappy", "zstd")),
+			),
+			expected: []Compressor{CompressorZstd, CompressorSnappy},
+		},
+		"WrongType": {
+			doc: types.MustNewDocument(
+				"hello", int32(1),
+				"compression", must.NotFail(types.NewArray(int32(1), "zlib")),
+			),
+			expected: []Compressor{CompressorZlib},
+		},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, NegotiateCompressors(tc.doc))
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"testing"
+
+	"github.com/FerretDB/FerretDB/internal/types"
+)
+
+var insertTestCases = []testCase{{
+	name: "Insert",
+	headerB: []byte{
+		0x45, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0xd2, 0x07, 0x00, 0x00,
+	},
+	bodyB: []byte{
+		0x01, 0x00, 0x00, 0x00, 0x74, 0x65, 0x73, 0x74,
+		0x2e, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x00,
+		0x17, 0x00, 0x00, 0x00, 0x10, 0x5f, 0x69, 0x64,
+		0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x76, 0x00,
+		0x02, 0x00, 0x00, 0x00, 0x61, 0x00, 0x00, 0x0e,
+		0x00, 0x00, 0x00, 0x10, 0x5f, 0x69, 0x64, 0x00,
+		0x02, 0x00, 0x00, 0x00, 0x00,
+	},
+	msgHeader: &MsgHeader{
+		MessageLength: 69,
+		RequestID:     1,
+		OpCode:        OP_INSERT,
+	},
+	msgBody: &OpInsert{
+		Flags:              OpInsertFlags(OpInsertContinueOnError),
+		FullCollectionName: "test.values",
+		Documents: []*types.Document{
+			types.MustNewDocument("_id", int32(1), "v", "a"),
+			types.MustNewDocument("_id", int32(2)),
+		},
+	},
+}, {
+	name: "NoDocuments",
+	headerB: []byte{
+		0x20, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0xd2, 0x07, 0x00, 0x00,
+	},
+	bodyB: []byte{
+		0x00, 0x00, 0x00, 0x00, 0x74, 0x65, 0x73, 0x74,
+		0x2e, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x00,
+	},
+	err: `EOF`,
+}}
+
+func TestInsert(t *testing.T) {
+	t.Parallel()
+	testMessages(t, insertTestCases)
+}
+
+func FuzzInsert(f *testing.F) {
+	fuzzMessages(f, insertTestCases)
+}
@@ -0,0 +1,122 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+)
+
+// maxKillCursors is the maximal number of cursor IDs in a single OpKillCursors message.
+const maxKillCursors = (MaxMsgLen - MsgHeaderLen - 8) / 8
+
+// OpKillCursors is a legacy message used to close cursors.
+type OpKillCursors struct {
+	CursorIDs []int64
+}
+
+func (kill *OpKillCursors) msgbody() {}
+
+func (kill *OpKillCursors) readFrom(bufr *bufio.Reader) error {
+	if err := readReserved(bufr); err != nil {
+		return lazyerrors.Errorf("wire.OpKillCursors.ReadFrom: %w", err)
+	}
+
+	var n int32
+	if err := binary.Read(bufr, binary.LittleEndian, &n); err != nil {
+		return lazyerrors.Errorf("wire.OpKillCursors.ReadFrom (binary.Read): %w", err)
+	}
+
+	if n < 0 || n > maxKillCursors {
+		return lazyerrors.Errorf("wire.OpKillCursors.ReadFrom: invalid number of cursor IDs %d", n)
+	}
+
+	kill.CursorIDs = make([]int64, 0, n)
+	for i := int32(0); i < n; i++ {
+		var id int64
+		if err := binary.Read(bufr, binary.LittleEndian, &id); err != nil {
+			return lazyerrors.Errorf("wire.OpKillCursors.ReadFrom (binary.Read): %w", err)
+		}
+		kill.CursorIDs = append(kill.CursorIDs, id)
+	}
+
+	return nil
+}
+
+// UnmarshalBinary reads an OpKillCursors from a byte array.
+func (kill *OpKillCursors) UnmarshalBinary(b []byte) error {
+	br := bytes.NewReader(b)
+	bufr := bufio.NewReader(br)
+
+	if err := kill.readFrom(bufr); err != nil {
+		return lazyerrors.Errorf("wire.OpKillCursors.UnmarshalBinary: %w", err)
+	}
+
+	if _, err := bufr.Peek(1); err != io.EOF {
+		return lazyerrors.Errorf("unexpected end of the OpKillCursors: %v", err)
+	}
+
+	return nil
+}
+
+// MarshalBinary writes an OpKillCursors to a byte array.
+func (kill *OpKillCursors) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	bufw := bufio.NewWriter(&buf)
+
+	if err := writeReserved(bufw); err != nil {
+		return nil, lazyerrors.Errorf("wire.OpKillCursors.MarshalBinary: %w", err)
+	}
+
+	if err := binary.Write(bufw, binary.LittleEndian, int32(len(kill.CursorIDs))); err != nil {
+		return nil, lazyerrors.Errorf("wire.OpKillCursors.MarshalBinary (binary.Write): %w", err)
+	}
+
+	for _, id := range kill.CursorIDs {
+		if err := binary.Write(bufw, binary.LittleEndian, id); err != nil {
+			return nil, lazyerrors.Errorf("wire.OpKillCursors.MarshalBinary (binary.Write): %w", err)
+		}
+	}
+
+	if err := bufw.Flush(); err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// String returns a string representation for logging.
+func (kill *OpKillCursors) String() string {
+	if kill == nil {
+		return "<nil>"
+	}
+
+	m := map[string]any{
+		"CursorIDs": kill.CursorIDs,
+	}
+
+	return string(must.NotFail(json.MarshalIndent(m, "", "  ")))
+}
+
+// check interfaces
+var (
+	_ MsgBody = (*OpKillCursors)(nil)
+)
@@ -0,0 +1,68 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import "testing"
+
+var killCursorsTestCases = []testCase{{
+	name: "KillCursors",
+	headerB: []byte{
+		0x28, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0xd7, 0x07, 0x00, 0x00,
+	},
+	bodyB: []byte{
+		0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	msgHeader: &MsgHeader{
+		MessageLength: 40,
+		RequestID:     5,
+		OpCode:        OP_KILL_CURSORS,
+	},
+	msgBody: &OpKillCursors{
+		CursorIDs: []int64{1, 2},
+	},
+}, {
+	name: "Negative",
+	headerB: []byte{
+		0x18, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0xd7, 0x07, 0x00, 0x00,
+	},
+	bodyB: []byte{
+		0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff,
+	},
+	err: `wire.OpKillCursors.ReadFrom: invalid number of cursor IDs -1`,
+}, {
+	name: "TooMany",
+	headerB: []byte{
+		0x20, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0xd7, 0x07, 0x00, 0x00,
+	},
+	bodyB: []byte{
+		0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	err: `EOF`,
+}}
+
+func TestKillCursors(t *testing.T) {
+	t.Parallel()
+	testMessages(t, killCursorsTestCases)
+}
+
+func FuzzKillCursors(f *testing.F) {
+	fuzzMessages(f, killCursorsTestCases)
+}
@@ -20,6 +20,7 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"hash/crc32"
 	"io"
 
 	"github.com/FerretDB/FerretDB/internal/bson"
@@ -44,6 +45,27 @@ type OpMsg struct {
 	sections []OpMsgSection
 }
 
+// castagnoliTable is used for OP_MSG checksums.
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// msgChecksum returns CRC-32C checksum of the whole OP_MSG message (header and body)
+// excluding the checksum itself.
+func msgChecksum(header *MsgHeader, body []byte) uint32 {
+	checksum := crc32.Update(0, castagnoliTable, must.NotFail(header.MarshalBinary()))
+	return crc32.Update(checksum, castagnoliTable, body[:len(body)-4])
+}
+
+// setChecksum sets checksum in the marshaled body of the OP_MSG message if checksumPresent flag is set.
+// It does nothing for other messages; msg itself is not modified.
+func setChecksum(header *MsgHeader, msg MsgBody, body []byte) {
+	m, ok := msg.(*OpMsg)
+	if !ok || !m.FlagBits.FlagSet(OpMsgChecksumPresent) {
+		return
+	}
+
+	binary.LittleEndian.PutUint32(body[len(body)-4:], msgChecksum(header, body))
+}
+
 // SetSections of the OpMsg.
 func (msg *OpMsg) SetSections(sections ...OpMsgSection) error {
 	msg.sections = sections
@@ -138,7 +160,7 @@ func (msg *OpMsg) readFrom(bufr *bufio.Reader) error {
 				return lazyerrors.Error(err)
 			}
 
-			if secSize < 5 {
+			if secSize < 5 || secSize > MaxMsgLen {
 				return lazyerrors.Errorf("wire.OpMsg.readFrom: invalid kind 1 section length %d", secSize)
 			}
 
@@ -197,8 +219,6 @@ func (msg *OpMsg) readFrom(bufr *bufio.Reader) error {
 		return lazyerrors.Error(err)
 	}
 
-	// TODO validate checksum
-
 	return nil
 }
 
@@ -15,8 +15,13 @@
 package wire
 
 import (
+	"bufio"
+	"bytes"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/FerretDB/FerretDB/internal/types"
 	"github.com/FerretDB/FerretDB/internal/util/must"
 	"github.com/FerretDB/FerretDB/internal/util/testutil"
@@ -147,6 +152,62 @@ var msgTestCases = []testCase{{
 	name:      "dollar_dot",
 	expectedB: testutil.MustParseDumpFile("testdata", "dollar_dot.hex"),
 	err:       `types.Document.validate: invalid key: "$."`,
+}, {
+	name: "Checksum",
+	headerB: []byte{
+		0x37, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0xdd, 0x07, 0x00, 0x00,
+	},
+	bodyB: []byte{
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x1e, 0x00, 0x00,
+		0x00, 0x10, 0x70, 0x69, 0x6e, 0x67, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x02, 0x24, 0x64, 0x62, 0x00,
+		0x06, 0x00, 0x00, 0x00, 0x61, 0x64, 0x6d, 0x69,
+		0x6e, 0x00, 0x00, 0x32, 0x7e, 0x69, 0xa8,
+	},
+	msgHeader: &MsgHeader{
+		MessageLength: 55,
+		RequestID:     1,
+		OpCode:        OP_MSG,
+	},
+	msgBody: &OpMsg{
+		FlagBits: OpMsgFlags(OpMsgChecksumPresent),
+		Checksum: 0xa8697e32,
+		sections: []OpMsgSection{{
+			Documents: []*types.Document{types.MustNewDocument(
+				"ping", int32(1),
+				"$db", "admin",
+			)},
+		}},
+	},
+}, {
+	name: "ChecksumMismatch",
+	headerB: []byte{
+		0x37, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0xdd, 0x07, 0x00, 0x00,
+	},
+	bodyB: []byte{
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x1e, 0x00, 0x00,
+		0x00, 0x10, 0x70, 0x69, 0x6e, 0x67, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x02, 0x24, 0x64, 0x62, 0x00,
+		0x06, 0x00, 0x00, 0x00, 0x61, 0x64, 0x6d, 0x69,
+		0x6e, 0x00, 0x00, 0x32, 0x7e, 0x69, 0xa9,
+	},
+	err: `OP_MSG checksum does not match contents`,
+}, {
+	name: "ChecksumMismatchInvalidSection",
+	headerB: []byte{
+		0x37, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0xdd, 0x07, 0x00, 0x00,
+	},
+	bodyB: []byte{
+		0x01, 0x00, 0x00, 0x00, 0x05, 0x1e, 0x00, 0x00,
+		0x00, 0x10, 0x70, 0x69, 0x6e, 0x67, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x02, 0x24, 0x64, 0x62, 0x00,
+		0x06, 0x00, 0x00, 0x00, 0x61, 0x64, 0x6d, 0x69,
+		0x6e, 0x00, 0x00, 0x32, 0x7e, 0x69, 0xa9,
+	},
+	err: `OP_MSG checksum does not match contents`,
 }, {
 	name:      "msg_fuzz1",
 	expectedB: testutil.MustParseDumpFile("testdata", "msg_fuzz1.hex"),
@@ -161,3 +222,23 @@ func TestMsg(t *testing.T) {
 func FuzzMsg(f *testing.F) {
 	fuzzMessages(f, msgTestCases)
 }
+
+func TestMsgChecksumMismatch(t *testing.T) {
+	t.Parallel()
+
+	var tc testCase
+	for _, tc = range msgTestCases {
+		if tc.name == "ChecksumMismatch" {
+			break
+		}
+	}
+
+	b := append(append([]byte{}, tc.headerB...), tc.bodyB...)
+	msgHeader, msgBody, err := ReadMessage(bufio.NewReader(bytes.NewReader(b)))
+
+	var ve *ValidationError
+	require.ErrorAs(t, err, &ve)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.Equal(t, &MsgHeader{MessageLength: 55, RequestID: 1, OpCode: OP_MSG}, msgHeader)
+	assert.Nil(t, msgBody)
+}
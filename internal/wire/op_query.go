@@ -62,7 +62,11 @@ func (query *OpQuery) readFrom(bufr *bufio.Reader) error {
 	if err := q.ReadFrom(bufr); err != nil {
 		return err
 	}
-	query.Query = types.MustConvertDocument(&q)
+	d, err := types.ConvertDocument(&q)
+	if err != nil {
+		return lazyerrors.Error(err)
+	}
+	query.Query = d
 
 	if _, err := bufr.Peek(1); err == nil {
 		var r bson.Document
@@ -70,7 +74,10 @@ func (query *OpQuery) readFrom(bufr *bufio.Reader) error {
 			return err
 		}
 
-		tr := types.MustConvertDocument(&r)
+		tr, err := types.ConvertDocument(&r)
+		if err != nil {
+			return lazyerrors.Error(err)
+		}
 		query.ReturnFieldsSelector = tr
 	}
 
@@ -65,7 +65,11 @@ func (reply *OpReply) readFrom(bufr *bufio.Reader) error {
 		if err := doc.ReadFrom(bufr); err != nil {
 			return lazyerrors.Errorf("wire.OpReply.ReadFrom: %w", err)
 		}
-		reply.Documents[i] = types.MustConvertDocument(&doc)
+		d, err := types.ConvertDocument(&doc)
+		if err != nil {
+			return lazyerrors.Errorf("wire.OpReply.ReadFrom: %w", err)
+		}
+		reply.Documents[i] = d
 	}
 
 	return nil
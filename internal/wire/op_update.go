@@ -0,0 +1,147 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+
+	"github.com/FerretDB/FerretDB/internal/bson"
+	"github.com/FerretDB/FerretDB/internal/fjson"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+)
+
+// OpUpdate is a legacy message used to update documents in a collection.
+type OpUpdate struct {
+	FullCollectionName string
+	Flags              OpUpdateFlags
+	Selector           *types.Document
+	Update             *types.Document
+}
+
+func (update *OpUpdate) msgbody() {}
+
+func (update *OpUpdate) readFrom(bufr *bufio.Reader) error {
+	if err := readReserved(bufr); err != nil {
+		return lazyerrors.Errorf("wire.OpUpdate.ReadFrom: %w", err)
+	}
+
+	var col bson.CString
+	if err := col.ReadFrom(bufr); err != nil {
+		return lazyerrors.Errorf("wire.OpUpdate.ReadFrom: %w", err)
+	}
+	update.FullCollectionName = string(col)
+
+	if err := binary.Read(bufr, binary.LittleEndian, &update.Flags); err != nil {
+		return lazyerrors.Errorf("wire.OpUpdate.ReadFrom (binary.Read): %w", err)
+	}
+
+	var selector bson.Document
+	if err := selector.ReadFrom(bufr); err != nil {
+		return lazyerrors.Errorf("wire.OpUpdate.ReadFrom: %w", err)
+	}
+
+	var err error
+	if update.Selector, err = types.ConvertDocument(&selector); err != nil {
+		return lazyerrors.Errorf("wire.OpUpdate.ReadFrom: %w", err)
+	}
+
+	var u bson.Document
+	if err := u.ReadFrom(bufr); err != nil {
+		return lazyerrors.Errorf("wire.OpUpdate.ReadFrom: %w", err)
+	}
+
+	if update.Update, err = types.ConvertDocument(&u); err != nil {
+		return lazyerrors.Errorf("wire.OpUpdate.ReadFrom: %w", err)
+	}
+
+	return nil
+}
+
+// UnmarshalBinary reads an OpUpdate from a byte array.
+func (update *OpUpdate) UnmarshalBinary(b []byte) error {
+	br := bytes.NewReader(b)
+	bufr := bufio.NewReader(br)
+
+	if err := update.readFrom(bufr); err != nil {
+		return lazyerrors.Errorf("wire.OpUpdate.UnmarshalBinary: %w", err)
+	}
+
+	if _, err := bufr.Peek(1); err != io.EOF {
+		return lazyerrors.Errorf("unexpected end of the OpUpdate: %v", err)
+	}
+
+	return nil
+}
+
+// MarshalBinary writes an OpUpdate to a byte array.
+func (update *OpUpdate) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	bufw := bufio.NewWriter(&buf)
+
+	if err := writeReserved(bufw); err != nil {
+		return nil, lazyerrors.Errorf("wire.OpUpdate.MarshalBinary: %w", err)
+	}
+
+	if err := bson.CString(update.FullCollectionName).WriteTo(bufw); err != nil {
+		return nil, lazyerrors.Errorf("wire.OpUpdate.MarshalBinary: %w", err)
+	}
+
+	if err := binary.Write(bufw, binary.LittleEndian, update.Flags); err != nil {
+		return nil, lazyerrors.Errorf("wire.OpUpdate.MarshalBinary (binary.Write): %w", err)
+	}
+
+	if err := bson.MustConvertDocument(update.Selector).WriteTo(bufw); err != nil {
+		return nil, lazyerrors.Errorf("wire.OpUpdate.MarshalBinary: %w", err)
+	}
+
+	if err := bson.MustConvertDocument(update.Update).WriteTo(bufw); err != nil {
+		return nil, lazyerrors.Errorf("wire.OpUpdate.MarshalBinary: %w", err)
+	}
+
+	if err := bufw.Flush(); err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// String returns a string representation for logging.
+//
+// Currently, it uses FJSON, but that may change in the future.
+func (update *OpUpdate) String() string {
+	if update == nil {
+		return "<nil>"
+	}
+
+	m := map[string]any{
+		"FullCollectionName": update.FullCollectionName,
+		"Flags":              update.Flags,
+		"Selector":           json.RawMessage(must.NotFail(fjson.Marshal(update.Selector))),
+		"Update":             json.RawMessage(must.NotFail(fjson.Marshal(update.Update))),
+	}
+
+	return string(must.NotFail(json.MarshalIndent(m, "", "  ")))
+}
+
+// check interfaces
+var (
+	_ MsgBody = (*OpUpdate)(nil)
+)
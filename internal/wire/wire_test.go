@@ -93,6 +93,19 @@ func testMessages(t *testing.T, testCases []testCase) {
 				require.Equal(t, tc.err, lastErr(err).Error())
 			})
 
+			t.Run("ReadMessageRaw", func(t *testing.T) {
+				t.Parallel()
+
+				_, _, raw, err := ReadMessageRaw(bufio.NewReader(bytes.NewReader(tc.expectedB)))
+				if err != nil && !errors.As(err, new(*ValidationError)) {
+					assert.Nil(t, raw)
+					return
+				}
+
+				// raw message is kept as-is even if it failed validation
+				assert.Equal(t, tc.expectedB, raw)
+			})
+
 			t.Run("WriteMessage", func(t *testing.T) {
 				if tc.msgHeader == nil {
 					t.Skip("msgHeader is nil")